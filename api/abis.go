@@ -0,0 +1,21 @@
+package api
+
+// abiStore is where uploaded ABIs are kept, the backend refuses to replace the ABI of a verified contract
+
+type abiStore interface {
+	AddContractABI(address string, abiJSON string) error
+}
+
+// AbiUploads serves explorer_addContractABI. It's a write anyone can make, so it's only served when enabled in the config
+
+type AbiUploads struct {
+	store abiStore
+}
+
+func (u *AbiUploads) AddContractABI(address string, abiJSON string) error {
+	return u.store.AddContractABI(address, abiJSON)
+}
+
+func NewAbiUploads(store abiStore) *AbiUploads {
+	return &AbiUploads{store}
+}
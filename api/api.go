@@ -7,6 +7,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/ubiq/go-ubiq/v7/log"
 	"github.com/ubiq/go-ubiq/v7/rpc"
+
+	"github.com/octanolabs/go-spectrum/events"
+	"github.com/octanolabs/go-spectrum/verifier"
 )
 
 type Config struct {
@@ -14,6 +17,13 @@ type Config struct {
 	//V3      bool   `json:"v4"`
 	Host string `json:"host"`
 	Port string `json:"port"`
+	// Verifier serves explorer_verifyContract when enabled
+	Verifier verifier.Config `json:"verifier"`
+	// AbiUploads serves explorer_addContractABI, which lets anyone upload the ABI of a contract that isn't verified
+	AbiUploads bool `json:"abi_uploads"`
+	// Subscriptions runs the api alongside the crawlers, which otherwise take precedence, and serves websocket
+	// subscriptions to what the block crawler commits
+	Subscriptions bool `json:"subscriptions"`
 	//Nodemap struct {
 	//	Enabled bool   `json:"enabled"`
 	//	Mode    string `json:"mode"`
@@ -23,6 +33,8 @@ type Config struct {
 
 type ApiServer struct {
 	handlers v4api
+	services []interface{}
+	feed     *events.Feed
 	cfg      *Config
 	logger   log.Logger
 }
@@ -37,6 +49,16 @@ func (a *ApiServer) Start() {
 		a.logger.Error("Error: couldn't register service: ", err)
 	}
 
+	for _, s := range a.services {
+		if err := rpcServer.RegisterName("explorer", s); err != nil {
+			a.logger.Error("Error: couldn't register service: ", err)
+		}
+	}
+
+	if err := rpcServer.RegisterName("explorer", &subscriptions{a.feed}); err != nil {
+		a.logger.Error("Error: couldn't register service: ", err)
+	}
+
 	router := gin.New()
 
 	router.Use(gin.Recovery())
@@ -69,6 +91,9 @@ func (a *ApiServer) Start() {
 		v4.POST("/", v4RouterHandler(rpcServer))
 	}
 
+	// explorer_subscribe is only served here, notifications need a connection to be pushed over
+	router.GET("/ws", gin.WrapH(rpcServer.WebsocketHandler([]string{"*"})))
+
 	go func() {
 		err := router.Run(a.cfg.Host + ":" + a.cfg.Port)
 
@@ -78,6 +103,19 @@ func (a *ApiServer) Start() {
 	}()
 }
 
+// AddService serves the exported methods of service in the explorer namespace too, it has to be called before Start
+
+func (a *ApiServer) AddService(service interface{}) {
+	a.services = append(a.services, service)
+}
+
+// SetFeed pushes what the block crawler commits to subscribers over /ws, it has to be called before Start.
+// Without a feed subscribing fails
+
+func (a *ApiServer) SetFeed(feed *events.Feed) {
+	a.feed = feed
+}
+
 func NewV3ApiServer(backend v4api, cfg *Config, logger log.Logger) *ApiServer {
 
 	s := &ApiServer{
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/events"
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/event"
+	"github.com/ubiq/go-ubiq/v7/rpc"
+)
+
+// subscriptionBuffer is how many events a subscription holds while its client is being written to, the feed
+// drops subscriptions that fall further behind and they stop sending
+const subscriptionBuffer = 64
+
+var errNoFeed = errors.New("subscriptions need api.subscriptions set and the block crawler running in the api process")
+
+// subscriptions serves explorer_subscribe over websockets. Each method below is a topic, named after the method,
+// e.g. ["newBlocks"] or ["addressActivity", "0x..."], and explorer_unsubscribe ends a subscription
+
+type subscriptions struct {
+	feed *events.Feed
+}
+
+// activity is what a block did to an address
+
+type activity struct {
+	BlockNumber  uint64                 `json:"blockNumber"`
+	Transactions []models.Transaction   `json:"transactions"`
+	Transfers    []models.TokenTransfer `json:"transfers"`
+}
+
+// NewBlocks sends every block the crawler commits, without its transactions
+
+func (s *subscriptions) NewBlocks(ctx context.Context) (*rpc.Subscription, error) {
+	return s.subscribe(ctx, func(b *events.Block, notify func(interface{}) error) error {
+		block := b.Block
+		block.Transactions = nil
+
+		return notify(block)
+	}, nil)
+}
+
+// NewTransactions sends the transactions of every block the crawler commits, one by one
+
+func (s *subscriptions) NewTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	return s.subscribe(ctx, func(b *events.Block, notify func(interface{}) error) error {
+		for _, txn := range b.Block.Transactions {
+			if err := notify(txn); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
+
+// AddressActivity sends the transactions and token transfers from or to address, once per block that has any
+
+func (s *subscriptions) AddressActivity(ctx context.Context, address string) (*rpc.Subscription, error) {
+	address = strings.ToLower(address)
+
+	return s.subscribe(ctx, func(b *events.Block, notify func(interface{}) error) error {
+		a := activity{BlockNumber: b.Block.Number, Transactions: make([]models.Transaction, 0), Transfers: make([]models.TokenTransfer, 0)}
+
+		for _, txn := range b.Block.Transactions {
+			if txn.From == address || txn.To == address || txn.ContractAddress == address {
+				a.Transactions = append(a.Transactions, txn)
+			}
+		}
+
+		for _, t := range b.TokenTransfers {
+			if t.From == address || t.To == address {
+				a.Transfers = append(a.Transfers, t)
+			}
+		}
+
+		if len(a.Transactions) == 0 && len(a.Transfers) == 0 {
+			return nil
+		}
+
+		return notify(a)
+	}, nil)
+}
+
+// TokenTransfers sends the transfers of the token at contract, one by one
+
+func (s *subscriptions) TokenTransfers(ctx context.Context, contract string) (*rpc.Subscription, error) {
+	contract = strings.ToLower(contract)
+
+	return s.subscribe(ctx, func(b *events.Block, notify func(interface{}) error) error {
+		for _, t := range b.TokenTransfers {
+			if t.Contract != contract {
+				continue
+			}
+
+			if err := notify(t); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
+
+// Reorgs sends the reorgs the crawler rolls back, blocks above the common ancestor were sent before and are gone
+
+func (s *subscriptions) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	return s.subscribe(ctx, nil, func(r *models.Reorg, notify func(interface{}) error) error {
+		return notify(r)
+	})
+}
+
+// subscribe creates a subscription that hands the events of the feed to onBlock and onReorg, either may be nil,
+// until the client unsubscribes or goes away
+
+func (s *subscriptions) subscribe(ctx context.Context, onBlock func(*events.Block, func(interface{}) error) error, onReorg func(*models.Reorg, func(interface{}) error) error) (*rpc.Subscription, error) {
+	if s.feed == nil {
+		return &rpc.Subscription{}, errNoFeed
+	}
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	sub := notifier.CreateSubscription()
+
+	notify := func(data interface{}) error {
+		return notifier.Notify(sub.ID, data)
+	}
+
+	var (
+		blocks = make(chan events.Block, subscriptionBuffer)
+		reorgs = make(chan models.Reorg, subscriptionBuffer)
+		subs   []event.Subscription
+
+		// closed or handed ErrSlowSubscriber when the feed drops us, nil for topics that don't take the events
+		blocksErr, reorgsErr <-chan error
+	)
+
+	// topics only subscribe to the events they take, the other channel never receives
+	if onBlock != nil {
+		fs := s.feed.SubscribeBlocks(blocks)
+		subs, blocksErr = append(subs, fs), fs.Err()
+	}
+
+	if onReorg != nil {
+		fs := s.feed.SubscribeReorgs(reorgs)
+		subs, reorgsErr = append(subs, fs), fs.Err()
+	}
+
+	go func() {
+		defer func() {
+			for _, fs := range subs {
+				fs.Unsubscribe()
+			}
+		}()
+
+		for {
+			var err error
+
+			select {
+			case b := <-blocks:
+				err = onBlock(&b, notify)
+			case r := <-reorgs:
+				err = onReorg(&r, notify)
+			case <-blocksErr:
+				return
+			case <-reorgsErr:
+				return
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+
+			// the connection is gone
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
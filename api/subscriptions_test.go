@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/events"
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/rpc"
+)
+
+func TestSubscriptions(t *testing.T) {
+	feed := events.NewFeed()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("explorer", &subscriptions{feed}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	var (
+		blocks = make(chan models.Block, 1)
+		active = make(chan activity, 1)
+		reorgs = make(chan models.Reorg, 1)
+	)
+
+	for topic, sub := range map[string][]interface{}{
+		"newBlocks":       {blocks, "newBlocks"},
+		"addressActivity": {active, "addressActivity", "0xAB"},
+		"reorgs":          {reorgs, "reorgs"},
+	} {
+		s, err := client.Subscribe(ctx, "explorer", sub[0], sub[1:]...)
+		if err != nil {
+			t.Fatalf("%s: %v", topic, err)
+		}
+		defer s.Unsubscribe()
+	}
+
+	// not a match, no activity is sent for it
+	feed.SendBlock(events.Block{Block: models.Block{Number: 1, Transactions: []models.Transaction{{From: "0xcd"}}}})
+	feed.SendBlock(events.Block{
+		Block:          models.Block{Number: 2, Transactions: []models.Transaction{{From: "0xcd"}, {To: "0xab"}}},
+		TokenTransfers: []models.TokenTransfer{{From: "0xab", Contract: "0xef"}},
+	})
+	feed.SendReorg(models.Reorg{Number: 2})
+
+	timeout := time.After(5 * time.Second)
+
+	for _, want := range []uint64{1, 2} {
+		select {
+		case b := <-blocks:
+			if b.Number != want || len(b.Transactions) != 0 {
+				t.Errorf("expected block %d without transactions, got %d with %d", want, b.Number, len(b.Transactions))
+			}
+		case <-timeout:
+			t.Fatalf("no block %d", want)
+		}
+	}
+
+	select {
+	case a := <-active:
+		if a.BlockNumber != 2 || len(a.Transactions) != 1 || len(a.Transfers) != 1 {
+			t.Errorf("unexpected activity %+v", a)
+		}
+	case <-timeout:
+		t.Fatal("no activity")
+	}
+
+	select {
+	case r := <-reorgs:
+		if r.Number != 2 {
+			t.Errorf("expected reorg at 2, got %d", r.Number)
+		}
+	case <-timeout:
+		t.Fatal("no reorg")
+	}
+}
+
+func TestSubscriptionsWithoutFeed(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("explorer", &subscriptions{}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	if _, err := client.Subscribe(context.Background(), "explorer", make(chan models.Block), "newBlocks"); err == nil || err.Error() != errNoFeed.Error() {
+		t.Errorf("expected %v, got %v", errNoFeed, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/storage"
 	"github.com/ubiq/go-ubiq/v7/log"
 	"github.com/ubiq/go-ubiq/v7/rpc"
 )
@@ -26,6 +27,7 @@ type v4api interface {
 	//reorgs
 	ForkedBlockByNumber(number uint64) (models.Block, error)
 	TotalForkedBlockCount() (int64, error)
+	TotalReorgCount() (int64, error)
 
 	//txs
 	TransactionByHash(hash string) (models.Transaction, error)
@@ -36,14 +38,36 @@ type v4api interface {
 	LatestTxTrace() (models.ITransaction, error)
 
 	//transfers
-	TokenTransfersByAccount(account string) ([]models.TokenTransfer, error)
+	TokenTransfersByAccount(account string, page *storage.Page) (map[string]interface{}, error)
 	TokenTransfersByAccountCount(account string) (int64, error)
-	TransfersOfTokenByAccount(token string, account string) ([]models.TokenTransfer, error)
+	TransfersOfTokenByAccount(token string, account string, page *storage.Page) (map[string]interface{}, error)
 	TransfersOfTokenByAccountCount(token string, account string) (int64, error)
-	TransfersByContract(address string) ([]models.TokenTransfer, error)
+	TransfersByContract(address string, page *storage.Page) (map[string]interface{}, error)
 	ContractTransferCount(address string) (int64, error)
 	TotalTransferCount() (int64, error)
 
+	//tokens
+	TokenInfo(address string) (models.Token, error)
+	TotalTokenCount() (int64, error)
+
+	//contracts
+	ContractCreation(address string) (models.ContractCreation, error)
+	ContractsByCreator(creator string, page *storage.Page) (map[string]interface{}, error)
+	ContractsWithSameCode(address string, page *storage.Page) (map[string]interface{}, error)
+
+	//abis
+	ContractABI(address string) (models.ContractABI, error)
+	DecodeTransaction(hash string) (models.DecodedTransaction, error)
+	LookupSelector(hash string) ([]models.Signature, error)
+	VerifiedContract(address string) (models.VerifiedContract, error)
+
+	//nfts
+	NFTOwner(contract string, tokenId string) (string, error)
+	TotalNFTTransferCount() (int64, error)
+
+	//accounts
+	BalanceAt(account string, block uint64) (models.BalanceRecord, error)
+
 	//charts
 	GetNumberChart(name string, limit int) (models.NumberChart, error)
 	GetNumberStringChart(name string, limit int) (models.NumberStringChart, error)
@@ -51,22 +75,30 @@ type v4api interface {
 	ListCharts() ([]string, error)
 
 	//api-specific
-	LatestBlocks(limit int64) (map[string]interface{}, error)
-	LatestMinedBlocks(account string, limit int64) (map[string]interface{}, error)
-	LatestUncles(limit int64) (map[string]interface{}, error)
-	LatestForkedBlocks(limit int64) (map[string]interface{}, error)
-	LatestTransactions(limit int64) (map[string]interface{}, error)
-	LatestTokenTransfers(limit int64) (map[string]interface{}, error)
-	LatestTransfersOfToken(account string) (map[string]interface{}, error)
-	LatestTokenTransfersByAccount(account string) (map[string]interface{}, error)
-	LatestTransactionsByAccount(account string) (map[string]interface{}, error)
-	LatestFailedTransactions(limit int64) (map[string]interface{}, error)
-	LatestContractCalls(limit int64) (map[string]interface{}, error)
-	LatestContractsDeployed(limit int64) (map[string]interface{}, error)
+	LatestBlocks(page *storage.Page) (map[string]interface{}, error)
+	LatestMinedBlocks(account string, page *storage.Page) (map[string]interface{}, error)
+	LatestUncles(page *storage.Page) (map[string]interface{}, error)
+	LatestForkedBlocks(page *storage.Page) (map[string]interface{}, error)
+	LatestReorgs(page *storage.Page) (map[string]interface{}, error)
+	LatestTransactions(page *storage.Page) (map[string]interface{}, error)
+	LatestTokenTransfers(page *storage.Page) (map[string]interface{}, error)
+	LatestTransfersOfToken(account string, page *storage.Page) (map[string]interface{}, error)
+	LatestTokenTransfersByAccount(account string, page *storage.Page) (map[string]interface{}, error)
+	LatestTransactionsByAccount(account string, page *storage.Page) (map[string]interface{}, error)
+	LatestFailedTransactions(page *storage.Page) (map[string]interface{}, error)
+	LatestContractCalls(page *storage.Page) (map[string]interface{}, error)
+	LatestContractsDeployed(page *storage.Page) (map[string]interface{}, error)
+	ListTokens(page *storage.Page) (map[string]interface{}, error)
+	LatestNFTTransfersByContract(contract string, page *storage.Page) (map[string]interface{}, error)
+	LatestNFTTransfersByToken(contract string, tokenId string, page *storage.Page) (map[string]interface{}, error)
+	LatestNFTTransfersByAccount(account string, page *storage.Page) (map[string]interface{}, error)
+	TokenHolders(contract string, page *storage.Page) (map[string]interface{}, error)
+	TokenBalancesByAccount(account string, page *storage.Page) (map[string]interface{}, error)
 
 	//accounts
-	AccountsByBalance(limit int64) (map[string]interface{}, error)
-	AccountsByLastSeen(limit int64) (map[string]interface{}, error)
+	AccountsByBalance(page *storage.Page) (map[string]interface{}, error)
+	AccountsByLastSeen(page *storage.Page) (map[string]interface{}, error)
+	BalanceHistory(account string, page *storage.Page) (map[string]interface{}, error)
 
 	//misc
 	Status() (models.Store, error)
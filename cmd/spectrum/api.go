@@ -2,11 +2,26 @@ package main
 
 import (
 	"github.com/octanolabs/go-spectrum/api"
+	"github.com/octanolabs/go-spectrum/events"
+	"github.com/octanolabs/go-spectrum/rpc"
 	"github.com/octanolabs/go-spectrum/storage"
+	"github.com/octanolabs/go-spectrum/verifier"
 	"github.com/ubiq/go-ubiq/v7/log"
 )
 
-func startApi(mongo *storage.MongoDB, cfg *api.Config, logger log.Logger) {
-	a := api.NewV3ApiServer(mongo, cfg, logger)
+func startApi(backend storage.Backend, cfg *api.Config, logger log.Logger, rpc *rpc.RPCClient, feed *events.Feed) {
+	a := api.NewV3ApiServer(backend, cfg, logger)
+	a.SetFeed(feed)
+
+	if cfg.Verifier.Enabled {
+		a.AddService(verifier.NewVerifier(backend, &cfg.Verifier, logger.New("service", "verifier"), rpc))
+		logger.Info("Contract verification enabled", "solc", cfg.Verifier.SolcDir)
+	}
+
+	if cfg.AbiUploads {
+		a.AddService(api.NewAbiUploads(backend))
+		logger.Info("ABI uploads enabled")
+	}
+
 	a.Start()
 }
@@ -4,26 +4,34 @@ import (
 	"github.com/octanolabs/go-spectrum/crawlers"
 	"github.com/octanolabs/go-spectrum/crawlers/block"
 	"github.com/octanolabs/go-spectrum/crawlers/database"
+	"github.com/octanolabs/go-spectrum/crawlers/tokens"
+	"github.com/octanolabs/go-spectrum/events"
 	"github.com/octanolabs/go-spectrum/rpc"
 	"github.com/octanolabs/go-spectrum/storage"
 	"github.com/ubiq/go-ubiq/v7/log"
 )
 
-func startCrawlers(mongo *storage.MongoDB, cfg *crawlers.Config, logger log.Logger, rpc *rpc.RPCClient) {
+func startCrawlers(backend storage.Backend, cfg *crawlers.Config, logger log.Logger, rpc *rpc.RPCClient, feed *events.Feed) {
 
-	var crawlerMap = make(map[string]crawlers.Crawler, 2)
+	var crawlerMap = make(map[string]crawlers.Crawler, 3)
 
 	if cfg.BlockCrawler.Enabled {
-		blockCrawler := block.NewBlockCrawler(mongo, &cfg.BlockCrawler, logger.New("crawler", "block"), rpc)
+		blockCrawler := block.NewBlockCrawler(backend, &cfg.BlockCrawler, logger.New("crawler", "block"), rpc, feed)
 		logger.Info("Starting block Crawler")
 		crawlerMap["blocks"] = blockCrawler
 	}
 
 	if cfg.DatabaseCrawler.Enabled {
-		dbCrawler := database.NewDbCrawler(mongo, &cfg.DatabaseCrawler, logger.New("crawler", "database"))
+		dbCrawler := database.NewDbCrawler(backend, &cfg.DatabaseCrawler, logger.New("crawler", "database"))
 		logger.Info("Starting database crawler")
 		crawlerMap["database"] = dbCrawler
 	}
 
+	if cfg.TokenCrawler.Enabled {
+		tokenCrawler := tokens.NewTokenCrawler(backend, &cfg.TokenCrawler, logger.New("crawler", "tokens"), rpc)
+		logger.Info("Starting token crawler")
+		crawlerMap["tokens"] = tokenCrawler
+	}
+
 	crawlers.RunCrawlers(crawlerMap, cfg, logger)
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/ubiq/go-ubiq/v7/log"
 
 	"github.com/octanolabs/go-spectrum/config"
+	"github.com/octanolabs/go-spectrum/events"
 	"github.com/octanolabs/go-spectrum/params"
 	"github.com/octanolabs/go-spectrum/rpc"
 	"github.com/octanolabs/go-spectrum/storage"
@@ -29,6 +30,7 @@ var (
 	loguiHandler *logui.PassthroughHandler
 
 	enableLogUi    bool
+	migrateOnly    bool
 	logLevel       string
 	configFileName string
 )
@@ -51,6 +53,8 @@ func init() {
 
 	flag.BoolVar(&enableLogUi, "logui", false, "Enables logui")
 
+	flag.BoolVar(&migrateOnly, "migrate", false, "apply pending database migrations and exit")
+
 	flag.Parse()
 
 	if enableLogUi {
@@ -90,28 +94,59 @@ func main() {
 		mainLogger.Info("App running with 1 thread")
 	}
 
-	mainLogger.Debug("Connecting to mongo", "addr", cfg.Mongo.ConnectionString())
+	storageCfg := &cfg.Storage
+	if storageCfg.Symbol == "" {
+		mainLogger.Warn("no storage section in config, using mongo")
+		storageCfg = &cfg.Mongo
+	}
+
+	dbType := storageCfg.Type
+	if dbType == "" {
+		dbType = storage.BackendMongo
+	}
 
-	mongo, err := storage.NewConnection(&cfg.Mongo) // TODO - iquidus: fix this check
+	if dbType == storage.BackendMemory {
+		mainLogger.Warn("using in-memory storage, nothing will be persisted")
+	} else {
+		mainLogger.Debug("Connecting to "+dbType, "addr", storageCfg.Address)
+	}
+
+	backend, err := storage.New(storageCfg) // TODO - iquidus: fix this check
 
 	if err != nil {
-		mainLogger.Error("can't establish connection to mongo", "err", err)
+		mainLogger.Error("can't establish connection to "+dbType, "err", err)
+		os.Exit(1)
 	} else {
-		mainLogger.Info("Successfully connected to mongo", "addr", cfg.Mongo.Address)
+		mainLogger.Info("Successfully connected to "+dbType, "addr", storageCfg.Address)
 	}
 
-	err = mongo.Ping()
+	err = backend.Ping()
 
 	if err != nil {
-		mainLogger.Error("Can't establish connection to mongo", "err", err)
+		mainLogger.Error("Can't establish connection to "+dbType, "err", err)
 	} else {
-		mainLogger.Info("mongo: PONG")
+		mainLogger.Info(dbType + ": PONG")
+	}
+
+	if migrateOnly {
+		if err := backend.Migrate(); err != nil {
+			mainLogger.Error("couldn't migrate database", "err", err)
+			os.Exit(1)
+		}
+
+		mainLogger.Info("database is up to date")
+		os.Exit(0)
 	}
 
 	rpcClient := rpc.NewRPCClient(&cfg.Rpc)
 
 	version, err := rpcClient.Ping()
 
+	if err == rpc.ErrNoHealthyNodes {
+		mainLogger.Error("no gubiq node available", "err", err)
+		os.Exit(1)
+	}
+
 	if err != nil {
 		switch err.(type) {
 		case *url.Error:
@@ -124,15 +159,28 @@ func main() {
 
 	mainLogger.Info("connected to gubiq rpc server", "version", version)
 
-	if mongo.IsFirstRun() {
-		mongo.Init(rpcClient)
-		mainLogger.Warn("mongo: initialized sysStore, genesis, indexes")
+	if backend.IsFirstRun() {
+		backend.Init(rpcClient)
+		mainLogger.Warn(dbType + ": initialized sysStore, genesis")
 	}
 
-	if cfg.Crawlers.Enabled {
-		go startCrawlers(mongo, &cfg.Crawlers, appLogger, rpcClient)
+	if err := backend.Migrate(); err != nil {
+		mainLogger.Error("couldn't migrate database", "err", err)
+		os.Exit(1)
+	}
+
+	importSignatures(backend, cfg.Signatures)
+
+	if cfg.Crawlers.Enabled && cfg.Api.Enabled && cfg.Api.Subscriptions {
+		// websocket subscriptions are fed by the block crawler, so both run in this process
+		feed := events.NewFeed()
+
+		go startCrawlers(backend, &cfg.Crawlers, appLogger, rpcClient, feed)
+		go startApi(backend, &cfg.Api, appLogger.New("pkg", "api"), rpcClient, feed)
+	} else if cfg.Crawlers.Enabled {
+		go startCrawlers(backend, &cfg.Crawlers, appLogger, rpcClient, nil)
 	} else if cfg.Api.Enabled {
-		go startApi(mongo, &cfg.Api, appLogger.New("pkg", "api"))
+		go startApi(backend, &cfg.Api, appLogger.New("pkg", "api"), rpcClient, nil)
 	} else {
 		mainLogger.Error("No crawlers enabled. exiting.")
 		os.Exit(1)
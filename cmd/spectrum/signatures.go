@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/octanolabs/go-spectrum/signatures"
+	"github.com/octanolabs/go-spectrum/storage"
+)
+
+// importSignatures adds the built in signatures, and those in file if set, to the signature db
+
+func importSignatures(backend storage.Backend, file string) {
+	sigs := signatures.Builtin()
+
+	if file != "" {
+		loaded, err := signatures.LoadFile(file)
+		if err != nil {
+			mainLogger.Error("couldn't load signatures", "file", file, "err", err)
+		} else {
+			sigs = append(sigs, loaded...)
+		}
+	}
+
+	added, err := backend.ImportSignatures(sigs)
+	if err != nil {
+		mainLogger.Error("couldn't import signatures", "err", err)
+		return
+	}
+
+	mainLogger.Info("imported signatures", "added", added, "total", len(sigs))
+}
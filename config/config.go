@@ -10,9 +10,13 @@ import (
 type Config struct {
 	Threads  int             `json:"threads"`
 	Crawlers crawlers.Config `json:"crawlers"`
-	Mongo    storage.Config  `json:"mongo"`
+	Storage  storage.Config  `json:"storage"`
 	Rpc      rpc.Config      `json:"rpc"`
 	Api      api.Config      `json:"api"`
+	// Mongo is the storage section of older configs, it's used when there's no storage section
+	Mongo storage.Config `json:"mongo"`
+	// Signatures is a file of function and event signatures to import on startup, along with the built in ones
+	Signatures string `json:"signatures"`
 }
 
 // {
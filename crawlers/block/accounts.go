@@ -0,0 +1,108 @@
+package block
+
+import (
+	"fmt"
+	"sort"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/storage"
+)
+
+// updateAccounts adds the accounts whose state changed in block to batch. By default the balance of every
+// account in the cache is fetched with eth_getBalance; in prestate mode the changes are taken from the state
+// diffs of the block's transactions instead, which also catch accounts only reached by internal transfers
+// or self-destructs. Either way, the miners are fetched as rewards are credited after the last transaction
+
+func (c *Crawler) updateAccounts(block *models.Block, accounts *lru.Cache, batch *storage.Batch) {
+	var (
+		states map[string]*models.AccountState
+		fetch  []string
+	)
+
+	if c.cfg.Balances == balancesPrestate && len(block.RawTransactions) > 0 {
+		var err error
+
+		states, err = c.getStateChanges(block.Number, len(block.RawTransactions))
+		if err != nil {
+			c.logger.Error("couldn't trace state changes, getting balances instead", "err", err, "block", block.Number)
+			states = nil
+		}
+	}
+
+	if states != nil {
+		miners := map[string]bool{block.Miner: true}
+		for _, u := range batch.Uncles {
+			miners[u.Miner] = true
+		}
+
+		for address := range miners {
+			fetch = append(fetch, address)
+		}
+
+		addresses := make([]string, 0, len(states))
+		for address := range states {
+			if !miners[address] {
+				addresses = append(addresses, address)
+			}
+		}
+		sort.Strings(addresses)
+
+		for _, address := range addresses {
+			batch.Accounts = append(batch.Accounts, stateAccount(address, block.Number, states[address]))
+		}
+	} else {
+		for _, k := range accounts.Keys() {
+			fetch = append(fetch, fmt.Sprintf("%v", k))
+		}
+	}
+
+	balances, err := c.rpc.GetBalances(fetch, block.Number)
+	if err != nil {
+		c.logger.Error("couldn't get balances", "err", err, "block", block.Number)
+	}
+
+	for x, address := range fetch {
+		if balances[x] == nil {
+			continue
+		}
+
+		account := &models.Account{Address: address, Balance: balances[x].String(), Block: block.Number}
+
+		// the diffs still know the miner's nonce
+		if s, ok := states[address]; ok {
+			account = stateAccount(address, block.Number, s)
+			account.Balance = balances[x].String()
+		}
+
+		batch.Accounts = append(batch.Accounts, account)
+	}
+}
+
+// getStateChanges returns the state of every account modified by the transactions in a block
+
+func (c *Crawler) getStateChanges(blockNumber uint64, txns int) (map[string]*models.AccountState, error) {
+	diffs, err := c.rpc.TraceBlockStateDiffs(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(diffs) != txns {
+		return nil, fmt.Errorf("got %v state diffs for %v transactions", len(diffs), txns)
+	}
+
+	states := make(map[string]*models.AccountState)
+
+	for _, diff := range diffs {
+		models.ApplyStateDiff(states, diff)
+	}
+
+	return states, nil
+}
+
+func stateAccount(address string, blockNumber uint64, s *models.AccountState) *models.Account {
+	nonce, hasCode := s.Nonce, s.HasCode
+
+	return &models.Account{Address: address, Balance: s.Balance.String(), Block: blockNumber, Nonce: &nonce, HasCode: &hasCode}
+}
@@ -2,7 +2,6 @@ package block
 
 import (
 	"errors"
-	"fmt"
 	"math/big"
 	"strconv"
 	"time"
@@ -10,17 +9,28 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/storage"
 
 	"github.com/octanolabs/go-spectrum/syncronizer"
 )
 
 func (c *Crawler) RunLoop() {
 
-	c.logChan = make(chan *logObject)
+	for {
+		c.logChan = make(chan *logObject)
 
-	c.crawBlocks()
+		c.crawBlocks()
 
-	close(c.logChan)
+		close(c.logChan)
+
+		if !c.state.reorg {
+			break
+		}
+
+		// blocks were rolled back to the common ancestor, sync forward again right away
+		c.state.reorg = false
+		c.state.syncing = false
+	}
 
 	err := c.backend.UpdateStore()
 
@@ -41,13 +51,23 @@ func (c *Crawler) crawBlocks() {
 
 	c.state.syncing = true
 
+	// purge anything past the checkpoint left by an aborted sync or a crash
+	if c.state.dirty {
+		err := c.recover()
+		if err != nil {
+			c.logger.Error("couldn't recover from checkpoint", "err", err)
+			return
+		}
+	}
+
 	// get db head
-	indexHead, err := c.backend.LatestBlock()
+	indexHead, err := c.backend.Checkpoint(checkpointName)
 	if err != nil {
-		c.logger.Error("couldn't get latest block", "err", err)
+		c.logger.Error("couldn't get checkpoint", "err", err)
+		return
 	}
 
-	c.logger.Debug("fetched block from db", "number", indexHead)
+	c.logger.Debug("fetched checkpoint from db", "number", indexHead.Number, "hash", indexHead.Hash)
 
 	// get node head
 	chainHead, err := c.rpc.LatestBlockNumber()
@@ -60,6 +80,11 @@ func (c *Crawler) crawBlocks() {
 	syncLogger := c.logger.New("pkg", "sync", "blockNumber", strconv.FormatInt(int64(currentBlock), 10))
 	startLogger(c.logChan, syncLogger)
 
+	if c.cfg.Bulk.Enabled && chainHead > indexHead.Number+c.cfg.Bulk.Distance {
+		syncLogger.Info("catching up, using bulk writes", "behind", chainHead-indexHead.Number)
+		c.bulk = c.newBulkWriter()
+	}
+
 	start := time.Now()
 
 	syncLogger.Debug("started sync at", "t", start)
@@ -96,6 +121,11 @@ func (c *Crawler) crawBlocks() {
 
 	abort := taskChain.Finish()
 
+	if c.bulk != nil {
+		c.flush()
+		c.bulk = nil
+	}
+
 	if abort {
 		syncLogger.Error("aborted")
 	} else {
@@ -116,6 +146,8 @@ func (c *Crawler) syncBlock(block models.Block, task *syncronizer.Task) {
 
 	accountsCache, _ := lru.New(1024)
 
+	batch := &storage.Batch{Checkpoint: checkpointName}
+
 	// get parent block info
 	prevBlock, err := c.getPreviousBlock(block.Number)
 
@@ -132,8 +164,9 @@ func (c *Crawler) syncBlock(block models.Block, task *syncronizer.Task) {
 
 	if pHash != block.ParentHash {
 		// If pHash != to currBlock's parentHash, pHash has reorg'd
-		// we remove phash from blocks collection and insert into Forkedblocks collection
-		// then we abort sync so that we can sync missing blocks
+		// we roll back every block up to the common ancestor, moving them to the forkedblocks collection
+		// then we abort sync so that we can sync the new chain
+		c.flush()
 		c.handleReorg(block)
 
 		task.AbortSync()
@@ -150,7 +183,7 @@ func (c *Crawler) syncBlock(block models.Block, task *syncronizer.Task) {
 	// add miner to accounts Cache
 	accountsCache.Add(block.Miner, true)
 
-	blockReward, uncleRewards, minted := c.processUncles(&block, uncles, accountsCache)
+	blockReward, uncleRewards, minted := c.processUncles(&block, uncles, accountsCache, batch)
 
 	// add minted to supply
 	var supply = new(big.Int)
@@ -167,7 +200,16 @@ func (c *Crawler) syncBlock(block models.Block, task *syncronizer.Task) {
 	transactions := make([]models.Transaction, len(block.Transactions))
 	itxns := make([]models.ITransaction, 0)
 	if len(block.Transactions) > 0 {
-		transactions, itxns, avgGasPrice, txFees, tokenTransfers, contractsDeployed, contractCalls = c.processTransactions(block.RawTransactions, block.Timestamp, block.BaseFeePerGas, accountsCache)
+		traces := c.getBlockTraces(&block)
+		transactions, itxns, avgGasPrice, txFees, tokenTransfers, contractsDeployed, contractCalls, err = c.processTransactions(block.RawTransactions, traces, block.Timestamp, block.BaseFeePerGas, accountsCache, batch)
+
+		// the block is synced again rather than written without receipts
+		if err != nil {
+			c.logger.Error("couldn't get tx receipts", "err", err, "block", block.Number)
+
+			task.AbortSync()
+			return
+		}
 	}
 
 	// combine rewards as minted
@@ -185,20 +227,7 @@ func (c *Crawler) syncBlock(block models.Block, task *syncronizer.Task) {
 	block.TotalBurned = totalBurned.String()
 
 	// if block contains transactions update accounts
-	accounts := accountsCache.Keys()
-	for x := 0; x < len(accounts); x++ {
-		address := fmt.Sprintf("%v", accounts[x])
-		balance, fail := c.rpc.GetBalance(address, block.Number)
-		if fail != nil {
-			c.logger.Error("couldn't get balance", "err", fail, "address", address, "balance", balance.String())
-		} else {
-			account := models.Account{Address: address, Balance: balance.String(), Block: block.Number}
-			err = c.backend.AddAccount(&account)
-			if err != nil {
-				c.logger.Error("couldn't add account", "err", err, "address", account.Address)
-			}
-		}
-	}
+	c.updateAccounts(&block, accountsCache, batch)
 
 	// clear cache
 	accountsCache.Purge()
@@ -215,38 +244,32 @@ func (c *Crawler) syncBlock(block models.Block, task *syncronizer.Task) {
 	// 	block.Trace = trace
 	// }
 
-	// write block to db
-	err = c.backend.AddBlock(&block)
-	if err != nil {
-		c.logger.Error("couldn't add block", "err", err)
-	}
+	// write block and its documents to db, and move checkpoint
+	batch.Block = &block
 
-	// add required block info to cache for next iteration
-	c.blockCache.Add(block.Number, blockCache{Supply: supply, Hash: block.Hash, TotalBurned: totalBurned})
+	if err := c.updateTokenBalances(batch); err != nil {
+		c.logger.Error("couldn't get token balances", "err", err, "block", block.Number)
 
-	c.log(block.Number, len(block.Transactions), tokenTransfers, contractsDeployed, contractCalls, block.UncleNo, minted, supply)
-}
-
-func (c *Crawler) syncForkedBlock(b models.Block) {
+		task.AbortSync()
+		return
+	}
 
-	reorgHeight := b.Number - 1
+	if err := c.updateContractCode(batch); err != nil {
+		c.logger.Error("couldn't get contract code", "err", err, "block", block.Number)
 
-	dbBlock, err := c.backend.BlockByNumber(reorgHeight)
-	if err != nil {
-		c.logger.Error("couldn't get forked block", "err", err)
+		task.AbortSync()
+		return
 	}
 
-	err = c.backend.AddForkedBlock(&dbBlock)
-	if err != nil {
-		c.logger.Error("couldn't add reorg'd block", "err", err)
+	if !c.commit(batch) {
+		task.AbortSync()
+		return
 	}
 
-	err = c.backend.PurgeBlock(reorgHeight)
-	if err != nil {
-		c.logger.Error("couldn't purge reorg'd block", "err", err)
-	}
+	// add required block info to cache for next iteration
+	c.blockCache.Add(block.Number, blockCache{Supply: supply, Hash: block.Hash, TotalBurned: totalBurned})
 
-	c.logger.Warn("Synced forked block", "HEAD", fmt.Sprintf("(number: %v, hash: %v)", b.Number, b.Hash), "FORKED", fmt.Sprintf("(number: %v, hash: %v)", dbBlock.Number, dbBlock.Hash))
+	c.log(block.Number, len(block.Transactions), tokenTransfers, contractsDeployed, contractCalls, block.UncleNo, minted, supply)
 }
 
 type data struct {
@@ -254,7 +277,7 @@ type data struct {
 	tokenTransfers, contractCalls, contractsDeployed int
 }
 
-func (c *Crawler) processUncles(block *models.Block, uncles []models.Uncle, accounts *lru.Cache) (*big.Int, *big.Int, *big.Int) {
+func (c *Crawler) processUncles(block *models.Block, uncles []models.Uncle, accounts *lru.Cache, batch *storage.Batch) (*big.Int, *big.Int, *big.Int) {
 
 	var (
 		uRewards = new(big.Int)
@@ -262,7 +285,8 @@ func (c *Crawler) processUncles(block *models.Block, uncles []models.Uncle, acco
 
 	blockReward, uncleRewards, minted := AccumulateRewards(block, uncles)
 
-	for idx, uncle := range uncles {
+	for idx := range uncles {
+		uncle := uncles[idx]
 		accounts.ContainsOrAdd(uncle.Miner, true)
 		uncle.BlockNumber = block.Number
 		uncle.Position = uint64(idx)
@@ -270,11 +294,7 @@ func (c *Crawler) processUncles(block *models.Block, uncles []models.Uncle, acco
 
 		uRewards.Add(uRewards, uncleRewards[idx])
 
-		err := c.backend.AddUncle(&uncle)
-
-		if err != nil {
-			c.logger.Error("couldn't add uncle", "uncle", uncle)
-		}
+		batch.Uncles = append(batch.Uncles, &uncle)
 	}
 
 	return blockReward, uRewards, minted
@@ -297,14 +317,10 @@ func (c *Crawler) proccessItxns(call models.ITransaction, parentHash string, blo
 			GasUsed:     call.GasUsed,
 			Input:       call.Input,
 			Output:      call.Output,
+			Error:       call.Error,
 			Calls:       call.Calls,
 		}
 		iTransactions = append(iTransactions, itxn)
-
-		err := c.backend.AddInternalTransaction(&itxn)
-		if err != nil {
-			c.logger.Error("couldn't add internal transaction", "itxn", itxn, "err", err)
-		}
 	}
 
 	if len(call.Calls) > 0 {
@@ -320,7 +336,11 @@ func (c *Crawler) proccessItxns(call models.ITransaction, parentHash string, blo
 	return iTransactions
 }
 
-func (c *Crawler) processTransactions(txs []models.RawTransaction, timestamp uint64, baseFeePerGas string, accounts *lru.Cache) (transactions []models.Transaction, itxns []models.ITransaction, avgGasPrice, txFees *big.Int, tokenTransfers, contractsDeployed, contractCalls int) {
+// processTransactions adds every transaction of a block to batch. traces holds the call trace of each transaction
+// when the block was traced as a whole, otherwise they are traced one by one unless tracing is disabled.
+// It fails, leaving batch as it was, when the receipts can't be fetched
+
+func (c *Crawler) processTransactions(txs []models.RawTransaction, traces []*models.ITransaction, timestamp uint64, baseFeePerGas string, accounts *lru.Cache, batch *storage.Batch) (transactions []models.Transaction, itxns []models.ITransaction, avgGasPrice, txFees *big.Int, tokenTransfers, contractsDeployed, contractCalls int, err error) {
 
 	data := &data{
 		gasPrice:          big.NewInt(0),
@@ -332,13 +352,26 @@ func (c *Crawler) processTransactions(txs []models.RawTransaction, timestamp uin
 
 	transactions = make([]models.Transaction, len(txs))
 	itxns = make([]models.ITransaction, 0)
+
+	hashes := make([]string, len(txs))
+	for i := range txs {
+		hashes[i] = txs[i].Hash
+	}
+
+	receipts, err := c.rpc.GetTxReceipts(hashes)
+	if err != nil {
+		return
+	}
+
 	// maxRoutines equal to 2 times the number of txs to account for possible token transfers
 	txSync := syncronizer.NewSync(len(txs) * 2)
 
-	for _, val := range txs {
+	for i, val := range txs {
 
 		// Capture value of rawTx
+		i := i
 		rt := val
+		receipt := receipts[i]
 
 		tx := rt.Convert()
 
@@ -347,26 +380,44 @@ func (c *Crawler) processTransactions(txs []models.RawTransaction, timestamp uin
 
 		txSync.AddLink(func(t *syncronizer.Task) {
 
-			receipt, err := c.rpc.GetTxReceipt(tx.Hash)
-			if err != nil {
-				c.logger.Error("couldn't get tx receipt", "err", err)
+			var trace *models.ITransaction
+
+			if traces != nil {
+				trace = traces[i]
+			} else if c.cfg.Tracing.Mode != traceNone {
+				trace = c.getTransactionTrace(tx)
 			}
+
+			if tx.To != "" {
+				tx.MethodName = c.methodName(tx.Input)
+			}
+
 			closed := t.Link()
 
 			if closed {
 				return
 			}
 
-			c.processTransaction(&tx, receipt, data, baseFeePerGas, accounts)
+			c.processTransaction(&tx, receipt, trace, data, baseFeePerGas, accounts, batch)
+
+			if c.cfg.Transfers != transfersInput {
+				for _, transfer := range tx.GetLogTransfers() {
+					data.tokenTransfers++
+					batch.TokenTransfers = append(batch.TokenTransfers, transfer)
+				}
+			}
+
+			batch.NFTTransfers = append(batch.NFTTransfers, tx.GetNFTTransfers()...)
+
 			transactions[tx.TransactionIndex] = tx
 			if len(tx.ITransactions) > 0 {
 				itxns = append(itxns, tx.ITransactions...)
 			}
 		})
 
-		// If tx is a token transfer we add another link right after
+		// With legacy transfer detection, if tx is a token transfer we add another link right after
 
-		if tx.IsTokenTransfer() {
+		if c.cfg.Transfers == transfersInput && tx.IsTokenTransfer() {
 
 			data.tokenTransfers++
 			txSync.AddLink(func(task *syncronizer.Task) {
@@ -379,17 +430,17 @@ func (c *Crawler) processTransactions(txs []models.RawTransaction, timestamp uin
 					return
 				}
 
-				c.processTokenTransfer(transfer, &tx)
+				c.processTokenTransfer(transfer, &tx, batch)
 			})
 		}
 	}
 
 	txSync.Finish()
 
-	return transactions, itxns, data.gasPrice.Div(data.gasPrice, big.NewInt(int64(len(txs)))), data.txFees, data.tokenTransfers, data.contractsDeployed, data.contractCalls
+	return transactions, itxns, data.gasPrice.Div(data.gasPrice, big.NewInt(int64(len(txs)))), data.txFees, data.tokenTransfers, data.contractsDeployed, data.contractCalls, nil
 }
 
-func (c *Crawler) processTransaction(tx *models.Transaction, receipt models.TxReceipt, data *data, baseFeePerGas string, accounts *lru.Cache) {
+func (c *Crawler) processTransaction(tx *models.Transaction, receipt models.TxReceipt, trace *models.ITransaction, data *data, baseFeePerGas string, accounts *lru.Cache, batch *storage.Batch) {
 
 	txGasPrice := big.NewInt(0).SetUint64(tx.GasPrice)
 
@@ -405,35 +456,29 @@ func (c *Crawler) processTransaction(tx *models.Transaction, receipt models.TxRe
 	tx.Status = receipt.Status
 	tx.BaseFeePerGas = baseFeePerGas
 
-	// perform trace
-	trace := c.getTransactionTrace(*tx)
 	if trace != nil {
 		tx.Trace = *trace
 		// look for internal transactions
 		tx.ITransactions = c.proccessItxns(*trace, tx.Hash, tx.BlockNumber, true)
-	}
 
-	err := c.backend.AddTransaction(tx)
-	if err != nil {
-		c.logger.Error("couldn't insert tx into backend", "err", err)
+		for i := range tx.ITransactions {
+			batch.ITransactions = append(batch.ITransactions, &tx.ITransactions[i])
+		}
 	}
 
+	batch.Transactions = append(batch.Transactions, tx)
+	batch.Creations = append(batch.Creations, tx.GetContractCreations()...)
+
 	if tx.IsContractDeployTxn() {
 		data.contractsDeployed++
 
-		err := c.backend.AddDeployedContract(tx)
-		if err != nil {
-			c.logger.Error("couldn't insert deployed contract into backend", "err", err)
-		}
+		batch.Contracts = append(batch.Contracts, tx)
 	}
 
 	if tx.IsContractCall() {
 		data.contractCalls++
 
-		err := c.backend.AddContractCall(tx)
-		if err != nil {
-			c.logger.Error("couldn't insert contract call into backend", "err", err)
-		}
+		batch.ContractCalls = append(batch.ContractCalls, tx)
 	}
 
 	accounts.ContainsOrAdd(tx.From, true)
@@ -453,16 +498,12 @@ func (c *Crawler) getTransactionTrace(txn models.Transaction) *models.ITransacti
 	return &itx
 }
 
-func (c *Crawler) processTokenTransfer(transfer *models.TokenTransfer, tx *models.Transaction) {
+func (c *Crawler) processTokenTransfer(transfer *models.TokenTransfer, tx *models.Transaction, batch *storage.Batch) {
 
 	// Setting status here as we need to wait for the tx in the previous link to be processed
 	transfer.Status = tx.Status
 
-	err := c.backend.AddTokenTransfer(transfer)
-	if err != nil {
-		c.logger.Error("couldn't insert token transfer into backend", "err", err)
-	}
-
+	batch.TokenTransfers = append(batch.TokenTransfers, transfer)
 }
 
 func (c *Crawler) getPreviousBlock(blockNumber uint64) (blockCache, error) {
@@ -491,22 +532,6 @@ func (c *Crawler) getPreviousBlock(blockNumber uint64) (blockCache, error) {
 	}
 }
 
-func (c *Crawler) handleReorg(b models.Block) {
-
-	// a reorg has occured
-	c.logger.Warn("reorg detected", "height", b.Number-1)
-
-	// clear cache
-	c.logger.Warn("Purging block cache.")
-	c.blockCache.Purge()
-
-	// sync forked Block and remove parent Block from db
-	c.syncForkedBlock(b)
-
-	c.logger.Warn("synced forked block and purged reorg from blocks collection", "height", b.Number-1)
-
-}
-
 func (c *Crawler) log(blockNo uint64, txns, transfers, contractsDeployed, contractCalls, uncles int, minted *big.Int, supply *big.Int) {
 	c.logChan <- &logObject{
 		blockNo:           blockNo,
@@ -0,0 +1,122 @@
+package block
+
+import (
+	"time"
+
+	"github.com/octanolabs/go-spectrum/events"
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/storage"
+)
+
+// recover brings the database back to the last checkpoint, purging whatever a previous run
+// wrote for blocks past it (e.g. a block whose transactions were inserted before the process was killed).
+// Databases synced before checkpoints existed get one from their latest block, as blocks are written
+// after their transactions
+
+func (c *Crawler) recover() error {
+
+	cp, err := c.backend.Checkpoint(checkpointName)
+
+	if err == storage.ErrNotFound {
+		latest, err := c.backend.LatestBlock()
+		if err != nil {
+			return err
+		}
+
+		c.logger.Warn("no checkpoint found, using latest block", "number", latest.Number, "hash", latest.Hash)
+
+		err = c.backend.SetCheckpoint(checkpointName, latest.Number, latest.Hash)
+		if err != nil {
+			return err
+		}
+
+		cp = models.Checkpoint{Crawler: checkpointName, Number: latest.Number, Hash: latest.Hash}
+	} else if err != nil {
+		return err
+	}
+
+	err = c.backend.PurgeFrom(cp.Number + 1)
+	if err != nil {
+		return err
+	}
+
+	c.blockCache.Purge()
+
+	c.state.dirty = false
+
+	c.logger.Info("resuming from checkpoint", "number", cp.Number, "hash", cp.Hash)
+
+	return nil
+}
+
+// commit writes the block with all its documents and moves the checkpoint to it, or queues it when bulk writes
+// are in use. If the write fails the checkpoint stays where it was, and anything that made it to the db
+// is purged before the next sync. Blocks written one by one are sent to the feed, those written in bulk while
+// catching up aren't, subscribers are after the head of the chain
+
+func (c *Crawler) commit(batch *storage.Batch) bool {
+	var err error
+
+	if c.bulk != nil {
+		err = c.bulk.Add(batch)
+	} else {
+		err = c.backend.CommitBlock(batch)
+	}
+
+	if err != nil {
+		c.logger.Error("couldn't commit block", "err", err, "number", batch.Block.Number, "hash", batch.Block.Hash)
+		c.state.dirty = true
+		return false
+	}
+
+	if c.bulk == nil {
+		c.publish(batch)
+	}
+
+	return true
+}
+
+// publish sends a committed block to the feed
+
+func (c *Crawler) publish(batch *storage.Batch) {
+	if c.feed == nil {
+		return
+	}
+
+	b := events.Block{Block: *batch.Block, TokenTransfers: make([]models.TokenTransfer, len(batch.TokenTransfers))}
+
+	for i, t := range batch.TokenTransfers {
+		b.TokenTransfers[i] = *t
+	}
+
+	c.feed.SendBlock(b)
+}
+
+// flush writes blocks queued for bulk writes, if any
+
+func (c *Crawler) flush() {
+	if c.bulk == nil {
+		return
+	}
+
+	err := c.bulk.Flush()
+	if err != nil {
+		c.logger.Error("couldn't flush bulk writes", "err", err)
+		c.state.dirty = true
+	}
+}
+
+func (c *Crawler) newBulkWriter() storage.BatchWriter {
+	var interval time.Duration
+
+	if c.cfg.Bulk.Interval != "" {
+		d, err := time.ParseDuration(c.cfg.Bulk.Interval)
+		if err != nil {
+			c.logger.Error("can't parse bulk interval", "d", c.cfg.Bulk.Interval, "err", err)
+		} else {
+			interval = d
+		}
+	}
+
+	return c.backend.NewBulkWriter(c.cfg.Bulk.Size, interval)
+}
@@ -0,0 +1,47 @@
+package block
+
+import (
+	"github.com/ubiq/go-ubiq/v7/common/hexutil"
+	"github.com/ubiq/go-ubiq/v7/crypto"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/storage"
+)
+
+// updateContractCode sets the runtime code of the contracts created in batch, as of the batch's block.
+// Contracts the node has no code for, because they self-destructed in the same block, are dropped. It fails
+// when the code can't be fetched, the block is synced again rather than written without its contracts
+
+func (c *Crawler) updateContractCode(batch *storage.Batch) error {
+
+	if len(batch.Creations) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, len(batch.Creations))
+	for i, creation := range batch.Creations {
+		addresses[i] = creation.Address
+	}
+
+	codes, err := c.rpc.GetCodes(addresses, batch.Block.Number)
+	if err != nil {
+		return err
+	}
+
+	creations := make([]*models.ContractCreation, 0, len(batch.Creations))
+
+	for i, creation := range batch.Creations {
+		if len(codes[i]) == 0 {
+			continue
+		}
+
+		creation.Code = hexutil.Encode(codes[i])
+		creation.CodeHash = crypto.Keccak256Hash(codes[i]).Hex()
+
+		creations = append(creations, creation)
+	}
+
+	batch.Creations = creations
+
+	return nil
+}
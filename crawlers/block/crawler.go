@@ -4,13 +4,27 @@ import (
 	"math/big"
 
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/octanolabs/go-spectrum/events"
 	"github.com/octanolabs/go-spectrum/rpc"
 	"github.com/octanolabs/go-spectrum/storage"
 	"github.com/ubiq/go-ubiq/v7/log"
 )
 
 const (
-	blockCacheLimit = 10
+	blockCacheLimit  = 10
+	methodCacheLimit = 4096
+	checkpointName   = "blocks"
+	maxReorgDepth    = 1024
+
+	traceNone  = "none"
+	traceTx    = "tx"
+	traceBlock = "block"
+
+	transfersLogs  = "logs"
+	transfersInput = "input"
+
+	balancesRPC      = "rpc"
+	balancesPrestate = "prestate"
 )
 
 type blockCache struct {
@@ -26,24 +40,46 @@ type Config struct {
 	Tracing     struct {
 		StartBlock uint64 `json:"start_block"`
 		BatchSize  int    `json:"batch_size"`
+		// Mode is one of "none", "tx" (debug_traceTransaction for each transaction) or "block" (one debug_traceBlockByNumber per block)
+		Mode string `json:"mode"`
 	} `json:"tracing"`
+	// Transfers is "logs" to index token transfers from Transfer events, or "input" for the legacy detection
+	// based on the selector of the transaction input
+	Transfers string `json:"transfers"`
+	// Balances is "rpc" to refresh the balance of accounts seen in a block with eth_getBalance, or "prestate" to
+	// take every balance and nonce change from a prestateTracer trace of the block
+	Balances string `json:"balances"`
+	// TokenBalances keeps the balance of token holders up to date, calling balanceOf for those touched by a block
+	TokenBalances bool `json:"token_balances"`
+	// Bulk writes are used while the db is more than Distance blocks behind the node
+	Bulk struct {
+		Enabled  bool   `json:"enabled"`
+		Distance uint64 `json:"distance"`
+		Size     int    `json:"size"`
+		Interval string `json:"interval"`
+	} `json:"bulk"`
 }
 
 type Crawler struct {
-	backend *storage.MongoDB
+	backend storage.Backend
 	rpc     *rpc.RPCClient
 	cfg     *Config
 	logChan chan *logObject
 	state   struct {
 		syncing bool
 		reorg   bool
+		dirty   bool // data past the checkpoint may be in the db and has to be purged before syncing
 	}
 	blockCache *lru.Cache // Cache for the most recent blocks
 	logger     log.Logger
+	bulk       storage.BatchWriter // Set while catching up
+	methods    *lru.Cache          // Method names by selector
+	feed       *events.Feed        // Committed blocks and reorgs are sent here, may be nil
 }
 
-func NewBlockCrawler(db *storage.MongoDB, cfg *Config, logger log.Logger, rpc *rpc.RPCClient) *Crawler {
+func NewBlockCrawler(db storage.Backend, cfg *Config, logger log.Logger, rpc *rpc.RPCClient, feed *events.Feed) *Crawler {
 	bc, _ := lru.New(blockCacheLimit)
+	mc, _ := lru.New(methodCacheLimit)
 
-	return &Crawler{db, rpc, cfg, make(chan *logObject), struct{ syncing, reorg bool }{false, false}, bc, logger}
+	return &Crawler{db, rpc, cfg, make(chan *logObject), struct{ syncing, reorg, dirty bool }{false, false, true}, bc, logger, nil, mc, feed}
 }
@@ -0,0 +1,33 @@
+package block
+
+import (
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/signatures"
+)
+
+// methodName returns the name of the function whose selector input starts with, as found in the signature db.
+// Unknown selectors are cached too, lookup errors aren't
+
+func (c *Crawler) methodName(input string) string {
+	if len(input) < 10 {
+		return ""
+	}
+
+	selector := strings.ToLower(input[:10])
+
+	if name, ok := c.methods.Get(selector); ok {
+		return name.(string)
+	}
+
+	sig, err := c.backend.MethodSignature(selector)
+	if err != nil {
+		c.logger.Error("couldn't look up method signature", "selector", selector, "err", err)
+		return ""
+	}
+
+	name := signatures.Name(sig)
+	c.methods.Add(selector, name)
+
+	return name
+}
@@ -0,0 +1,87 @@
+package block
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+// handleReorg is called when b's parent doesn't match the block we have at b.Number-1. It walks back until it
+// finds a block that's still canonical, rolls back everything above it in one go, and flags the crawler so it
+// syncs forward again
+
+func (c *Crawler) handleReorg(b models.Block) {
+
+	// a reorg has occured
+	c.logger.Warn("reorg detected", "height", b.Number-1, "head", b.Hash)
+
+	// clear cache
+	c.logger.Warn("Purging block cache.")
+	c.blockCache.Purge()
+
+	ancestor, err := c.findCommonAncestor(b.Number - 1)
+	if err != nil {
+		c.logger.Error("couldn't find common ancestor", "err", err, "height", b.Number-1)
+		return
+	}
+
+	orphaned, err := c.backend.Rollback(ancestor, checkpointName)
+	if err != nil {
+		c.logger.Error("couldn't roll back reorg'd blocks", "err", err, "ancestor", ancestor)
+		c.state.dirty = true
+		return
+	}
+
+	hashes := make([]string, len(orphaned))
+	for i := range orphaned {
+		hashes[i] = orphaned[i].Hash
+	}
+
+	reorg := models.Reorg{
+		Number:    ancestor,
+		Depth:     len(orphaned),
+		Hashes:    hashes,
+		Head:      b.Hash,
+		Timestamp: time.Now().Unix(),
+	}
+
+	err = c.backend.AddReorg(&reorg)
+	if err != nil {
+		c.logger.Error("couldn't add reorg", "err", err)
+	}
+
+	c.feed.SendReorg(reorg)
+
+	c.state.reorg = true
+
+	c.logger.Warn("rolled back reorg'd blocks", "ancestor", ancestor, "depth", len(orphaned), "hashes", hashes)
+}
+
+// findCommonAncestor returns the highest block at or below height that is the same in the db and on the node
+
+func (c *Crawler) findCommonAncestor(height uint64) (uint64, error) {
+
+	for h := height; height-h < maxReorgDepth; h-- {
+		dbBlock, err := c.backend.BlockByNumber(h)
+		if err != nil {
+			return 0, err
+		}
+
+		hash, err := c.rpc.GetBlockHash(h)
+		if err != nil {
+			return 0, err
+		}
+
+		if dbBlock.Hash == hash {
+			return h, nil
+		}
+
+		if h == 0 {
+			return 0, errors.New("genesis block doesn't match")
+		}
+	}
+
+	return 0, errors.New("reorg is deeper than " + strconv.Itoa(maxReorgDepth) + " blocks")
+}
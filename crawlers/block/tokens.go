@@ -0,0 +1,63 @@
+package block
+
+import (
+	"math/big"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/storage"
+	"github.com/ubiq/go-ubiq/v7/common"
+)
+
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// balanceOf(address)
+var balanceOfSelector = []byte{0x70, 0xa0, 0x82, 0x31}
+
+// updateTokenBalances refreshes, with balanceOf at the batch's block, the balance of every holder touched by
+// the token transfers in batch. Holders whose call reverted keep their previous balance, it fails when the
+// node couldn't be asked so the block is synced again
+
+func (c *Crawler) updateTokenBalances(batch *storage.Batch) error {
+
+	if !c.cfg.TokenBalances || len(batch.TokenTransfers) == 0 {
+		return nil
+	}
+
+	var (
+		holders   []models.TokenBalance
+		contracts []string
+		inputs    [][]byte
+		seen      = make(map[[2]string]bool)
+	)
+
+	for _, t := range batch.TokenTransfers {
+		for _, address := range []string{t.From, t.To} {
+			key := [2]string{t.Contract, address}
+
+			if address == "" || address == zeroAddress || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			holders = append(holders, models.TokenBalance{Contract: t.Contract, Address: address, Block: batch.Block.Number})
+			contracts = append(contracts, t.Contract)
+			inputs = append(inputs, append(append([]byte{}, balanceOfSelector...), common.LeftPadBytes(common.HexToAddress(address).Bytes(), 32)...))
+		}
+	}
+
+	outputs, err := c.rpc.CallContracts(contracts, inputs, batch.Block.Number)
+	if err != nil {
+		return err
+	}
+
+	for i := range holders {
+		if len(outputs[i]) != 32 {
+			continue
+		}
+
+		holders[i].Balance = new(big.Int).SetBytes(outputs[i]).String()
+		batch.TokenBalances = append(batch.TokenBalances, &holders[i])
+	}
+
+	return nil
+}
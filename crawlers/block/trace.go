@@ -0,0 +1,29 @@
+package block
+
+import (
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+// getBlockTraces traces every transaction in block with a single call when tracing whole blocks.
+// It returns nil if transactions are traced one at a time, or if the block trace doesn't match the transactions,
+// in which case they are traced one by one instead
+
+func (c *Crawler) getBlockTraces(block *models.Block) []*models.ITransaction {
+
+	if c.cfg.Tracing.Mode != traceBlock {
+		return nil
+	}
+
+	traces, err := c.rpc.TraceBlockCalls(block.Number)
+	if err != nil {
+		c.logger.Error("couldn't trace block, tracing transactions instead", "number", block.Number, "err", err)
+		return nil
+	}
+
+	if len(traces) != len(block.RawTransactions) {
+		c.logger.Error("block trace doesn't match transactions, tracing transactions instead", "number", block.Number, "traces", len(traces), "txns", len(block.RawTransactions))
+		return nil
+	}
+
+	return traces
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/octanolabs/go-spectrum/crawlers/block"
 	"github.com/octanolabs/go-spectrum/crawlers/database"
+	"github.com/octanolabs/go-spectrum/crawlers/tokens"
 	"github.com/ubiq/go-ubiq/v7/log"
 )
 
@@ -17,6 +18,7 @@ type Config struct {
 	Enabled         bool            `json:"enabled"`
 	BlockCrawler    block.Config    `json:"blocks"`
 	DatabaseCrawler database.Config `json:"database"`
+	TokenCrawler    tokens.Config   `json:"tokens"`
 }
 
 func runCrawler(ticker *time.Ticker, c Crawler) {
@@ -58,4 +60,18 @@ func RunCrawlers(crawlers map[string]Crawler, cfg *Config, logger log.Logger) {
 
 		go runCrawler(databaseTicker, dbCrawler)
 	}
+
+	if tCrawler, ok := crawlers["tokens"]; ok {
+		tokenInterval, err := time.ParseDuration(cfg.TokenCrawler.Interval)
+		if err != nil {
+			logger.Error("can't parse tokenCrawler duration", "d", cfg.TokenCrawler.Interval, "err", err)
+			os.Exit(1)
+		}
+
+		tokenTicker := time.NewTicker(tokenInterval)
+
+		logger.Warn("tokenCrawler interval set", "d", cfg.TokenCrawler.Interval)
+
+		go runCrawler(tokenTicker, tCrawler)
+	}
 }
@@ -8,7 +8,7 @@ import (
 )
 
 type Crawler struct {
-	backend *storage.MongoDB
+	backend storage.Backend
 	cfg     *Config
 	logger  log.Logger
 }
@@ -18,7 +18,7 @@ type Config struct {
 	Interval string `json:"interval"`
 }
 
-func NewDbCrawler(db *storage.MongoDB, cfg *Config, logger log.Logger) *Crawler {
+func NewDbCrawler(db storage.Backend, cfg *Config, logger log.Logger) *Crawler {
 	return &Crawler{db, cfg, logger}
 }
 
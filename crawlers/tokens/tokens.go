@@ -0,0 +1,169 @@
+package tokens
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/rpc"
+	"github.com/octanolabs/go-spectrum/storage"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+	"github.com/ubiq/go-ubiq/v7/log"
+	gorpc "github.com/ubiq/go-ubiq/v7/rpc"
+)
+
+const erc20ABI = `[
+	{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"totalSupply","outputs":[{"name":"","type":"uint256"}],"type":"function"}
+]`
+
+type Config struct {
+	Enabled  bool   `json:"enabled"`
+	Interval string `json:"interval"`
+	// Metadata older than Refresh is fetched again, so that total supplies follow mints and burns
+	Refresh string `json:"refresh"`
+	// Calls are made this many blocks behind the db head, so that they don't see blocks that may be rolled back
+	Confirmations uint64 `json:"confirmations"`
+}
+
+type Crawler struct {
+	backend storage.Backend
+	rpc     *rpc.RPCClient
+	cfg     *Config
+	logger  log.Logger
+	abi     abi.ABI
+	refresh time.Duration
+}
+
+func NewTokenCrawler(db storage.Backend, cfg *Config, logger log.Logger, rpc *rpc.RPCClient) *Crawler {
+	parsed, err := abi.JSON(strings.NewReader(erc20ABI))
+	if err != nil {
+		// erc20ABI is a constant
+		panic(err)
+	}
+
+	refresh, err := time.ParseDuration(cfg.Refresh)
+	if err != nil {
+		logger.Error("can't parse token refresh duration, using 24h", "d", cfg.Refresh, "err", err)
+		refresh = 24 * time.Hour
+	}
+
+	return &Crawler{db, rpc, cfg, logger, parsed, refresh}
+}
+
+func (c *Crawler) RunLoop() {
+	start := time.Now()
+
+	head, err := c.backend.LatestBlock()
+	if err != nil {
+		c.logger.Error("couldn't get latest block", "err", err)
+		return
+	}
+
+	if head.Number < c.cfg.Confirmations {
+		return
+	}
+
+	block := head.Number - c.cfg.Confirmations
+
+	candidates, err := c.backend.TokenCandidates(start.Add(-c.refresh).Unix())
+	if err != nil {
+		c.logger.Error("couldn't get token candidates", "err", err)
+		return
+	}
+
+	var found int
+
+	for _, address := range candidates {
+		token, err := c.fetchToken(address, block)
+		if err != nil {
+			c.logger.Error("couldn't fetch token", "address", address, "err", err)
+			continue
+		}
+
+		if err := c.backend.AddToken(token); err != nil {
+			c.logger.Error("couldn't add token", "address", address, "err", err)
+			continue
+		}
+
+		if token.IsToken {
+			found++
+		}
+	}
+
+	c.logger.Info("crawled tokens", "block", block, "contracts", len(candidates), "tokens", found, "took", time.Since(start))
+}
+
+// fetchToken reads the ERC-20 metadata of a contract at block. A contract is considered a token if it
+// answers totalSupply and decimals, name and symbol are optional. Errors are only returned if the node
+// couldn't be reached, so that contracts aren't marked as not being tokens because of a failed call
+
+func (c *Crawler) fetchToken(address string, block uint64) (*models.Token, error) {
+	token := &models.Token{Address: address, Block: block, Updated: time.Now().Unix()}
+
+	outputs := make(map[string][]byte)
+
+	for _, method := range []string{"totalSupply", "decimals", "name", "symbol"} {
+		output, err := c.callContract(address, method, block)
+		if err != nil {
+			return nil, err
+		}
+		outputs[method] = output
+	}
+
+	supply, err := c.abi.Unpack("totalSupply", outputs["totalSupply"])
+	if err != nil {
+		return token, nil
+	}
+
+	decimals, err := c.abi.Unpack("decimals", outputs["decimals"])
+	if err != nil {
+		return token, nil
+	}
+
+	token.IsToken = true
+	token.TotalSupply = supply[0].(*big.Int).String()
+	token.Decimals = decimals[0].(uint8)
+	token.Name = c.unpackString("name", outputs["name"])
+	token.Symbol = c.unpackString("symbol", outputs["symbol"])
+
+	return token, nil
+}
+
+// callContract calls a getter, returning no output if the call reverted
+
+func (c *Crawler) callContract(address, method string, block uint64) ([]byte, error) {
+	input, err := c.abi.Pack(method)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := c.rpc.CallContract(address, input, block)
+	if err != nil {
+		if _, ok := err.(gorpc.Error); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// unpackString decodes the output of a string getter. Some early tokens return bytes32 instead, which is
+// decoded as a null padded string
+
+func (c *Crawler) unpackString(method string, output []byte) string {
+	if len(output) == 32 {
+		return strings.TrimRight(string(output), "\x00")
+	}
+
+	values, err := c.abi.Unpack(method, output)
+	if err != nil {
+		return ""
+	}
+
+	return values[0].(string)
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/event"
+)
+
+// ErrSlowSubscriber ends a subscription whose channel was full when an event was sent
+var ErrSlowSubscriber = errors.New("subscriber fell behind the feed")
+
+// Block is a block the block crawler committed, along with its transactions and the token transfers they made.
+// Events are handed to every subscriber as they are, they must not be changed
+
+type Block struct {
+	Block          models.Block
+	TokenTransfers []models.TokenTransfer
+}
+
+// Feed carries what the block crawler commits to the api in the same process, which pushes it to websocket
+// subscribers. Sends never wait, a subscriber whose channel is full is dropped and gets ErrSlowSubscriber from
+// its Err channel, so subscribers should read from a buffered channel. A nil Feed drops everything, for crawlers
+// running without an api
+
+type Feed struct {
+	mu     sync.Mutex
+	blocks map[*subscription]chan<- Block
+	reorgs map[*subscription]chan<- models.Reorg
+}
+
+func NewFeed() *Feed {
+	return &Feed{
+		blocks: make(map[*subscription]chan<- Block),
+		reorgs: make(map[*subscription]chan<- models.Reorg),
+	}
+}
+
+// SendBlock is called once b has been committed and is visible to the api
+
+func (f *Feed) SendBlock(b Block) {
+	if f == nil {
+		return
+	}
+
+	var slow []*subscription
+
+	f.mu.Lock()
+	for sub, ch := range f.blocks {
+		select {
+		case ch <- b:
+		default:
+			delete(f.blocks, sub)
+			slow = append(slow, sub)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, sub := range slow {
+		sub.end(ErrSlowSubscriber)
+	}
+}
+
+// SendReorg is called once the blocks of r have been rolled back
+
+func (f *Feed) SendReorg(r models.Reorg) {
+	if f == nil {
+		return
+	}
+
+	var slow []*subscription
+
+	f.mu.Lock()
+	for sub, ch := range f.reorgs {
+		select {
+		case ch <- r:
+		default:
+			delete(f.reorgs, sub)
+			slow = append(slow, sub)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, sub := range slow {
+		sub.end(ErrSlowSubscriber)
+	}
+}
+
+func (f *Feed) SubscribeBlocks(ch chan<- Block) event.Subscription {
+	sub := newSubscription(f)
+
+	f.mu.Lock()
+	f.blocks[sub] = ch
+	f.mu.Unlock()
+
+	return sub
+}
+
+func (f *Feed) SubscribeReorgs(ch chan<- models.Reorg) event.Subscription {
+	sub := newSubscription(f)
+
+	f.mu.Lock()
+	f.reorgs[sub] = ch
+	f.mu.Unlock()
+
+	return sub
+}
+
+// subscription is a channel subscribed to a Feed, its err channel is closed once it ends
+
+type subscription struct {
+	feed *Feed
+	once sync.Once
+	err  chan error
+}
+
+func newSubscription(f *Feed) *subscription {
+	return &subscription{feed: f, err: make(chan error, 1)}
+}
+
+func (s *subscription) Err() <-chan error {
+	return s.err
+}
+
+func (s *subscription) Unsubscribe() {
+	s.feed.mu.Lock()
+	delete(s.feed.blocks, s)
+	delete(s.feed.reorgs, s)
+	s.feed.mu.Unlock()
+
+	s.end(nil)
+}
+
+// end closes the err channel of s, after handing it err if there's one. s must be off the feed already
+
+func (s *subscription) end(err error) {
+	s.once.Do(func() {
+		if err != nil {
+			s.err <- err
+		}
+
+		close(s.err)
+	})
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+func TestFeedDropsSlowSubscribers(t *testing.T) {
+	feed := NewFeed()
+
+	var (
+		stuck   = make(chan Block, 1)
+		reading = make(chan Block, 1)
+		reorgs  = make(chan models.Reorg)
+	)
+
+	stuckSub := feed.SubscribeBlocks(stuck)
+	readingSub := feed.SubscribeBlocks(reading)
+	defer readingSub.Unsubscribe()
+	reorgSub := feed.SubscribeReorgs(reorgs)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := uint64(1); i <= 10; i++ {
+			feed.SendBlock(Block{Block: models.Block{Number: i}})
+
+			if got := <-reading; got.Block.Number != i {
+				t.Errorf("expected block %d, got %d", i, got.Block.Number)
+			}
+		}
+
+		// nobody reads reorgs, it mustn't wait either
+		feed.SendReorg(models.Reorg{Number: 1})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sends blocked on a subscriber that never reads")
+	}
+
+	for name, sub := range map[string]interface{ Err() <-chan error }{"blocks": stuckSub, "reorgs": reorgSub} {
+		select {
+		case err := <-sub.Err():
+			if err != ErrSlowSubscriber {
+				t.Errorf("%s: expected %v, got %v", name, ErrSlowSubscriber, err)
+			}
+		default:
+			t.Errorf("%s: slow subscriber wasn't dropped", name)
+		}
+	}
+
+	if len(stuck) != 1 {
+		t.Errorf("expected the first block to stay in the full channel, got %d", len(stuck))
+	}
+
+	select {
+	case <-readingSub.Err():
+		t.Error("subscriber that kept up was dropped")
+	default:
+	}
+}
+
+func TestFeedUnsubscribe(t *testing.T) {
+	feed := NewFeed()
+
+	ch := make(chan Block, 1)
+	sub := feed.SubscribeBlocks(ch)
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+
+	if _, ok := <-sub.Err(); ok {
+		t.Error("expected the err channel closed without an error")
+	}
+
+	feed.SendBlock(Block{})
+
+	if len(ch) != 0 {
+		t.Error("unsubscribed channel got a block")
+	}
+}
+
+func TestNilFeed(t *testing.T) {
+	var feed *Feed
+
+	feed.SendBlock(Block{})
+	feed.SendReorg(models.Reorg{})
+}
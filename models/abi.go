@@ -0,0 +1,60 @@
+package models
+
+// ContractABI is a json ABI uploaded for a contract, used to decode the calls to it and the events it emits.
+// Verified is set when it came from verifying the contract, uploads can't replace it then
+
+type ContractABI struct {
+	Address  string `bson:"address" json:"address"`
+	ABI      string `bson:"abi" json:"abi"`
+	Updated  int64  `bson:"updated" json:"updated"`
+	Verified bool   `bson:"verified" json:"verified"`
+}
+
+// DecodedArg is an argument of a decoded call or event. Integers are decimal strings, bytes and addresses hex.
+// Indexed dynamic event arguments only carry the hash of their value
+type DecodedArg struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Value   interface{} `json:"value"`
+	Indexed bool        `json:"indexed,omitempty"`
+}
+
+// DecodedCall is a call input decoded against the ABI of the called contract
+type DecodedCall struct {
+	Contract  string       `json:"contract"`
+	Method    string       `json:"method"`
+	Signature string       `json:"signature"`
+	Args      []DecodedArg `json:"args"`
+}
+
+// DecodedLog is an event log decoded against the ABI of the contract that emitted it
+type DecodedLog struct {
+	Contract  string       `json:"contract"`
+	LogIndex  string       `json:"logIndex"`
+	Event     string       `json:"event"`
+	Signature string       `json:"signature"`
+	Args      []DecodedArg `json:"args"`
+}
+
+// DecodedTransaction holds everything that could be decoded in a transaction: its input, the internal calls
+// in trace order and its logs
+type DecodedTransaction struct {
+	Hash  string         `json:"hash"`
+	Input *DecodedCall   `json:"input,omitempty"`
+	Calls []*DecodedCall `json:"calls"`
+	Logs  []*DecodedLog  `json:"logs"`
+}
+
+const (
+	SignatureFunction = "function"
+	SignatureEvent    = "event"
+)
+
+// Signature maps a 4-byte function selector or a 32-byte event topic to the text it's the hash of.
+// Selectors can collide, built in signatures are preferred when they do
+type Signature struct {
+	Hash    string `bson:"hash" json:"hash"`
+	Text    string `bson:"text" json:"text"`
+	Type    string `bson:"type" json:"type"`
+	Builtin bool   `bson:"builtin" json:"builtin"`
+}
@@ -149,3 +149,13 @@ func (rbt *RawBlockTrace) Convert() BlockTrace {
 		StructLogs:  rbt.Result.StructLogs,
 	}
 }
+
+// Reorg is recorded every time the block crawler rolls back blocks that are no longer canonical
+
+type Reorg struct {
+	Number    uint64   `bson:"number" json:"number"` // common ancestor
+	Depth     int      `bson:"depth" json:"depth"`
+	Hashes    []string `bson:"hashes" json:"hashes"` // rolled back blocks, highest first
+	Head      string   `bson:"head" json:"head"`     // first block of the new chain we saw
+	Timestamp int64    `bson:"timestamp" json:"timestamp"`
+}
@@ -0,0 +1,65 @@
+package models
+
+// ContractCreation is a contract deployed by a transaction, or by another contract with CREATE or CREATE2.
+// Code is the runtime code at the end of the creation block
+
+type ContractCreation struct {
+	Address     string `bson:"address" json:"address"`
+	Creator     string `bson:"creator" json:"creator"`
+	Hash        string `bson:"hash" json:"hash"`
+	BlockNumber uint64 `bson:"blockNumber" json:"blockNumber"`
+	Timestamp   uint64 `bson:"timestamp" json:"timestamp"`
+	Type        string `bson:"type" json:"type"`
+	Internal    bool   `bson:"internal" json:"internal"`
+	Code        string `bson:"code" json:"code"`
+	CodeHash    string `bson:"codeHash" json:"codeHash"`
+}
+
+// GetContractCreations returns the contracts created by the transaction, the deployed one first and then
+// those created by internal calls in trace order. Creations that failed, or were reverted with a calling
+// frame, are left out
+func (tx *Transaction) GetContractCreations() []*ContractCreation {
+	var creations []*ContractCreation
+
+	if !tx.Status {
+		return nil
+	}
+
+	base := ContractCreation{Hash: tx.Hash, BlockNumber: tx.BlockNumber, Timestamp: tx.Timestamp}
+
+	if tx.ContractAddress != "" {
+		c := base
+		c.Address = tx.ContractAddress
+		c.Creator = tx.From
+		c.Type = "CREATE"
+
+		creations = append(creations, &c)
+	}
+
+	var walk func(calls []ITransaction)
+	walk = func(calls []ITransaction) {
+		for i := range calls {
+			call := &calls[i]
+
+			if call.Error != "" {
+				continue
+			}
+
+			if (call.Type == "CREATE" || call.Type == "CREATE2") && call.To != "" {
+				c := base
+				c.Address = call.To
+				c.Creator = call.From
+				c.Type = call.Type
+				c.Internal = true
+
+				creations = append(creations, &c)
+			}
+
+			walk(call.Calls)
+		}
+	}
+
+	walk(tx.Trace.Calls)
+
+	return creations
+}
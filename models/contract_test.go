@@ -0,0 +1,50 @@
+package models
+
+import "testing"
+
+func TestGetContractCreations(t *testing.T) {
+	tx := &Transaction{
+		Hash:            "0xt",
+		BlockNumber:     10,
+		From:            "0xa",
+		ContractAddress: "0xc1",
+		Status:          true,
+		Trace: ITransaction{Type: "CREATE", From: "0xa", To: "0xc1", Calls: []ITransaction{
+			{Type: "CREATE2", From: "0xc1", To: "0xc2", Calls: []ITransaction{
+				{Type: "CREATE", From: "0xc2", To: "0xc3"},
+			}},
+			{Type: "CALL", From: "0xc1", To: "0xb", Error: "execution reverted", Calls: []ITransaction{
+				{Type: "CREATE", From: "0xb", To: "0xc4"},
+			}},
+			{Type: "CREATE", From: "0xc1", Error: "out of gas"},
+		}},
+	}
+
+	creations := tx.GetContractCreations()
+
+	want := []struct {
+		address, creator, typ string
+		internal              bool
+	}{
+		{"0xc1", "0xa", "CREATE", false},
+		{"0xc2", "0xc1", "CREATE2", true},
+		{"0xc3", "0xc2", "CREATE", true},
+	}
+
+	if len(creations) != len(want) {
+		t.Fatalf("got %d creations, want %d", len(creations), len(want))
+	}
+
+	for i, w := range want {
+		c := creations[i]
+		if c.Address != w.address || c.Creator != w.creator || c.Type != w.typ || c.Internal != w.internal || c.Hash != "0xt" || c.BlockNumber != 10 {
+			t.Errorf("creation %d: got %+v, want %+v", i, c, w)
+		}
+	}
+
+	tx.Status = false
+
+	if len(tx.GetContractCreations()) != 0 {
+		t.Error("failed transaction created contracts")
+	}
+}
@@ -20,6 +20,17 @@ const (
 	STORE         = "sysstores"
 	ENODES        = "enodes"
 	ACCOUNTS      = "accounts"
+	REORGS        = "reorgs"
+	BALANCES      = "balancehistory"
+	TRACES        = "traces"
+	NFTTRANSFERS  = "nfttransfers"
+	TOKENS        = "tokens"
+	TOKENBALANCES = "tokenbalances"
+	TOKENHISTORY  = "tokenbalancehistory"
+	ABIS          = "abis"
+	SIGNATURES    = "signatures"
+	VERIFIED      = "verifiedcontracts"
+	CREATIONS     = "contractcreations"
 )
 
 type Store struct {
@@ -39,6 +50,27 @@ type Store struct {
 	TotalUncles            int64 `bson:"totalUncles" json:"totalUncles"`
 }
 
+// Checkpoint is kept in sysstores alongside the Store, one per crawler, and points at the last block
+// whose documents were all written successfully
+
+type Checkpoint struct {
+	Crawler string `bson:"crawler" json:"crawler"`
+	Number  uint64 `bson:"number" json:"number"`
+	Hash    string `bson:"hash" json:"hash"`
+	Updated int64  `bson:"updated" json:"updated"`
+}
+
+// Schema is kept in sysstores alongside the Store, and records the last migration applied to the database.
+// BalanceHistoryFrom is the first block the crawler recorded balance history for, accounts seen before it were
+// seeded with the balance they had then
+
+type Schema struct {
+	Schema             string `bson:"schema" json:"schema"`
+	Version            int    `bson:"version" json:"version"`
+	Updated            int64  `bson:"updated" json:"updated"`
+	BalanceHistoryFrom uint64 `bson:"balanceHistoryFrom,omitempty" json:"balanceHistoryFrom,omitempty"`
+}
+
 type Enode struct {
 	Id   enode.ID `json:"id"`
 	Ip   net.IP   `json:"ip"`
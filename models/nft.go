@@ -0,0 +1,150 @@
+package models
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/util"
+)
+
+const (
+	// TransferSingle(address,address,address,uint256,uint256)
+	TransferSingleTopic = "0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62"
+	// TransferBatch(address,address,address,uint256[],uint256[])
+	TransferBatchTopic = "0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb"
+
+	ERC721  = "erc721"
+	ERC1155 = "erc1155"
+)
+
+// NFTTransfer is a single token id moved by an ERC-721 Transfer or an ERC-1155 TransferSingle/TransferBatch event.
+// Batches are split in one transfer per token id, sharing the log index
+type NFTTransfer struct {
+	BlockNumber uint64 `bson:"blockNumber" json:"blockNumber"`
+	Hash        string `bson:"hash" json:"hash"`
+	LogIndex    uint64 `bson:"logIndex" json:"logIndex"`
+	Timestamp   uint64 `bson:"timestamp" json:"timestamp"`
+	Contract    string `bson:"contract" json:"contract"`
+	Standard    string `bson:"standard" json:"standard"`
+	Operator    string `bson:"operator,omitempty" json:"operator,omitempty"`
+	From        string `bson:"from" json:"from"`
+	To          string `bson:"to" json:"to"`
+	TokenId     string `bson:"tokenId" json:"tokenId"`
+	Amount      string `bson:"amount" json:"amount"`
+}
+
+// GetNFTTransfers decodes the ERC-721 and ERC-1155 transfer events in the transaction logs
+func (tx *Transaction) GetNFTTransfers() []*NFTTransfer {
+	var transfers []*NFTTransfer
+
+	for _, l := range tx.Logs {
+		if len(l.Topics) == 0 {
+			continue
+		}
+
+		base := NFTTransfer{
+			BlockNumber: tx.BlockNumber,
+			Hash:        tx.Hash,
+			LogIndex:    util.DecodeHex(l.LogIndex),
+			Timestamp:   tx.Timestamp,
+			Contract:    strings.ToLower(l.Address),
+		}
+
+		switch {
+		case l.Topics[0] == TransferTopic && len(l.Topics) == 4 && len(l.Topics[3]) == 66:
+			t := base
+			t.Standard = ERC721
+			t.From = topicToAddress(l.Topics[1])
+			t.To = topicToAddress(l.Topics[2])
+			t.TokenId = util.DecodeValueHex(l.Topics[3][2:])
+			t.Amount = "1"
+
+			transfers = append(transfers, &t)
+
+		case l.Topics[0] == TransferSingleTopic && len(l.Topics) == 4:
+			words := dataWords(l.Data)
+			if len(words) != 2 {
+				continue
+			}
+
+			t := base
+			t.Standard = ERC1155
+			t.Operator = topicToAddress(l.Topics[1])
+			t.From = topicToAddress(l.Topics[2])
+			t.To = topicToAddress(l.Topics[3])
+			t.TokenId = words[0].String()
+			t.Amount = words[1].String()
+
+			transfers = append(transfers, &t)
+
+		case l.Topics[0] == TransferBatchTopic && len(l.Topics) == 4:
+			words := dataWords(l.Data)
+			ids, amounts := wordArray(words, 0), wordArray(words, 1)
+			if ids == nil || len(ids) != len(amounts) {
+				continue
+			}
+
+			for i := range ids {
+				t := base
+				t.Standard = ERC1155
+				t.Operator = topicToAddress(l.Topics[1])
+				t.From = topicToAddress(l.Topics[2])
+				t.To = topicToAddress(l.Topics[3])
+				t.TokenId = ids[i].String()
+				t.Amount = amounts[i].String()
+
+				transfers = append(transfers, &t)
+			}
+		}
+	}
+
+	return transfers
+}
+
+func topicToAddress(topic string) string {
+	if len(topic) != 66 {
+		return ""
+	}
+
+	return util.InputParamsToAddress(topic[2:])
+}
+
+// dataWords splits abi encoded log data in 32 byte words
+func dataWords(data string) []*big.Int {
+	data = strings.TrimPrefix(data, "0x")
+
+	if len(data)%64 != 0 {
+		return nil
+	}
+
+	words := make([]*big.Int, len(data)/64)
+
+	for i := range words {
+		w, ok := new(big.Int).SetString(data[i*64:(i+1)*64], 16)
+		if !ok {
+			return nil
+		}
+		words[i] = w
+	}
+
+	return words
+}
+
+// wordArray decodes the dynamic uint256 array whose offset is in the arg-th head word
+func wordArray(words []*big.Int, arg int) []*big.Int {
+	if arg >= len(words) || !words[arg].IsUint64() || words[arg].Uint64()%32 != 0 {
+		return nil
+	}
+
+	start := words[arg].Uint64() / 32
+	if start >= uint64(len(words)) || !words[start].IsUint64() {
+		return nil
+	}
+
+	length := words[start].Uint64()
+	if length > uint64(len(words))-start-1 {
+		return nil
+	}
+
+	return words[start+1 : start+1+length]
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestGetNFTTransfers(t *testing.T) {
+
+	var (
+		a = "0x0000000000000000000000001111111111111111111111111111111111111111"
+		b = "0x0000000000000000000000002222222222222222222222222222222222222222"
+		o = "0x0000000000000000000000003333333333333333333333333333333333333333"
+	)
+
+	tx := &Transaction{
+		BlockNumber: 10,
+		Hash:        "0x01",
+		Logs: []TxLog{
+			{
+				Address:  "0x4444444444444444444444444444444444444444",
+				Topics:   []string{TransferTopic, a, b, "0x000000000000000000000000000000000000000000000000000000000000002a"},
+				Data:     "0x",
+				LogIndex: "0x0",
+			},
+			{
+				// ERC-20 transfers are left to GetLogTransfers
+				Address:  "0x5555555555555555555555555555555555555555",
+				Topics:   []string{TransferTopic, a, b},
+				Data:     "0x00000000000000000000000000000000000000000000000000000000000003e8",
+				LogIndex: "0x1",
+			},
+			{
+				Address:  "0x6666666666666666666666666666666666666666",
+				Topics:   []string{TransferSingleTopic, o, a, b},
+				Data:     "0x" + "0000000000000000000000000000000000000000000000000000000000000007" + "0000000000000000000000000000000000000000000000000000000000000005",
+				LogIndex: "0x2",
+			},
+			{
+				Address: "0x6666666666666666666666666666666666666666",
+				Topics:  []string{TransferBatchTopic, o, b, a},
+				Data: "0x" +
+					"0000000000000000000000000000000000000000000000000000000000000040" + // ids offset
+					"00000000000000000000000000000000000000000000000000000000000000a0" + // amounts offset
+					"0000000000000000000000000000000000000000000000000000000000000002" +
+					"0000000000000000000000000000000000000000000000000000000000000001" +
+					"0000000000000000000000000000000000000000000000000000000000000002" +
+					"0000000000000000000000000000000000000000000000000000000000000002" +
+					"000000000000000000000000000000000000000000000000000000000000000a" +
+					"0000000000000000000000000000000000000000000000000000000000000014",
+				LogIndex: "0x3",
+			},
+		},
+	}
+
+	want := []NFTTransfer{
+		{BlockNumber: 10, Hash: "0x01", LogIndex: 0, Contract: "0x4444444444444444444444444444444444444444", Standard: ERC721, From: "0x1111111111111111111111111111111111111111", To: "0x2222222222222222222222222222222222222222", TokenId: "42", Amount: "1"},
+		{BlockNumber: 10, Hash: "0x01", LogIndex: 2, Contract: "0x6666666666666666666666666666666666666666", Standard: ERC1155, Operator: "0x3333333333333333333333333333333333333333", From: "0x1111111111111111111111111111111111111111", To: "0x2222222222222222222222222222222222222222", TokenId: "7", Amount: "5"},
+		{BlockNumber: 10, Hash: "0x01", LogIndex: 3, Contract: "0x6666666666666666666666666666666666666666", Standard: ERC1155, Operator: "0x3333333333333333333333333333333333333333", From: "0x2222222222222222222222222222222222222222", To: "0x1111111111111111111111111111111111111111", TokenId: "1", Amount: "10"},
+		{BlockNumber: 10, Hash: "0x01", LogIndex: 3, Contract: "0x6666666666666666666666666666666666666666", Standard: ERC1155, Operator: "0x3333333333333333333333333333333333333333", From: "0x2222222222222222222222222222222222222222", To: "0x1111111111111111111111111111111111111111", TokenId: "2", Amount: "20"},
+	}
+
+	transfers := tx.GetNFTTransfers()
+
+	if len(transfers) != len(want) {
+		t.Fatal("expected", len(want), "transfers, got", len(transfers))
+	}
+
+	for i := range want {
+		if *transfers[i] != want[i] {
+			t.Error("unexpected transfer", i, *transfers[i])
+		}
+	}
+}
@@ -1,12 +1,96 @@
 package models
 
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ubiq/go-ubiq/v7/common/hexutil"
+)
+
 type RawState struct {
 	Root     string                 `bson:"root" json:"root"`
 	Accounts map[string]interface{} `bson:"accounts" json:"accounts"`
 }
 
+// Account is the latest known state of an address. Nonce and HasCode are only known when balances are
+// tracked with the prestate tracer, and are left untouched otherwise
+
 type Account struct {
-	Address string `bson:"address" json:"address"`
-	Balance string `bson:"balance" json:"balance"`
-	Block   uint64 `bson:"block" json:"block"`
+	Address string  `bson:"address" json:"address"`
+	Balance string  `bson:"balance" json:"balance"`
+	Block   uint64  `bson:"block" json:"block"`
+	Nonce   *uint64 `bson:"nonce,omitempty" json:"nonce,omitempty"`
+	HasCode *bool   `bson:"hasCode,omitempty" json:"hasCode,omitempty"`
+}
+
+// BalanceRecord is an account's balance as of a block, one is kept for every block that touched the account.
+// Delta is the change from the previous record, or the whole balance for the first one
+
+type BalanceRecord struct {
+	Address string  `bson:"address" json:"address"`
+	Block   uint64  `bson:"block" json:"block"`
+	Balance string  `bson:"balance" json:"balance"`
+	Delta   string  `bson:"delta" json:"delta"`
+	Nonce   *uint64 `bson:"nonce,omitempty" json:"nonce,omitempty"`
+	HasCode *bool   `bson:"hasCode,omitempty" json:"hasCode,omitempty"`
+}
+
+// RawStateDiff is the prestateTracer output for a transaction in diff mode. Pre holds the state of every account
+// the transaction modified, and post the fields that changed; accounts missing from post were self-destructed
+
+type RawStateDiff struct {
+	Pre  map[string]RawAccountState `json:"pre"`
+	Post map[string]RawAccountState `json:"post"`
+}
+
+// RawAccountState leaves out zero values, so missing fields are zero in pre and unchanged in post
+
+type RawAccountState struct {
+	Balance *hexutil.Big   `json:"balance,omitempty"`
+	Nonce   *uint64        `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes `json:"code,omitempty"`
+}
+
+// RawBlockStateDiff is a single transaction's entry in the prestateTracer trace of a whole block
+type RawBlockStateDiff struct {
+	TxHash string       `json:"txHash,omitempty"`
+	Result RawStateDiff `json:"result"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// AccountState is the state of an account after some transactions
+type AccountState struct {
+	Balance *big.Int
+	Nonce   uint64
+	HasCode bool
+}
+
+// ApplyStateDiff updates states with the changes made by a transaction. Diffs have to be applied in transaction order
+func ApplyStateDiff(states map[string]*AccountState, diff *RawStateDiff) {
+	for address, pre := range diff.Pre {
+		s := &AccountState{Balance: new(big.Int)}
+
+		post, ok := diff.Post[address]
+		if ok {
+			// the pre state of an account is its state after the previous transaction that touched it
+			s.apply(&pre)
+			s.apply(&post)
+		}
+
+		states[strings.ToLower(address)] = s
+	}
+}
+
+func (s *AccountState) apply(r *RawAccountState) {
+	if r.Balance != nil {
+		s.Balance = r.Balance.ToInt()
+	}
+
+	if r.Nonce != nil {
+		s.Nonce = *r.Nonce
+	}
+
+	if r.Code != nil {
+		s.HasCode = len(*r.Code) > 0
+	}
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyStateDiff(t *testing.T) {
+	// sender pays a contract which self-destructs to a fresh beneficiary
+	raw := []string{
+		`{"pre":{"0xaa":{"balance":"0x64","nonce":1},"0xcc":{"balance":"0x0","code":"0x6060"}},
+		  "post":{"0xaa":{"balance":"0x50","nonce":2},"0xcc":{"balance":"0x14"}}}`,
+		`{"pre":{"0xaa":{"balance":"0x50","nonce":2},"0xcc":{"balance":"0x14","code":"0x6060"},"0xdd":{"balance":"0x0"}},
+		  "post":{"0xaa":{"balance":"0x4b","nonce":3},"0xdd":{"balance":"0x14"}}}`,
+	}
+
+	states := make(map[string]*AccountState)
+
+	for _, r := range raw {
+		var diff RawStateDiff
+
+		if err := json.Unmarshal([]byte(r), &diff); err != nil {
+			t.Fatal(err)
+		}
+
+		ApplyStateDiff(states, &diff)
+	}
+
+	want := map[string]struct {
+		balance string
+		nonce   uint64
+		hasCode bool
+	}{
+		"0xaa": {"75", 3, false},
+		"0xcc": {"0", 0, false},
+		"0xdd": {"20", 0, false},
+	}
+
+	if len(states) != len(want) {
+		t.Fatalf("got %d accounts, want %d", len(states), len(want))
+	}
+
+	for address, w := range want {
+		s, ok := states[address]
+		if !ok {
+			t.Errorf("%v missing", address)
+			continue
+		}
+
+		if s.Balance.String() != w.balance || s.Nonce != w.nonce || s.HasCode != w.hasCode {
+			t.Errorf("%v: got %v %v %v, want %v %v %v", address, s.Balance, s.Nonce, s.HasCode, w.balance, w.nonce, w.hasCode)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+// Token holds the metadata of a token contract as of Block. Contracts that don't implement the ERC-20
+// getters are kept with IsToken unset, so they aren't queried again until the next refresh
+
+type Token struct {
+	Address     string `bson:"address" json:"address"`
+	Name        string `bson:"name" json:"name"`
+	Symbol      string `bson:"symbol" json:"symbol"`
+	Decimals    uint8  `bson:"decimals" json:"decimals"`
+	TotalSupply string `bson:"totalSupply" json:"totalSupply"`
+	IsToken     bool   `bson:"isToken" json:"-"`
+	Block       uint64 `bson:"block" json:"block"`
+	Updated     int64  `bson:"updated" json:"updated"`
+}
+
+// TokenBalance is the balance of a token holder as of Block. The latest one for each holder is kept in
+// tokenbalances, and one for every block that touched the holder in tokenbalancehistory
+
+type TokenBalance struct {
+	Contract string `bson:"contract" json:"contract"`
+	Address  string `bson:"address" json:"address"`
+	Balance  string `bson:"balance" json:"balance"`
+	Block    uint64 `bson:"block" json:"block"`
+}
+
+// TokenHolder is a holder's balance along with its share of the token supply, in percent
+
+type TokenHolder struct {
+	TokenBalance `bson:",inline"`
+	Percentage   float64 `bson:"-" json:"percentage"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"strings"
+
 	"github.com/octanolabs/go-spectrum/util"
 	"github.com/ubiq/go-ubiq/v7/log"
 )
@@ -79,8 +81,23 @@ type Transaction struct {
 	BaseFeePerGas        string `bson:"baseFeePerGas" json:"baseFeePerGas,omitempty"`
 	//
 	Trace ITransaction `bson:"trace,omitempty" json:"trace,omitempty"`
+	// Set when the trace was too large for the document and is stored in chunks instead, along with the internal
+	// transactions. Lists leave it set, the transaction by hash has them put back
+	TraceChunks int `bson:"traceChunks,omitempty" json:"traceChunks,omitempty"`
 	//
 	ITransactions []ITransaction `bson:"iTransactions" json:"iTransactions,omitempty"`
+	// Best guess from the signature db of the called function's name
+	MethodName string `bson:"methodName,omitempty" json:"methodName,omitempty"`
+	// Set by the api when the ABI of the called contract is known
+	Decoded *DecodedCall `bson:"-" json:"decoded,omitempty"`
+}
+
+// TraceChunk is a piece of a gzipped trace that is too large to be embedded in its transaction
+type TraceChunk struct {
+	Hash        string `bson:"hash" json:"hash"`
+	BlockNumber uint64 `bson:"blockNumber" json:"blockNumber"`
+	Index       int    `bson:"index" json:"index"`
+	Data        []byte `bson:"data" json:"data"`
 }
 
 func (tx *Transaction) IsTokenTransfer() bool {
@@ -185,9 +202,41 @@ func (tx *Transaction) GetTokenTransfer() *TokenTransfer {
 
 }
 
+// TransferTopic is the topic of Transfer(address,address,uint256) events
+const TransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// GetLogTransfers decodes the ERC-20 Transfer events in the transaction logs, including the ones emitted by
+// internal calls. ERC-721 transfers share the topic but index the token id, so they are skipped. Their Method is "transfer", as
+// for transfers decoded from the input
+func (tx *Transaction) GetLogTransfers() []*TokenTransfer {
+	var transfers []*TokenTransfer
+
+	for _, l := range tx.Logs {
+		if len(l.Topics) != 3 || l.Topics[0] != TransferTopic || len(l.Topics[1]) != 66 || len(l.Topics[2]) != 66 || len(l.Data) != 66 {
+			continue
+		}
+
+		transfers = append(transfers, &TokenTransfer{
+			BlockNumber: tx.BlockNumber,
+			Hash:        tx.Hash,
+			LogIndex:    util.DecodeHex(l.LogIndex),
+			Timestamp:   tx.Timestamp,
+			From:        util.InputParamsToAddress(l.Topics[1][2:]),
+			To:          util.InputParamsToAddress(l.Topics[2][2:]),
+			Value:       util.DecodeValueHex(l.Data[2:]),
+			Contract:    strings.ToLower(l.Address),
+			Method:      "transfer",
+			Status:      tx.Status,
+		})
+	}
+
+	return transfers
+}
+
 type TokenTransfer struct {
 	BlockNumber uint64 `bson:"blockNumber" json:"blockNumber"`
 	Hash        string `bson:"hash" json:"hash"`
+	LogIndex    uint64 `bson:"logIndex" json:"logIndex"`
 	Timestamp   uint64 `bson:"timestamp" json:"timestamp"`
 	From        string `bson:"from" json:"from"`
 	To          string `bson:"to" json:"to"`
@@ -277,6 +326,8 @@ type TxLog struct {
 	BlockHash        string   `bson:"blockHash" json:"blockHash"`
 	LogIndex         string   `bson:"logIndex" json:"logIndex"`
 	Removed          bool     `bson:"removed" json:"removed"`
+	// Set by the api when the ABI of the emitting contract is known
+	Decoded *DecodedLog `bson:"-" json:"decoded,omitempty"`
 }
 
 // RawTxTrace is what we get from the node tracer
@@ -289,10 +340,18 @@ type RawTxTrace struct {
 	GasUsed string       `json:"gasUsed"`         // hex
 	Input   string       `json:"input"`
 	Output  string       `json:"output"`
+	Error   string       `json:"error,omitempty"`
 	Time    string       `json:"-"`
 	Calls   []RawTxTrace `json:"calls,omitempty"`
 }
 
+// RawBlockTxTrace is a single transaction's entry in the callTracer trace of a whole block
+type RawBlockTxTrace struct {
+	TxHash string     `json:"txHash,omitempty"`
+	Result RawTxTrace `json:"result"`
+	Error  string     `json:"error,omitempty"`
+}
+
 func (rtt *RawTxTrace) Convert() ITransaction {
 	t := ITransaction{
 		Type:    rtt.Type,
@@ -303,6 +362,7 @@ func (rtt *RawTxTrace) Convert() ITransaction {
 		GasUsed: util.DecodeValueHex(rtt.GasUsed),
 		Input:   rtt.Input,
 		Output:  rtt.Output,
+		Error:   rtt.Error,
 		Calls:   nil,
 	}
 
@@ -328,5 +388,9 @@ type ITransaction struct {
 	GasUsed     string         `json:"gasUsed" bson:"gasUsed"`                 //convert to number -> string
 	Input       string         `json:"input" bson:"input"`
 	Output      string         `json:"output" bson:"output"`
+	Error       string         `json:"error,omitempty" bson:"error,omitempty"`
 	Calls       []ITransaction `json:"calls,omitempty" bson:"calls,omitempty"`
+	// Set when the trace of the transaction is stored in chunks, nested calls are then only kept in the trace
+	CallsOmitted bool         `json:"callsOmitted,omitempty" bson:"callsOmitted,omitempty"`
+	Decoded      *DecodedCall `json:"decoded,omitempty" bson:"-"`
 }
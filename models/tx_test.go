@@ -0,0 +1,102 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestGetLogTransfers(t *testing.T) {
+
+	tx := &Transaction{
+		BlockNumber: 10,
+		Hash:        "0x01",
+		From:        "0x1111111111111111111111111111111111111111",
+		To:          "0x2222222222222222222222222222222222222222", // router
+		Input:       "0x38ed1739",
+		Status:      true,
+		Logs: []TxLog{
+			{
+				Address:  "0x3333333333333333333333333333333333333333",
+				Topics:   []string{TransferTopic, "0x0000000000000000000000001111111111111111111111111111111111111111", "0x0000000000000000000000002222222222222222222222222222222222222222"},
+				Data:     "0x00000000000000000000000000000000000000000000000000000000000003e8",
+				LogIndex: "0x0",
+			},
+			{
+				// some other event
+				Address:  "0x2222222222222222222222222222222222222222",
+				Topics:   []string{"0x1c411e9a96e071241c2f21f7726b17ae89e3cab4c78be50e062b03a9fffbbad1"},
+				Data:     "0x",
+				LogIndex: "0x1",
+			},
+			{
+				// ERC-721, token id is indexed
+				Address:  "0x4444444444444444444444444444444444444444",
+				Topics:   []string{TransferTopic, "0x0000000000000000000000001111111111111111111111111111111111111111", "0x0000000000000000000000002222222222222222222222222222222222222222", "0x0000000000000000000000000000000000000000000000000000000000000001"},
+				Data:     "0x",
+				LogIndex: "0x2",
+			},
+			{
+				Address:  "0x5555555555555555555555555555555555555555",
+				Topics:   []string{TransferTopic, "0x0000000000000000000000002222222222222222222222222222222222222222", "0x0000000000000000000000001111111111111111111111111111111111111111"},
+				Data:     "0x0000000000000000000000000000000000000000000000000de0b6b3a7640000",
+				LogIndex: "0x3",
+			},
+		},
+	}
+
+	transfers := tx.GetLogTransfers()
+
+	if len(transfers) != 2 {
+		t.Fatal("expected 2 transfers, got", len(transfers))
+	}
+
+	want := []TokenTransfer{
+		{BlockNumber: 10, Hash: "0x01", LogIndex: 0, From: "0x1111111111111111111111111111111111111111", To: "0x2222222222222222222222222222222222222222", Value: "1000", Contract: "0x3333333333333333333333333333333333333333", Method: "transfer", Status: true},
+		{BlockNumber: 10, Hash: "0x01", LogIndex: 3, From: "0x2222222222222222222222222222222222222222", To: "0x1111111111111111111111111111111111111111", Value: "1000000000000000000", Contract: "0x5555555555555555555555555555555555555555", Method: "transfer", Status: true},
+	}
+
+	for i := range want {
+		if *transfers[i] != want[i] {
+			t.Error("unexpected transfer", i, *transfers[i])
+		}
+	}
+}
+
+func TestTransferMethod(t *testing.T) {
+	var (
+		to    = "0x0000000000000000000000002222222222222222222222222222222222222222"
+		value = "0x00000000000000000000000000000000000000000000000000000000000003e8"
+	)
+
+	// the same transfer, decoded from the input and from its event
+	tx := &Transaction{
+		BlockNumber: 10,
+		Hash:        "0x01",
+		From:        "0x1111111111111111111111111111111111111111",
+		To:          "0x3333333333333333333333333333333333333333",
+		Input:       "0xa9059cbb" + to[2:] + value[2:],
+		Status:      true,
+		Logs: []TxLog{
+			{
+				Address:  "0x3333333333333333333333333333333333333333",
+				Topics:   []string{TransferTopic, "0x0000000000000000000000001111111111111111111111111111111111111111", to},
+				Data:     value,
+				LogIndex: "0x0",
+			},
+		},
+	}
+
+	fromInput := tx.GetTokenTransfer()
+	fromLogs := tx.GetLogTransfers()
+
+	if len(fromLogs) != 1 {
+		t.Fatal("expected 1 transfer, got", len(fromLogs))
+	}
+
+	if fromInput.Method != "transfer" || fromLogs[0].Method != "transfer" {
+		t.Errorf("expected method transfer, got %q from the input and %q from the logs", fromInput.Method, fromLogs[0].Method)
+	}
+
+	if fromInput.From != fromLogs[0].From || fromInput.To != fromLogs[0].To || fromInput.Value != fromLogs[0].Value || fromInput.Contract != fromLogs[0].Contract {
+		t.Error("transfers differ", *fromInput, *fromLogs[0])
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+const (
+	// the compiled code matches including the metadata hash, so the sources are exactly the ones deployed
+	MatchFull = "full"
+	// the code matches but the metadata hash differs, e.g. because of comments or file names
+	MatchPartial = "partial"
+)
+
+// VerifiedContract holds the sources a contract's code was verified against, and the settings it was compiled with
+
+type VerifiedContract struct {
+	Address         string            `bson:"address" json:"address"`
+	ContractName    string            `bson:"contractName" json:"contractName"`
+	CompilerVersion string            `bson:"compilerVersion" json:"compilerVersion"`
+	Settings        string            `bson:"settings" json:"settings"`
+	Sources         map[string]string `bson:"sources" json:"sources"`
+	ABI             string            `bson:"abi" json:"abi"`
+	Match           string            `bson:"match" json:"match"`
+	Verified        int64             `bson:"verified" json:"verified"`
+}
@@ -0,0 +1,274 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ubiq/go-ubiq/v7/log"
+	"github.com/ubiq/go-ubiq/v7/rpc"
+
+	"github.com/octanolabs/go-spectrum/util"
+)
+
+const (
+	defaultHealthInterval = 10 * time.Second
+	healthCheckTimeout    = 5 * time.Second
+)
+
+var ErrNoHealthyNodes = errors.New("no healthy rpc nodes")
+
+// node is a single gubiq endpoint along with the state seen by the last health check
+
+type node struct {
+	Endpoint
+
+	mu      sync.RWMutex
+	client  *rpc.Client
+	head    uint64
+	version string
+	healthy bool
+}
+
+func (n *node) rpcClient() *rpc.Client {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.client
+}
+
+// fail marks the node as unusable until the next health check. The client is closed so
+// that a dropped websocket or ipc connection is dialed again instead of being reused
+
+func (n *node) fail(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.healthy {
+		log.Warn("rpc node down", "endpoint", n.Endpoint.Endpoint, "err", err)
+	}
+
+	n.healthy = false
+
+	if n.client != nil {
+		n.client.Close()
+		n.client = nil
+	}
+}
+
+// probe dials the node if needed and fetches its head and client version
+
+func (n *node) probe() (uint64, string, error) {
+	client := n.rpcClient()
+
+	if client == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		c, err := dialNewClient(ctx, &n.Endpoint)
+		cancel()
+
+		if err != nil {
+			return 0, "", err
+		}
+
+		n.mu.Lock()
+		n.client, client = c, c
+		n.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	var (
+		bn      string
+		version string
+	)
+
+	if err := client.CallContext(ctx, &bn, "eth_blockNumber"); err != nil {
+		return 0, "", err
+	}
+
+	if err := client.CallContext(ctx, &version, "web3_clientVersion"); err != nil {
+		return 0, "", err
+	}
+
+	return util.DecodeHex(bn), version, nil
+}
+
+// checkHealth probes every node and marks the ones that respond and are within maxLag blocks of the best head as healthy
+
+func (r *RPCClient) checkHealth() {
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+
+	var (
+		wg     sync.WaitGroup
+		heads  = make([]uint64, len(r.nodes))
+		errs   = make([]error, len(r.nodes))
+		best   uint64
+		nodeOk int
+	)
+
+	for i, n := range r.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+
+			var version string
+
+			heads[i], version, errs[i] = n.probe()
+
+			if errs[i] == nil {
+				n.mu.Lock()
+				n.version = version
+				n.mu.Unlock()
+			}
+		}(i, n)
+	}
+
+	wg.Wait()
+
+	for i := range r.nodes {
+		if errs[i] == nil && heads[i] > best {
+			best = heads[i]
+		}
+	}
+
+	for i, n := range r.nodes {
+		if errs[i] != nil {
+			n.fail(errs[i])
+			continue
+		}
+
+		healthy := heads[i]+r.maxLag >= best
+
+		n.mu.Lock()
+		if healthy != n.healthy {
+			if healthy {
+				log.Info("rpc node up", "endpoint", n.Endpoint.Endpoint, "version", n.version, "head", heads[i])
+			} else {
+				log.Warn("rpc node lagging behind", "endpoint", n.Endpoint.Endpoint, "head", heads[i], "best", best)
+			}
+		}
+		n.head = heads[i]
+		n.healthy = healthy
+		n.mu.Unlock()
+
+		if healthy {
+			nodeOk++
+		}
+	}
+
+	if nodeOk == 0 {
+		log.Error("no healthy rpc nodes", "nodes", len(r.nodes))
+	}
+
+	r.lastCheck = time.Now()
+}
+
+func (r *RPCClient) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.checkHealth()
+	}
+}
+
+// pick returns the healthy node with the lowest priority value, preferring the highest head between equals.
+// Nodes in skip were already tried for the current call
+
+func (r *RPCClient) pick(skip map[*node]bool) *node {
+	var (
+		best     *node
+		bestHead uint64
+	)
+
+	for _, n := range r.nodes {
+		if skip[n] {
+			continue
+		}
+
+		n.mu.RLock()
+		healthy, head, ok := n.healthy, n.head, n.client != nil
+		n.mu.RUnlock()
+
+		if !healthy || !ok {
+			continue
+		}
+
+		if best == nil || n.Priority < best.Priority || (n.Priority == best.Priority && head > bestHead) {
+			best, bestHead = n, head
+		}
+	}
+
+	return best
+}
+
+// callOnce runs fn against the best node, failing over to the next one if the connection to it is lost.
+// Errors returned by the node itself are passed on as they are
+
+func (r *RPCClient) callOnce(ctx context.Context, method string, fn func(context.Context, *rpc.Client) error) error {
+	var (
+		tried   = make(map[*node]bool)
+		lastErr error
+	)
+
+	for {
+		n := r.pick(tried)
+		if n == nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return ErrNoHealthyNodes
+		}
+
+		client := n.rpcClient()
+		if client == nil {
+			tried[n] = true
+			continue
+		}
+
+		err := fn(ctx, client)
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
+
+		log.Warn("rpc call failed, failing over", "endpoint", n.Endpoint.Endpoint, "method", method, "err", err)
+
+		n.fail(err)
+		tried[n] = true
+		lastErr = err
+	}
+}
+
+// isConnectionError tells whether err means the node couldn't be reached, as opposed to an error response
+
+func isConnectionError(err error) bool {
+	if _, ok := err.(rpc.Error); ok {
+		return false
+	}
+
+	// context errors satisfy net.Error, but they come from the caller's deadline
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var (
+		netErr  net.Error
+		httpErr rpc.HTTPError
+	)
+
+	switch {
+	case errors.Is(err, rpc.ErrClientQuit), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode >= 500
+	case errors.As(err, &netErr):
+		return true
+	}
+
+	return false
+}
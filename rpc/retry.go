@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/ubiq/go-ubiq/v7/log"
+	"github.com/ubiq/go-ubiq/v7/rpc"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultTraceTimeout = 5 * time.Minute
+	defaultRetries      = 3
+	defaultBackoff      = 500 * time.Millisecond
+	defaultMaxBatch     = 100
+	maxBackoff          = 30 * time.Second
+	minReconnectDelay   = time.Second
+)
+
+// idempotent lists the methods that are safe to send again after a failure
+
+var idempotent = map[string]bool{
+	"eth_blockNumber":                   true,
+	"eth_getBlockByNumber":              true,
+	"eth_getBlockByHash":                true,
+	"eth_getUncleByBlockNumberAndIndex": true,
+	"eth_getTransactionReceipt":         true,
+	"eth_getBalance":                    true,
+	"eth_getCode":                       true,
+	"eth_call":                          true,
+	"web3_clientVersion":                true,
+	"debug_traceBlockByNumber":          true,
+	"debug_traceTransaction":            true,
+	"debug_dumpBlock":                   true,
+}
+
+func (r *RPCClient) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return r.do(ctx, method, func(ctx context.Context, client *rpc.Client) error {
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+// batchCall sends elems in batches of at most maxBatch requests. Errors of single requests are set on
+// their element, the returned error is only set if a whole batch failed
+
+func (r *RPCClient) batchCall(ctx context.Context, elems []rpc.BatchElem) error {
+	for i := 0; i < len(elems); i += r.maxBatch {
+		end := i + r.maxBatch
+		if end > len(elems) {
+			end = len(elems)
+		}
+
+		batch := elems[i:end]
+
+		err := r.do(ctx, batch[0].Method, func(ctx context.Context, client *rpc.Client) error {
+			return client.BatchCallContext(ctx, batch)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// do runs fn with a deadline of its own, retrying idempotent methods with exponential backoff
+// while no node can be reached. Once every node is down it redials them before the next attempt
+
+func (r *RPCClient) do(ctx context.Context, method string, fn func(context.Context, *rpc.Client) error) error {
+	attempts := 1
+	if idempotent[method] {
+		attempts += r.retries
+	}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+
+		cctx, cancel := context.WithTimeout(ctx, r.timeoutFor(method))
+		err := r.callOnce(cctx, method, fn)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+
+		if !r.retryable(ctx, err) {
+			return err
+		}
+
+		if attempt >= attempts {
+			log.Error("rpc call failed", "method", method, "attempts", attempt, "err", err)
+			return err
+		}
+
+		delay := r.backoffFor(attempt)
+
+		log.Warn("retrying rpc call", "method", method, "attempt", attempt, "took", time.Since(start), "delay", delay, "err", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if err == ErrNoHealthyNodes || isConnectionError(err) {
+			r.reconnect()
+		}
+	}
+}
+
+// retryable tells whether err is worth another attempt: the node couldn't be reached or the attempt's
+// own deadline ran out, while the caller's context is still alive
+
+func (r *RPCClient) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	return err == ErrNoHealthyNodes || isConnectionError(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (r *RPCClient) timeoutFor(method string) time.Duration {
+	if len(method) > 6 && method[:6] == "debug_" {
+		return r.traceTimeout
+	}
+
+	return r.timeout
+}
+
+// backoffFor doubles the delay on every attempt up to maxBackoff, picking a random delay in the upper half
+// so that crawler routines failing at the same time don't retry in lockstep
+
+func (r *RPCClient) backoffFor(attempt int) time.Duration {
+	d := r.backoff << uint(attempt-1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// reconnect runs a health check right away, which dials every node that is down. Calls failing at the same
+// time share one check
+
+func (r *RPCClient) reconnect() {
+	r.healthMu.Lock()
+	recent := time.Since(r.lastCheck) < minReconnectDelay
+	r.healthMu.Unlock()
+
+	if recent {
+		return
+	}
+
+	log.Info("reconnecting rpc nodes", "nodes", len(r.nodes))
+
+	r.checkHealth()
+}
+
+// parseDuration returns def if s is empty or invalid
+
+func parseDuration(name, s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Error("can't parse rpc "+name, "d", s, "err", err)
+		return def
+	}
+
+	return d
+}
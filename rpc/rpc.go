@@ -4,11 +4,10 @@ import (
 	"context"
 	"errors"
 	"math/big"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/ubiq/go-ubiq/v7/log"
-
 	"github.com/mitchellh/go-homedir"
 	"github.com/ubiq/go-ubiq/v7/common/hexutil"
 	"github.com/ubiq/go-ubiq/v7/rpc"
@@ -17,16 +16,49 @@ import (
 	"github.com/octanolabs/go-spectrum/util"
 )
 
+// Config takes either a single Type/Endpoint or a list of Endpoints. Nodes whose head is more than
+// MaxLag blocks behind the best node aren't used. Timeout applies to each attempt of a call, TraceTimeout
+// replaces it for debug_ methods. Retries is how many times idempotent calls are retried, 3 when it's left
+// out, 0 turns retries off. MaxBatch caps the number of requests sent in a single batch.
+// TraceEndpoint is an optional http endpoint used to stream block traces
+
 type Config struct {
+	Type           string     `json:"type"`
+	Endpoint       string     `json:"endpoint"`
+	Endpoints      []Endpoint `json:"endpoints"`
+	MaxLag         uint64     `json:"max_lag"`
+	HealthInterval string     `json:"health_interval"`
+	Timeout        string     `json:"timeout"`
+	TraceTimeout   string     `json:"trace_timeout"`
+	Retries        *int       `json:"retries"`
+	Backoff        string     `json:"backoff"`
+	MaxBatch       int        `json:"max_batch"`
+	TraceEndpoint  string     `json:"trace_endpoint"`
+}
+
+// Endpoint is a single gubiq node. Nodes with lower priority values are preferred
+
+type Endpoint struct {
 	Type     string `json:"type"`
 	Endpoint string `json:"endpoint"`
+	Priority int    `json:"priority"`
 }
 
 type RPCClient struct {
-	client *rpc.Client
+	nodes         []*node
+	maxLag        uint64
+	timeout       time.Duration
+	traceTimeout  time.Duration
+	retries       int
+	backoff       time.Duration
+	maxBatch      int
+	traceEndpoint string
+
+	healthMu  sync.Mutex
+	lastCheck time.Time
 }
 
-func dialNewClient(cfg *Config) (*rpc.Client, error) {
+func dialNewClient(ctx context.Context, cfg *Endpoint) (*rpc.Client, error) {
 
 	var (
 		client *rpc.Client
@@ -39,11 +71,11 @@ func dialNewClient(cfg *Config) (*rpc.Client, error) {
 			return nil, err
 		}
 	case "unix", "ipc":
-		if client, err = rpc.DialIPC(context.Background(), cfg.Endpoint); err != nil {
+		if client, err = rpc.DialIPC(ctx, cfg.Endpoint); err != nil {
 			return nil, err
 		}
 	case "ws", "websocket", "websockets":
-		if client, err = rpc.DialWebsocket(context.Background(), cfg.Endpoint, ""); err != nil {
+		if client, err = rpc.DialWebsocket(ctx, cfg.Endpoint, ""); err != nil {
 			return nil, err
 		}
 	default:
@@ -51,7 +83,7 @@ func dialNewClient(cfg *Config) (*rpc.Client, error) {
 		if err != nil {
 			return nil, err
 		}
-		if client, err = rpc.DialIPC(context.Background(), fp); err != nil {
+		if client, err = rpc.DialIPC(ctx, fp); err != nil {
 			return nil, err
 		}
 	}
@@ -59,15 +91,41 @@ func dialNewClient(cfg *Config) (*rpc.Client, error) {
 	return client, nil
 }
 
+// NewRPCClient dials every configured endpoint and keeps checking their health in the background.
+// Endpoints that can't be reached are dialed again on the next health check
+
 func NewRPCClient(cfg *Config) *RPCClient {
 
-	client, err := dialNewClient(cfg)
-	if err != nil {
-		log.Error("could not dial rpc client", "err", err)
-		os.Exit(1)
+	endpoints := cfg.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = []Endpoint{{Type: cfg.Type, Endpoint: cfg.Endpoint}}
+	}
+
+	rpcClient := &RPCClient{
+		maxLag:        cfg.MaxLag,
+		timeout:       parseDuration("timeout", cfg.Timeout, defaultTimeout),
+		traceTimeout:  parseDuration("trace timeout", cfg.TraceTimeout, defaultTraceTimeout),
+		retries:       defaultRetries,
+		backoff:       parseDuration("backoff", cfg.Backoff, defaultBackoff),
+		maxBatch:      cfg.MaxBatch,
+		traceEndpoint: cfg.TraceEndpoint,
+	}
+
+	if cfg.Retries != nil && *cfg.Retries >= 0 {
+		rpcClient.retries = *cfg.Retries
+	}
+
+	if rpcClient.maxBatch < 1 {
+		rpcClient.maxBatch = defaultMaxBatch
+	}
+
+	for _, e := range endpoints {
+		rpcClient.nodes = append(rpcClient.nodes, &node{Endpoint: e})
 	}
 
-	rpcClient := &RPCClient{client}
+	rpcClient.checkHealth()
+
+	go rpcClient.monitor(parseDuration("health interval", cfg.HealthInterval, defaultHealthInterval))
 
 	return rpcClient
 }
@@ -75,7 +133,7 @@ func NewRPCClient(cfg *Config) *RPCClient {
 func (r *RPCClient) getBlockBy(method string, params ...interface{}) (models.Block, error) {
 	var reply models.RawBlock
 
-	err := r.client.Call(&reply, method, params...)
+	err := r.call(context.Background(), &reply, method, params...)
 
 	if err != nil {
 		return models.Block{}, err
@@ -87,7 +145,7 @@ func (r *RPCClient) getBlockBy(method string, params ...interface{}) (models.Blo
 func (r *RPCClient) getUncleBy(method string, params ...interface{}) (models.Uncle, error) {
 	var reply models.RawUncle
 
-	err := r.client.Call(&reply, method, params...)
+	err := r.call(context.Background(), &reply, method, params...)
 	if err != nil {
 		return models.Uncle{}, err
 	}
@@ -109,6 +167,21 @@ func (r *RPCClient) GetBlockByHeight(height uint64) (models.Block, error) {
 	return r.getBlockBy("eth_getBlockByNumber", hexutil.EncodeUint64(height), true)
 }
 
+// GetBlockHash returns the hash of the canonical block at height, without fetching its transactions
+
+func (r *RPCClient) GetBlockHash(height uint64) (string, error) {
+	var reply models.RawBlockDetails
+
+	err := r.call(context.Background(), &reply, "eth_getBlockByNumber", hexutil.EncodeUint64(height), false)
+	if err != nil {
+		return "", err
+	}
+
+	_, hash := reply.Convert()
+
+	return hash, nil
+}
+
 func (r *RPCClient) GetBlockByHash(hash string) (models.Block, error) {
 	return r.getBlockBy("eth_getBlockByHash", hash, true)
 }
@@ -136,7 +209,7 @@ func (r *RPCClient) GetUnclesInBlock(uncles []string, height uint64) ([]models.U
 func (r *RPCClient) LatestBlockNumber() (uint64, error) {
 	var bn string
 
-	err := r.client.Call(&bn, "eth_blockNumber")
+	err := r.call(context.Background(), &bn, "eth_blockNumber")
 	if err != nil {
 		return 0, err
 	}
@@ -148,7 +221,7 @@ func (r *RPCClient) LatestBlockNumber() (uint64, error) {
 func (r *RPCClient) GetTxReceipt(hash string) (models.TxReceipt, error) {
 	var reply models.RawTxReceipt
 
-	err := r.client.Call(&reply, "eth_getTransactionReceipt", hash)
+	err := r.call(context.Background(), &reply, "eth_getTransactionReceipt", hash)
 
 	if err != nil {
 		return models.TxReceipt{}, err
@@ -157,10 +230,50 @@ func (r *RPCClient) GetTxReceipt(hash string) (models.TxReceipt, error) {
 	return reply.Convert(), nil
 }
 
+// GetTxReceipts fetches the receipts of hashes in batches. Receipts are returned in the same order as hashes,
+// if some of them couldn't be fetched they are left empty and the first error is returned
+
+func (r *RPCClient) GetTxReceipts(hashes []string) ([]models.TxReceipt, error) {
+	var (
+		replies  = make([]models.RawTxReceipt, len(hashes))
+		receipts = make([]models.TxReceipt, len(hashes))
+		elems    = make([]rpc.BatchElem, len(hashes))
+	)
+
+	for i, hash := range hashes {
+		elems[i] = rpc.BatchElem{Method: "eth_getTransactionReceipt", Args: []interface{}{hash}, Result: &replies[i]}
+	}
+
+	if err := r.batchCall(context.Background(), elems); err != nil {
+		return receipts, err
+	}
+
+	var err error
+
+	for i := range elems {
+		if elems[i].Error != nil {
+			if err == nil {
+				err = elems[i].Error
+			}
+			continue
+		}
+		// a node that doesn't have the receipt replies null
+		if replies[i].BlockHash == "" {
+			if err == nil {
+				err = errors.New("receipt not found: " + hashes[i])
+			}
+			continue
+		}
+		receipts[i] = replies[i].Convert()
+	}
+
+	return receipts, err
+}
+
 func (r *RPCClient) Ping() (string, error) {
 	var version string
 
-	err := r.client.Call(&version, "web3_clientVersion")
+	err := r.call(context.Background(), &version, "web3_clientVersion")
 	if err != nil {
 		return "", err
 	}
@@ -171,7 +284,7 @@ func (r *RPCClient) Ping() (string, error) {
 func (r *RPCClient) TraceBlock(blockNumber uint64) ([]models.BlockTrace, error) {
 	var trace []models.RawBlockTrace
 
-	err := r.client.Call(&trace, "debug_traceBlockByNumber", hexutil.EncodeUint64(blockNumber))
+	err := r.call(context.Background(), &trace, "debug_traceBlockByNumber", hexutil.EncodeUint64(blockNumber))
 
 	if err != nil {
 		return []models.BlockTrace{}, err
@@ -189,15 +302,7 @@ func (r *RPCClient) TraceBlock(blockNumber uint64) ([]models.BlockTrace, error)
 func (r *RPCClient) TraceTransaction(hash string) (models.ITransaction, error) {
 	var trace models.RawTxTrace
 
-	c, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-
-	defer cancel()
-
-	err := r.client.CallContext(c, &trace, "debug_traceTransaction", hash, map[string]interface{}{
-		"tracer": "callTracer",
-		//if tracer errors out with "execution timeout" increase this timeout
-		"timeout": "300s",
-	})
+	err := r.call(context.Background(), &trace, "debug_traceTransaction", hash, r.tracerConfig())
 	if err != nil {
 		return models.ITransaction{}, err
 	}
@@ -208,7 +313,7 @@ func (r *RPCClient) TraceTransaction(hash string) (models.ITransaction, error) {
 func (r *RPCClient) GetState(blockNumber uint64) (models.RawState, error) {
 	var state models.RawState
 
-	err := r.client.Call(&state, "debug_dumpBlock", hexutil.EncodeUint64(blockNumber))
+	err := r.call(context.Background(), &state, "debug_dumpBlock", hexutil.EncodeUint64(blockNumber))
 
 	if err != nil {
 		return models.RawState{}, err
@@ -220,7 +325,7 @@ func (r *RPCClient) GetState(blockNumber uint64) (models.RawState, error) {
 func (r *RPCClient) GetBalance(address string, blockNumber uint64) (big.Int, error) {
 	var balance string
 
-	err := r.client.Call(&balance, "eth_getBalance", address, hexutil.EncodeUint64(blockNumber))
+	err := r.call(context.Background(), &balance, "eth_getBalance", address, hexutil.EncodeUint64(blockNumber))
 	if err != nil {
 		return *new(big.Int).SetUint64(0), err
 	}
@@ -229,3 +334,167 @@ func (r *RPCClient) GetBalance(address string, blockNumber uint64) (big.Int, err
 
 	return *decoded, nil
 }
+
+// GetBalances fetches the balances of addresses at blockNumber in batches. Balances are returned in the same
+// order as addresses, if some of them couldn't be fetched they are nil and the first error is returned
+
+func (r *RPCClient) GetBalances(addresses []string, blockNumber uint64) ([]*big.Int, error) {
+	var (
+		replies  = make([]string, len(addresses))
+		balances = make([]*big.Int, len(addresses))
+		elems    = make([]rpc.BatchElem, len(addresses))
+	)
+
+	for i, address := range addresses {
+		elems[i] = rpc.BatchElem{Method: "eth_getBalance", Args: []interface{}{address, hexutil.EncodeUint64(blockNumber)}, Result: &replies[i]}
+	}
+
+	if err := r.batchCall(context.Background(), elems); err != nil {
+		return balances, err
+	}
+
+	var err error
+
+	for i := range elems {
+		if elems[i].Error == nil {
+			balances[i], elems[i].Error = hexutil.DecodeBig(replies[i])
+		}
+
+		if elems[i].Error != nil && err == nil {
+			err = elems[i].Error
+		}
+	}
+
+	return balances, err
+}
+
+// GetCode returns the runtime code currently deployed at address
+
+func (r *RPCClient) GetCode(address string) ([]byte, error) {
+	var code hexutil.Bytes
+
+	err := r.call(context.Background(), &code, "eth_getCode", address, "latest")
+	if err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
+
+// GetCodes returns the runtime code of each of addresses at blockNumber, in batches. Failed requests leave a nil
+// code and the first error is returned
+
+func (r *RPCClient) GetCodes(addresses []string, blockNumber uint64) ([][]byte, error) {
+	var (
+		replies = make([]hexutil.Bytes, len(addresses))
+		codes   = make([][]byte, len(addresses))
+		elems   = make([]rpc.BatchElem, len(addresses))
+	)
+
+	for i, address := range addresses {
+		elems[i] = rpc.BatchElem{Method: "eth_getCode", Args: []interface{}{address, hexutil.EncodeUint64(blockNumber)}, Result: &replies[i]}
+	}
+
+	if err := r.batchCall(context.Background(), elems); err != nil {
+		return codes, err
+	}
+
+	var err error
+
+	for i := range elems {
+		if elems[i].Error != nil {
+			if err == nil {
+				err = elems[i].Error
+			}
+			continue
+		}
+		codes[i] = replies[i]
+	}
+
+	return codes, err
+}
+
+// CallContract runs a read only call of contract at blockNumber and returns its output
+
+func (r *RPCClient) CallContract(contract string, data []byte, blockNumber uint64) ([]byte, error) {
+	var result hexutil.Bytes
+
+	msg := map[string]interface{}{
+		"to":   contract,
+		"data": hexutil.Bytes(data),
+	}
+
+	err := r.call(context.Background(), &result, "eth_call", msg, hexutil.EncodeUint64(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CallContracts runs a read only call for each of contracts with the matching input at blockNumber, in batches.
+// Outputs are returned in the same order. Calls that reverted or otherwise failed in the evm have a nil output,
+// the first error the node or transport returned for the others is returned
+
+func (r *RPCClient) CallContracts(contracts []string, inputs [][]byte, blockNumber uint64) ([][]byte, error) {
+	var (
+		replies = make([]hexutil.Bytes, len(contracts))
+		outputs = make([][]byte, len(contracts))
+		elems   = make([]rpc.BatchElem, len(contracts))
+	)
+
+	for i := range contracts {
+		msg := map[string]interface{}{
+			"to":   contracts[i],
+			"data": hexutil.Bytes(inputs[i]),
+		}
+		elems[i] = rpc.BatchElem{Method: "eth_call", Args: []interface{}{msg, hexutil.EncodeUint64(blockNumber)}, Result: &replies[i]}
+	}
+
+	if err := r.batchCall(context.Background(), elems); err != nil {
+		return outputs, err
+	}
+
+	var err error
+
+	for i := range elems {
+		if elems[i].Error != nil {
+			if err == nil && !isCallFailure(elems[i].Error) {
+				err = elems[i].Error
+			}
+			continue
+		}
+		outputs[i] = replies[i]
+	}
+
+	return outputs, err
+}
+
+// vmErrors are how nodes report a call that failed while it ran, rather than one they couldn't serve
+var vmErrors = []string{
+	"execution reverted",
+	"invalid opcode",
+	"out of gas",
+	"invalid jump destination",
+	"stack underflow",
+	"stack limit reached",
+	"write protection",
+	"return data out of bounds",
+	"max call depth exceeded",
+}
+
+// isCallFailure tells whether err is the failure of the call itself
+func isCallFailure(err error) bool {
+	// revert with a reason
+	if e, ok := err.(rpc.Error); ok && e.ErrorCode() == 3 {
+		return true
+	}
+
+	for _, msg := range vmErrors {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+
+	return false
+}
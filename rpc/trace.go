@@ -0,0 +1,233 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ubiq/go-ubiq/v7/common/hexutil"
+	"github.com/ubiq/go-ubiq/v7/log"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+// TraceBlockCalls runs callTracer on every transaction of a block with a single debug_traceBlockByNumber call.
+// Traces are returned in transaction order, those the node failed to produce are nil.
+// If a trace endpoint is configured the response is streamed over http and decoded one transaction at a time,
+// as busy blocks easily go past the websocket read limit
+
+func (r *RPCClient) TraceBlockCalls(blockNumber uint64) ([]*models.ITransaction, error) {
+	var (
+		traces []*models.ITransaction
+		params = []interface{}{hexutil.EncodeUint64(blockNumber), r.tracerConfig()}
+	)
+
+	add := func(t *models.RawBlockTxTrace) {
+		if t.Error != "" {
+			log.Warn("couldn't trace transaction", "block", blockNumber, "index", len(traces), "hash", t.TxHash, "err", t.Error)
+			traces = append(traces, nil)
+			return
+		}
+
+		itx := t.Result.Convert()
+		traces = append(traces, &itx)
+	}
+
+	if r.traceEndpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), r.traceTimeout)
+		defer cancel()
+
+		err := streamCall(ctx, r.traceEndpoint, "debug_traceBlockByNumber", params, func(dec *json.Decoder) error {
+			var t models.RawBlockTxTrace
+
+			if err := dec.Decode(&t); err != nil {
+				return err
+			}
+
+			add(&t)
+
+			return nil
+		})
+
+		return traces, err
+	}
+
+	var reply []models.RawBlockTxTrace
+
+	err := r.call(context.Background(), &reply, "debug_traceBlockByNumber", params...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reply {
+		add(&reply[i])
+	}
+
+	return traces, nil
+}
+
+// TraceBlockStateDiffs runs prestateTracer in diff mode on every transaction of a block, and returns the state
+// changes of each transaction in order. Unlike call traces a missing diff makes the rest unusable, so any
+// transaction the node failed to trace fails the whole block
+
+func (r *RPCClient) TraceBlockStateDiffs(blockNumber uint64) ([]*models.RawStateDiff, error) {
+	var (
+		diffs  []*models.RawStateDiff
+		params = []interface{}{hexutil.EncodeUint64(blockNumber), r.prestateConfig()}
+	)
+
+	add := func(d *models.RawBlockStateDiff) error {
+		if d.Error != "" {
+			return fmt.Errorf("couldn't trace state of transaction %v: %v", d.TxHash, d.Error)
+		}
+
+		diffs = append(diffs, &d.Result)
+
+		return nil
+	}
+
+	if r.traceEndpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), r.traceTimeout)
+		defer cancel()
+
+		err := streamCall(ctx, r.traceEndpoint, "debug_traceBlockByNumber", params, func(dec *json.Decoder) error {
+			var d models.RawBlockStateDiff
+
+			if err := dec.Decode(&d); err != nil {
+				return err
+			}
+
+			return add(&d)
+		})
+
+		return diffs, err
+	}
+
+	var reply []models.RawBlockStateDiff
+
+	err := r.call(context.Background(), &reply, "debug_traceBlockByNumber", params...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reply {
+		if err := add(&reply[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return diffs, nil
+}
+
+func (r *RPCClient) tracerConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"tracer": "callTracer",
+		//if tracer errors out with "execution timeout" increase this timeout along with trace_timeout
+		"timeout": r.traceTimeout.String(),
+	}
+}
+
+// streamCall posts a json-rpc request to an http endpoint whose result is an array, and calls fn for each of
+// its elements as they are read so that the response is never held in memory as a whole
+
+func streamCall(ctx context.Context, endpoint, method string, params []interface{}, fn func(*json.Decoder) error) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "result":
+			if err := expectDelim(dec, '['); err != nil {
+				return err
+			}
+
+			for dec.More() {
+				if err := fn(dec); err != nil {
+					return err
+				}
+			}
+
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		case "error":
+			var rpcErr struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+
+			if err := dec.Decode(&rpcErr); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("%s: %s (%d)", method, rpcErr.Message, rpcErr.Code)
+		default:
+			var skip json.RawMessage
+
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != d {
+		return errors.New("unexpected token in rpc response: " + fmt.Sprint(t))
+	}
+
+	return nil
+}
+
+func (r *RPCClient) prestateConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"tracer":  "prestateTracer",
+		"timeout": r.traceTimeout.String(),
+		// storage isn't used, nodes that don't know the option return it anyway
+		"tracerConfig": map[string]interface{}{"diffMode": true, "disableStorage": true},
+	}
+}
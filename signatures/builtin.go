@@ -0,0 +1,107 @@
+package signatures
+
+// builtin holds the function and event signatures of the common token, ownership and dex contracts,
+// so that calls to them are labelled without importing anything
+var builtin = []string{
+	// erc-20
+	"transfer(address,uint256)",
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+	"increaseAllowance(address,uint256)",
+	"decreaseAllowance(address,uint256)",
+	"balanceOf(address)",
+	"allowance(address,address)",
+	"totalSupply()",
+	"name()",
+	"symbol()",
+	"decimals()",
+	"mint(address,uint256)",
+	"burn(uint256)",
+	"burnFrom(address,uint256)",
+	"Transfer(address,address,uint256)",
+	"Approval(address,address,uint256)",
+
+	// weth
+	"deposit()",
+	"withdraw(uint256)",
+	"Deposit(address,uint256)",
+	"Withdrawal(address,uint256)",
+
+	// erc-721
+	"ownerOf(uint256)",
+	"safeTransferFrom(address,address,uint256)",
+	"safeTransferFrom(address,address,uint256,bytes)",
+	"setApprovalForAll(address,bool)",
+	"getApproved(uint256)",
+	"isApprovedForAll(address,address)",
+	"tokenURI(uint256)",
+	"ApprovalForAll(address,address,bool)",
+
+	// erc-1155
+	"safeTransferFrom(address,address,uint256,uint256,bytes)",
+	"safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)",
+	"balanceOfBatch(address[],uint256[])",
+	"uri(uint256)",
+	"TransferSingle(address,address,address,uint256,uint256)",
+	"TransferBatch(address,address,address,uint256[],uint256[])",
+	"URI(string,uint256)",
+
+	// erc-165
+	"supportsInterface(bytes4)",
+
+	// ownership and access control
+	"owner()",
+	"transferOwnership(address)",
+	"renounceOwnership()",
+	"OwnershipTransferred(address,address)",
+	"grantRole(bytes32,address)",
+	"revokeRole(bytes32,address)",
+	"renounceRole(bytes32,address)",
+	"hasRole(bytes32,address)",
+	"RoleGranted(bytes32,address,address)",
+	"RoleRevoked(bytes32,address,address)",
+	"pause()",
+	"unpause()",
+	"Paused(address)",
+	"Unpaused(address)",
+
+	// multicall and proxies
+	"aggregate((address,bytes)[])",
+	"multicall(bytes[])",
+	"upgradeTo(address)",
+	"upgradeToAndCall(address,bytes)",
+	"Upgraded(address)",
+	"AdminChanged(address,address)",
+
+	// uniswap v2 style dexes
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokens(uint256,address[],address,uint256)",
+	"swapTokensForExactETH(uint256,uint256,address[],address,uint256)",
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+	"swapETHForExactTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForTokensSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokensSupportingFeeOnTransferTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+	"addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)",
+	"addLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+	"removeLiquidity(address,address,uint256,uint256,uint256,address,uint256)",
+	"removeLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+	"createPair(address,address)",
+	"getReserves()",
+	"swap(uint256,uint256,address,bytes)",
+	"sync()",
+	"skim(address)",
+	"Swap(address,uint256,uint256,uint256,uint256,address)",
+	"Sync(uint112,uint112)",
+	"Mint(address,uint256,uint256)",
+	"Burn(address,uint256,uint256,address)",
+	"PairCreated(address,address,address,uint256)",
+
+	// staking
+	"stake(uint256)",
+	"unstake(uint256)",
+	"claim()",
+	"getReward()",
+	"exit()",
+}
@@ -0,0 +1,101 @@
+// Package signatures maps 4-byte function selectors and 32-byte event topics to the text signatures they were
+// hashed from. A set of common signatures is built in, more can be loaded from a file with one signature per line
+package signatures
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ubiq/go-ubiq/v7/common/hexutil"
+	"github.com/ubiq/go-ubiq/v7/crypto"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+var signatureRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*\([A-Za-z0-9_$\[\](),]*\)$`)
+
+// Parse returns the function selector and event topic of a text signature. Whitespace is ignored,
+// as the hash is taken of the canonical form
+
+func Parse(text string) (function, event models.Signature, err error) {
+	text = strings.Join(strings.Fields(text), "")
+
+	if !signatureRegexp.MatchString(text) {
+		return function, event, fmt.Errorf("invalid signature %q", text)
+	}
+
+	hash := crypto.Keccak256([]byte(text))
+
+	function = models.Signature{Hash: hexutil.Encode(hash[:4]), Text: text, Type: models.SignatureFunction}
+	event = models.Signature{Hash: hexutil.Encode(hash), Text: text, Type: models.SignatureEvent}
+
+	return function, event, nil
+}
+
+// Builtin returns the built in signatures, both as functions and events
+
+func Builtin() []models.Signature {
+	sigs := make([]models.Signature, 0, 2*len(builtin))
+
+	for _, text := range builtin {
+		function, event, err := Parse(text)
+		if err != nil {
+			panic(err)
+		}
+
+		function.Builtin, event.Builtin = true, true
+
+		sigs = append(sigs, function, event)
+	}
+
+	return sigs
+}
+
+// LoadFile reads text signatures from a file, one per line. Empty lines and lines starting with # are skipped,
+// and a leading hex hash as found in 4-byte dumps is ignored since hashes are computed from the text
+
+func LoadFile(path string) ([]models.Signature, error) {
+	var sigs []models.Signature
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if fields := strings.SplitN(line, " ", 2); len(fields) == 2 && strings.HasPrefix(fields[0], "0x") {
+			line = fields[1]
+		}
+
+		function, event, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+
+		sigs = append(sigs, function, event)
+	}
+
+	return sigs, scanner.Err()
+}
+
+// Name returns the function or event name of a text signature
+
+func Name(text string) string {
+	if i := strings.IndexByte(text, '('); i >= 0 {
+		return text[:i]
+	}
+
+	return text
+}
@@ -0,0 +1,69 @@
+package signatures
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+func TestParse(t *testing.T) {
+	function, event, err := Parse("transfer(address, uint256)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if function.Hash != "0xa9059cbb" || function.Text != "transfer(address,uint256)" {
+		t.Errorf("unexpected function signature %+v", function)
+	}
+
+	_, event, err = Parse("Transfer(address,address,uint256)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Hash != models.TransferTopic {
+		t.Errorf("unexpected event signature %+v", event)
+	}
+
+	if _, _, err := Parse("transfer address"); err == nil {
+		t.Error("parsed invalid signature")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "signatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("# comment\n\n0xa9059cbb transfer(address,uint256)\nbalanceOf(address)\n")
+	f.Close()
+
+	sigs, err := LoadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sigs) != 4 || sigs[0].Hash != "0xa9059cbb" || sigs[2].Hash != "0x70a08231" {
+		t.Errorf("unexpected signatures %+v", sigs)
+	}
+
+	if sigs[1].Type != models.SignatureEvent || len(sigs[1].Hash) != 66 {
+		t.Errorf("expected event signature, got %+v", sigs[1])
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for _, s := range Builtin() {
+		key := s.Type + s.Text
+		if seen[key] {
+			t.Errorf("duplicate builtin signature %v", s.Text)
+		}
+		seen[key] = true
+	}
+}
@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+	"github.com/ubiq/go-ubiq/v7/common"
+	"github.com/ubiq/go-ubiq/v7/common/hexutil"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrVerifiedABI is returned when an upload would replace the ABI of a verified contract
+var ErrVerifiedABI = errors.New("the ABI of a verified contract can't be replaced")
+
+// AddContractABI stores the json ABI of a contract, replacing the previous one unless it came from verification.
+// The ABI is rejected if it can't be parsed
+
+func (m *MongoDB) AddContractABI(address string, abiJSON string) error {
+	return m.addContractABI(address, abiJSON, false)
+}
+
+// addContractABI stores an ABI, one that came from verification replaces any other
+
+func (m *MongoDB) addContractABI(address string, abiJSON string, verified bool) error {
+	if _, err := abi.JSON(strings.NewReader(abiJSON)); err != nil {
+		return err
+	}
+
+	address = strings.ToLower(address)
+
+	filter := bson.M{"address": address}
+	if !verified {
+		filter["verified"] = bson.M{"$ne": true}
+	}
+
+	_, err := m.C(models.ABIS).UpdateOne(context.Background(), filter, bson.D{{"$set", &models.ContractABI{
+		Address:  address,
+		ABI:      abiJSON,
+		Updated:  time.Now().Unix(),
+		Verified: verified,
+	}}}, options.Update().SetUpsert(true))
+
+	// the verified ABI didn't match the filter, so the upsert ran into it
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrVerifiedABI
+	}
+
+	return err
+}
+
+func (m *MongoDB) ContractABI(address string) (models.ContractABI, error) {
+	var contractABI models.ContractABI
+
+	err := m.C(models.ABIS).FindOne(context.Background(), bson.M{"address": strings.ToLower(address)}, options.FindOne()).Decode(&contractABI)
+	return contractABI, err
+}
+
+// DecodeTransaction returns the input, internal calls and logs of a transaction that could be decoded with the
+// ABIs in the registry
+
+func (m *MongoDB) DecodeTransaction(hash string) (models.DecodedTransaction, error) {
+	return decodedTransaction(hash, m.TransactionByHash)
+}
+
+// decodedTransaction collects the decoded parts of the transaction returned by get
+
+func decodedTransaction(hash string, get func(hash string) (models.Transaction, error)) (models.DecodedTransaction, error) {
+	decoded := models.DecodedTransaction{Hash: hash, Calls: make([]*models.DecodedCall, 0), Logs: make([]*models.DecodedLog, 0)}
+
+	txn, err := get(hash)
+	if err != nil {
+		return decoded, err
+	}
+
+	decoded.Input = txn.Decoded
+
+	// the root of the trace is the transaction itself
+	walkCalls(txn.Trace.Calls, func(call *models.ITransaction) {
+		if call.Decoded != nil {
+			decoded.Calls = append(decoded.Calls, call.Decoded)
+		}
+	})
+
+	for _, l := range txn.Logs {
+		if l.Decoded != nil {
+			decoded.Logs = append(decoded.Logs, l.Decoded)
+		}
+	}
+
+	return decoded, nil
+}
+
+// decodeTransaction decodes the input, internal calls and logs of txn in place, wherever the ABI of the contract
+// is known. Anything that can't be decoded is left as is. contractABIs looks up the ABIs of a set of addresses
+
+func decodeTransaction(txn *models.Transaction, contractABIs func(addresses []string) (map[string]*abi.ABI, error)) error {
+	addresses := []string{txn.To}
+
+	walkCalls([]models.ITransaction{txn.Trace}, func(call *models.ITransaction) {
+		addresses = append(addresses, call.To)
+	})
+
+	for _, l := range txn.Logs {
+		addresses = append(addresses, l.Address)
+	}
+
+	abis, err := contractABIs(addresses)
+	if err != nil || len(abis) == 0 {
+		return err
+	}
+
+	if txn.To != "" {
+		txn.Decoded = decodeCall(abis[strings.ToLower(txn.To)], txn.To, txn.Input)
+	}
+
+	decodeCalls := func(call *models.ITransaction) {
+		call.Decoded = decodeCall(abis[strings.ToLower(call.To)], call.To, call.Input)
+	}
+
+	walkCalls([]models.ITransaction{txn.Trace}, decodeCalls)
+
+	for i := range txn.ITransactions {
+		decodeCalls(&txn.ITransactions[i])
+	}
+
+	for i := range txn.Logs {
+		txn.Logs[i].Decoded = decodeLog(abis[strings.ToLower(txn.Logs[i].Address)], &txn.Logs[i])
+	}
+
+	return nil
+}
+
+// contractABIs returns the parsed ABIs of addresses, those without one or whose ABI doesn't parse are left out
+
+func (m *MongoDB) contractABIs(addresses []string) (map[string]*abi.ABI, error) {
+	var (
+		stored []models.ContractABI
+		filter = abiAddresses(addresses)
+	)
+
+	if len(filter) == 0 {
+		return make(map[string]*abi.ABI), nil
+	}
+
+	c, err := m.C(models.ABIS).Find(context.Background(), bson.M{"address": bson.M{"$in": filter}}, options.Find())
+	if err != nil {
+		return make(map[string]*abi.ABI), err
+	}
+
+	if err = c.All(context.Background(), &stored); err != nil {
+		return make(map[string]*abi.ABI), err
+	}
+
+	return parseABIs(stored), nil
+}
+
+// abiAddresses lowercases and dedupes addresses, leaving out empty ones
+
+func abiAddresses(addresses []string) []string {
+	var (
+		filter  = make([]string, 0, len(addresses))
+		present = make(map[string]bool)
+	)
+
+	for _, a := range addresses {
+		a = strings.ToLower(a)
+		if a != "" && !present[a] {
+			present[a] = true
+			filter = append(filter, a)
+		}
+	}
+
+	return filter
+}
+
+func parseABIs(stored []models.ContractABI) map[string]*abi.ABI {
+	abis := make(map[string]*abi.ABI)
+
+	for _, s := range stored {
+		parsed, err := abi.JSON(strings.NewReader(s.ABI))
+		if err != nil {
+			log.Warn("couldn't parse stored abi", "address", s.Address, "err", err)
+			continue
+		}
+		abis[s.Address] = &parsed
+	}
+
+	return abis
+}
+
+// walkCalls calls fn on each call and, depth first, on all of their subcalls
+
+func walkCalls(calls []models.ITransaction, fn func(*models.ITransaction)) {
+	for i := range calls {
+		fn(&calls[i])
+		walkCalls(calls[i].Calls, fn)
+	}
+}
+
+func decodeCall(contractABI *abi.ABI, contract string, input string) *models.DecodedCall {
+	if contractABI == nil {
+		return nil
+	}
+
+	data, err := hexutil.Decode(input)
+	if err != nil || len(data) < 4 {
+		return nil
+	}
+
+	method, err := contractABI.MethodById(data[:4])
+	if err != nil {
+		return nil
+	}
+
+	values, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil
+	}
+
+	call := &models.DecodedCall{Contract: strings.ToLower(contract), Method: method.RawName, Signature: method.Sig, Args: make([]models.DecodedArg, len(values))}
+
+	for i, v := range values {
+		call.Args[i] = models.DecodedArg{Name: method.Inputs[i].Name, Type: method.Inputs[i].Type.String(), Value: abiValue(reflect.ValueOf(v))}
+	}
+
+	return call
+}
+
+func decodeLog(contractABI *abi.ABI, l *models.TxLog) *models.DecodedLog {
+	if contractABI == nil || len(l.Topics) == 0 {
+		return nil
+	}
+
+	event, err := contractABI.EventByID(common.HexToHash(l.Topics[0]))
+	if err != nil {
+		return nil
+	}
+
+	data, err := hexutil.Decode(l.Data)
+	if err != nil {
+		return nil
+	}
+
+	values, err := event.Inputs.NonIndexed().Unpack(data)
+	if err != nil {
+		return nil
+	}
+
+	// topics are parsed into a map by name, unnamed arguments would overwrite each other so they get their position
+	var indexed abi.Arguments
+	for i, arg := range event.Inputs {
+		if arg.Indexed {
+			arg.Name = argKey(arg, i)
+			indexed = append(indexed, arg)
+		}
+	}
+
+	if len(indexed) != len(l.Topics)-1 {
+		return nil
+	}
+
+	topics := make(map[string]interface{})
+	hashes := make([]common.Hash, len(indexed))
+	for i := range indexed {
+		hashes[i] = common.HexToHash(l.Topics[i+1])
+	}
+
+	if err := abi.ParseTopicsIntoMap(topics, indexed, hashes); err != nil {
+		return nil
+	}
+
+	decoded := &models.DecodedLog{Contract: strings.ToLower(l.Address), LogIndex: l.LogIndex, Event: event.RawName, Signature: event.Sig, Args: make([]models.DecodedArg, 0, len(event.Inputs))}
+
+	for i, arg := range event.Inputs {
+		var v interface{}
+
+		if arg.Indexed {
+			v = topics[argKey(arg, i)]
+		} else {
+			v, values = values[0], values[1:]
+		}
+
+		decoded.Args = append(decoded.Args, models.DecodedArg{Name: arg.Name, Type: arg.Type.String(), Value: abiValue(reflect.ValueOf(v)), Indexed: arg.Indexed})
+	}
+
+	return decoded
+}
+
+// argKey is the name of arg, or arg<i> for an unnamed one at position i
+
+func argKey(arg abi.Argument, i int) string {
+	if arg.Name == "" {
+		return "arg" + strconv.Itoa(i)
+	}
+
+	return arg.Name
+}
+
+var (
+	bigIntType  = reflect.TypeOf(&big.Int{})
+	addressType = reflect.TypeOf(common.Address{})
+	hashType    = reflect.TypeOf(common.Hash{})
+)
+
+// abiValue converts an unpacked value to something that serializes the way the rest of the api does:
+// integers as decimal strings, addresses lowercase, bytes as hex and tuples as objects
+
+func abiValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch {
+	case v.Type() == bigIntType:
+		if v.IsNil() {
+			return "0"
+		}
+		return v.Interface().(*big.Int).String()
+	case v.Type() == addressType:
+		return strings.ToLower(v.Interface().(common.Address).Hex())
+	case v.Type() == hashType:
+		return v.Interface().(common.Hash).Hex()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b)
+		}
+
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = abiValue(v.Index(i))
+		}
+		return values
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Tag.Get("json")
+			if name == "" {
+				name = v.Type().Field(i).Name
+			}
+			fields[name] = abiValue(v.Field(i))
+		}
+		return fields
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()).String()
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()).String()
+	}
+
+	return v.Interface()
+}
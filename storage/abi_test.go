@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+)
+
+const testABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}
+]`
+
+func TestDecodeCallAndLog(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(testABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "0xa9059cbb" +
+		"000000000000000000000000000000000000000000000000000000000000beef" +
+		"00000000000000000000000000000000000000000000000000000000000003e8"
+
+	call := decodeCall(&parsed, "0xC0", input)
+
+	wantCall := &models.DecodedCall{Contract: "0xc0", Method: "transfer", Signature: "transfer(address,uint256)", Args: []models.DecodedArg{
+		{Name: "to", Type: "address", Value: "0x000000000000000000000000000000000000beef"},
+		{Name: "value", Type: "uint256", Value: "1000"},
+	}}
+
+	if !reflect.DeepEqual(call, wantCall) {
+		t.Errorf("decodeCall got %+v, want %+v", call, wantCall)
+	}
+
+	if decodeCall(&parsed, "0xc0", "0x12345678") != nil {
+		t.Error("decoded unknown selector")
+	}
+
+	l := &models.TxLog{
+		Address:  "0xc0",
+		LogIndex: "0x1",
+		Topics: []string{
+			"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			"0x000000000000000000000000000000000000000000000000000000000000dead",
+			"0x000000000000000000000000000000000000000000000000000000000000beef",
+		},
+		Data: "0x00000000000000000000000000000000000000000000000000000000000003e8",
+	}
+
+	wantLog := &models.DecodedLog{Contract: "0xc0", LogIndex: "0x1", Event: "Transfer", Signature: "Transfer(address,address,uint256)", Args: []models.DecodedArg{
+		{Name: "from", Type: "address", Value: "0x000000000000000000000000000000000000dead", Indexed: true},
+		{Name: "to", Type: "address", Value: "0x000000000000000000000000000000000000beef", Indexed: true},
+		{Name: "value", Type: "uint256", Value: "1000"},
+	}}
+
+	if decoded := decodeLog(&parsed, l); !reflect.DeepEqual(decoded, wantLog) {
+		t.Errorf("decodeLog got %+v, want %+v", decoded, wantLog)
+	}
+
+	// unnamed indexed arguments each keep their own value
+	event := parsed.Events["Transfer"]
+	event.Inputs = append(abi.Arguments{}, event.Inputs...)
+
+	for i := range event.Inputs {
+		event.Inputs[i].Name = ""
+		wantLog.Args[i].Name = ""
+	}
+
+	parsed.Events["Transfer"] = event
+
+	if decoded := decodeLog(&parsed, l); !reflect.DeepEqual(decoded, wantLog) {
+		t.Errorf("decodeLog of unnamed arguments got %+v, want %+v", decoded, wantLog)
+	}
+}
+
+func TestMongoVerifiedABI(t *testing.T) {
+	testVerifiedABI(t, testDB(t))
+}
+
+// testVerifiedABI checks uploads replace each other but not the ABI of a verified contract
+func testVerifiedABI(t *testing.T, b Backend) {
+	const otherABI = `[{"type":"function","name":"burn","inputs":[],"outputs":[]}]`
+
+	for _, a := range []string{otherABI, testABI} {
+		if err := b.AddContractABI("0xC0", a); err != nil {
+			t.Fatal("couldn't upload abi", err)
+		}
+	}
+
+	if a, err := b.ContractABI("0xc0"); err != nil || a.ABI != testABI || a.Verified {
+		t.Error("upload didn't replace the previous one", a, err)
+	}
+
+	if err := b.AddVerifiedContract(&models.VerifiedContract{Address: "0xc0", ABI: otherABI}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.AddContractABI("0xc0", testABI); err != ErrVerifiedABI {
+		t.Error("upload over a verified abi returned", err)
+	}
+
+	if a, err := b.ContractABI("0xc0"); err != nil || a.ABI != otherABI || !a.Verified {
+		t.Error("verified abi was replaced", a, err)
+	}
+}
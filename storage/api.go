@@ -2,423 +2,373 @@ package storage
 
 import (
 	"context"
+	"math/big"
+	"reflect"
 
 	"github.com/octanolabs/go-spectrum/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// These methods are used exclusively by the api, and since they return a subset of elements in a given collection we also include the totals for those collections
+// These methods are used exclusively by the api, and since they return a page of elements in a given collection we also include the totals for those collections
 
-//Blocks
-
-func (m *MongoDB) LatestBlocks(limit int64) (map[string]interface{}, error) {
-	var (
-		blocks = make([]models.Block, 0)
-		result = map[string]interface{}{}
-	)
-
-	c, err := m.C(models.BLOCKS).Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{"number", -1}}).SetLimit(limit))
+var (
+	blockOrder       = order{number: "number"}
+	transactionOrder = order{number: "blockNumber", index: "transactionIndex"}
+	transferOrder    = order{number: "blockNumber", index: "logIndex", key: "_id"} // legacy transfers have no log index
+	nftOrder         = order{number: "blockNumber", index: "logIndex", key: "_id"} // erc1155 batches share a log
+	creationOrder    = order{number: "blockNumber", key: "address"}
+	balanceOrder     = order{value: "balance", key: "address", largest: true}
+)
 
-	if err != nil {
-		return result, err
-	}
+//Blocks
 
-	err = c.All(context.Background(), &blocks)
+func (m *MongoDB) LatestBlocks(page *Page) (map[string]interface{}, error) {
+	return m.page(page, blockOrder, models.BLOCKS, bson.M{}, "blocks", &[]models.Block{}, func() (int64, error) {
+		latest, err := m.LatestBlock()
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
 
-	result["blocks"] = blocks
-	result["total"] = blocks[0].Number + 1
+		return int64(latest.Number) + 1, err
+	})
+}
 
-	return result, err
+func (m *MongoDB) LatestMinedBlocks(account string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, blockOrder, models.BLOCKS, bson.M{"miner": account}, "blocks", &[]models.Block{}, func() (int64, error) {
+		return m.C(models.BLOCKS).CountDocuments(context.Background(), bson.M{"miner": account}, options.Count())
+	})
 }
 
-func (m *MongoDB) LatestMinedBlocks(account string, limit int64) (map[string]interface{}, error) {
-	var (
-		mined  = make([]models.Block, 0)
-		result = map[string]interface{}{}
-	)
+//Uncles
 
-	c, err := m.C(models.BLOCKS).Find(context.Background(), bson.M{"miner": account}, options.Find().SetSort(bson.D{{"number", -1}}).SetLimit(limit))
+func (m *MongoDB) LatestUncles(page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{number: "blockNumber", index: "position"}, models.UNCLES, bson.M{}, "uncles", &[]models.Uncle{}, func() (int64, error) {
+		status, err := m.Status()
+		return status.TotalUncles, err
+	})
+}
 
-	if err != nil {
-		return result, err
-	}
+//Forked Blocks
 
-	err = c.All(context.Background(), &mined)
+func (m *MongoDB) LatestForkedBlocks(page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{number: "number", key: "hash"}, models.FORKEDBLOCKS, bson.M{}, "forkedBlocks", &[]models.Block{}, func() (int64, error) {
+		status, err := m.Status()
+		return status.TotalForkedBlocks, err
+	})
+}
 
-	count, err := m.C(models.BLOCKS).CountDocuments(context.Background(), bson.M{"miner": account}, options.Count())
+//Reorgs
 
-	if err != nil {
-		return map[string]interface{}{}, err
-	}
+func (m *MongoDB) LatestReorgs(page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{number: "number", key: "_id"}, models.REORGS, bson.M{}, "reorgs", &[]models.Reorg{}, m.TotalReorgCount)
+}
 
-	result["blocks"] = mined
-	result["total"] = count
+//Transactions
 
-	return result, err
+func (m *MongoDB) LatestTransactions(page *Page) (map[string]interface{}, error) {
+	return m.page(page, transactionOrder, models.TRANSACTIONS, bson.M{}, "txns", &[]models.Transaction{}, func() (int64, error) {
+		status, err := m.Status()
+		return status.TotalTransactions, err
+	})
 }
 
-//Uncles
-
-func (m *MongoDB) LatestUncles(limit int64) (map[string]interface{}, error) {
-	var (
-		uncles = make([]models.Uncle, 0)
-		result = map[string]interface{}{}
-	)
+func (m *MongoDB) LatestFailedTransactions(page *Page) (map[string]interface{}, error) {
+	filter := bson.M{"$and": []bson.M{{"blockNumber": bson.M{"$gte": 1075090}}, {"status": false}}}
 
-	status, err := m.Status()
+	return m.page(page, transactionOrder, models.TRANSACTIONS, filter, "txns", &[]models.Transaction{}, func() (int64, error) {
+		return m.C(models.TRANSACTIONS).CountDocuments(context.Background(), filter, options.Count())
+	})
+}
 
-	if err != nil {
-		return result, err
-	}
+//Contracts
 
-	c, err := m.C(models.UNCLES).Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(limit))
+func (m *MongoDB) LatestContractCalls(page *Page) (map[string]interface{}, error) {
+	return m.page(page, transactionOrder, models.CONTRACTCALLS, bson.M{}, "txns", &[]models.Transaction{}, func() (int64, error) {
+		status, err := m.Status()
+		return status.TotalContractCalls, err
+	})
+}
 
-	if err != nil {
-		return result, err
-	}
+func (m *MongoDB) LatestContractsDeployed(page *Page) (map[string]interface{}, error) {
+	return m.page(page, transactionOrder, models.CONTRACTS, bson.M{}, "txns", &[]models.Transaction{}, func() (int64, error) {
+		status, err := m.Status()
+		return status.TotalContractsDeployed, err
+	})
+}
 
-	err = c.All(context.Background(), &uncles)
+//Tokens
 
-	result["uncles"] = uncles
-	result["total"] = status.TotalUncles
+func (m *MongoDB) LatestTokenTransfers(page *Page) (map[string]interface{}, error) {
+	return m.page(page, transferOrder, models.TRANSFERS, bson.M{}, "transfers", &[]models.TokenTransfer{}, func() (int64, error) {
+		status, err := m.Status()
+		return status.TotalTokenTransfers, err
+	})
+}
 
-	return result, err
+func (m *MongoDB) LatestTransfersOfToken(hash string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, transferOrder, models.TRANSFERS, bson.M{"contract": hash}, "transfers", &[]models.TokenTransfer{}, func() (int64, error) {
+		return m.ContractTransferCount(hash)
+	})
 }
 
-//Forked Blocks
+//NFTs
 
-func (m *MongoDB) LatestForkedBlocks(limit int64) (map[string]interface{}, error) {
-	var (
-		forkedBlocks = make([]models.Block, 0)
-		result       = map[string]interface{}{}
-	)
-
-	status, err := m.Status()
-	if err != nil {
-		return result, err
-	}
+func (m *MongoDB) LatestNFTTransfersByContract(contract string, page *Page) (map[string]interface{}, error) {
+	return m.latestNFTTransfers(bson.M{"contract": contract}, page)
+}
 
-	c, err := m.C(models.FORKEDBLOCKS).Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{"number", -1}}).SetLimit(limit))
+func (m *MongoDB) LatestNFTTransfersByToken(contract string, tokenId string, page *Page) (map[string]interface{}, error) {
+	return m.latestNFTTransfers(bson.M{"contract": contract, "tokenId": tokenId}, page)
+}
 
-	if err != nil {
-		return result, err
-	}
+func (m *MongoDB) LatestNFTTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.latestNFTTransfers(bson.M{"$or": []bson.M{{"from": account}, {"to": account}}}, page)
+}
 
-	err = c.All(context.Background(), &forkedBlocks)
+func (m *MongoDB) latestNFTTransfers(filter bson.M, page *Page) (map[string]interface{}, error) {
+	return m.page(page, nftOrder, models.NFTTRANSFERS, filter, "transfers", &[]models.NFTTransfer{}, func() (int64, error) {
+		return m.C(models.NFTTRANSFERS).CountDocuments(context.Background(), filter, options.Count())
+	})
+}
 
-	result["forkedBlocks"] = forkedBlocks
-	result["total"] = status.TotalForkedBlocks
+//Tokens
 
-	return result, err
+func (m *MongoDB) ListTokens(page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{value: "symbol", key: "address"}, models.TOKENS, bson.M{"isToken": true}, "tokens", &[]models.Token{}, m.TotalTokenCount)
 }
 
-//Transactions
+// TokenHolders returns the largest holders of a token, with their share of its total supply
 
-func (m *MongoDB) LatestTransactions(limit int64) (map[string]interface{}, error) {
+func (m *MongoDB) TokenHolders(contract string, page *Page) (map[string]interface{}, error) {
 	var (
-		txns   = make([]models.Transaction, 0)
-		result = map[string]interface{}{}
+		holders = make([]models.TokenHolder, 0)
+		result  = map[string]interface{}{}
 	)
 
-	status, err := m.Status()
+	p, err := newPager(page, balanceOrder, DefaultPageSize)
 	if err != nil {
 		return result, err
 	}
 
-	c, err := m.C(models.TRANSACTIONS).Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(limit))
-
-	if err != nil {
+	// balances are decimal strings, the pager sorts them by value
+	if err := m.findPage(p, models.TOKENBALANCES, bson.M{"contract": contract}, &holders, nil); err != nil {
 		return result, err
 	}
 
-	err = c.All(context.Background(), &txns)
-
-	result["txns"] = txns
-	result["total"] = status.TotalTransactions
-
-	return result, err
-}
-
-func (m *MongoDB) LatestFailedTransactions(limit int64) (map[string]interface{}, error) {
-	var (
-		txns   = make([]models.Transaction, 0)
-		result = map[string]interface{}{}
-	)
-
-	filter := bson.M{"$and": []bson.M{{"blockNumber": bson.M{"$gte": 1075090}}, {"status": false}}}
-
-	c, err := m.C(models.TRANSACTIONS).Find(context.Background(), filter, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(limit))
-
-	if err != nil {
-		return result, err
+	// percentages are left at 0 if the token metadata isn't known yet
+	if token, err := m.TokenInfo(contract); err == nil {
+		setHolderPercentages(holders, token.TotalSupply)
 	}
 
-	err = c.All(context.Background(), &txns)
+	count, err := p.total(func() (int64, error) {
+		return m.C(models.TOKENBALANCES).CountDocuments(context.Background(), bson.M{"contract": contract}, options.Count())
+	})
 
-	result["txns"] = txns
-
-	count, err := m.C(models.TRANSACTIONS).CountDocuments(context.Background(), filter, options.Count())
 	if err != nil {
-		return map[string]interface{}{}, err
+		return result, err
 	}
 
-	result["total"] = count
+	p.setResult(result, "holders", holders, count)
 
 	return result, err
 }
 
-//Contracts
-
-func (m *MongoDB) LatestContractCalls(limit int64) (map[string]interface{}, error) {
-	var (
-		txns   = make([]models.Transaction, 0)
-		result = map[string]interface{}{}
-	)
+// setHolderPercentages sets the share of totalSupply held by each of holders
 
-	//can't create compund index for this, input field may be too long
+func setHolderPercentages(holders []models.TokenHolder, totalSupply string) {
+	supply, ok := new(big.Float).SetString(totalSupply)
 
-	pipeline := []bson.M{
-		{"$sort": bson.M{"blockNumber": -1}},
-		{"$limit": limit},
+	if !ok || supply.Sign() <= 0 {
+		return
 	}
 
-	c, err := m.C(models.CONTRACTCALLS).Aggregate(context.Background(), pipeline, options.Aggregate())
+	for i := range holders {
+		balance, ok := new(big.Float).SetString(holders[i].Balance)
+		if !ok {
+			continue
+		}
 
-	if err != nil {
-		return result, err
+		holders[i].Percentage, _ = new(big.Float).Quo(new(big.Float).Mul(balance, big.NewFloat(100)), supply).Float64()
 	}
-
-	err = c.All(context.Background(), &txns)
-
-	result["txns"] = txns
-
-	store, err := m.Status()
-	if err != nil {
-		return map[string]interface{}{}, err
-	}
-
-	result["total"] = store.TotalContractCalls
-
-	return result, err
 }
 
-func (m *MongoDB) LatestContractsDeployed(limit int64) (map[string]interface{}, error) {
-	var (
-		txns   = make([]models.Transaction, 0)
-		result = map[string]interface{}{}
-	)
+// TokenBalancesByAccount returns the token balances held by account, by contract
 
-	pipeline := []bson.M{
-		{"$sort": bson.M{"blockNumber": -1}},
-		{"$limit": limit},
-	}
+func (m *MongoDB) TokenBalancesByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{key: "contract"}, models.TOKENBALANCES, bson.M{"address": account}, "balances", &[]models.TokenBalance{}, func() (int64, error) {
+		return m.C(models.TOKENBALANCES).CountDocuments(context.Background(), bson.M{"address": account}, options.Count())
+	})
+}
 
-	c, err := m.C(models.CONTRACTS).Aggregate(context.Background(), pipeline, options.Aggregate())
+//Accounts
 
-	if err != nil {
-		return result, err
-	}
+func (m *MongoDB) LatestTransactionsByAccount(hash string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, transactionOrder, models.TRANSACTIONS, bson.M{"$or": []bson.M{{"from": hash}, {"to": hash}}}, "txns", &[]models.Transaction{}, func() (int64, error) {
+		return m.TxnCount(hash)
+	})
+}
 
-	err = c.All(context.Background(), &txns)
+// LatestITransactionsByAccount returns internal transactions newest first, those of a block in the order they were written
 
-	result["txns"] = txns
+func (m *MongoDB) LatestITransactionsByAccount(hash string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{number: "blockNumber", key: "_id"}, models.ITRANSACTIONS, bson.M{"$or": []bson.M{{"from": hash}, {"to": hash}}}, "itxns", &[]models.ITransaction{}, func() (int64, error) {
+		return m.ITxnCount(hash)
+	})
+}
 
-	store, err := m.Status()
-	if err != nil {
-		return map[string]interface{}{}, err
-	}
+func (m *MongoDB) LatestTokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, transferOrder, models.TRANSFERS, bson.M{"$or": []bson.M{{"from": account}, {"to": account}}}, "transfers", &[]models.TokenTransfer{}, func() (int64, error) {
+		return m.TokenTransfersByAccountCount(account)
+	})
+}
 
-	result["total"] = store.TotalContractsDeployed
+func (m *MongoDB) AccountsByBalance(page *Page) (map[string]interface{}, error) {
+	return m.page(page, balanceOrder, models.ACCOUNTS, bson.M{}, "accounts", &[]models.Account{}, m.TotalAccountCount)
+}
 
-	return result, err
+func (m *MongoDB) AccountsByLastSeen(page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{number: "block", key: "address"}, models.ACCOUNTS, bson.M{}, "accounts", &[]models.Account{}, m.TotalAccountCount)
 }
 
-//Tokens
+// BalanceHistory returns the latest balance changes of account, newest first
 
-func (m *MongoDB) LatestTokenTransfers(limit int64) (map[string]interface{}, error) {
-	var (
-		transfers = make([]models.TokenTransfer, 0)
-		result    = map[string]interface{}{}
-	)
+func (m *MongoDB) BalanceHistory(account string, page *Page) (map[string]interface{}, error) {
+	return m.page(page, order{number: "block"}, models.BALANCES, bson.M{"address": account}, "history", &[]models.BalanceRecord{}, func() (int64, error) {
+		return m.C(models.BALANCES).CountDocuments(context.Background(), bson.M{"address": account}, options.Count())
+	})
+}
 
-	status, err := m.Status()
-	if err != nil {
-		return result, err
-	}
+// page reads a page of the documents of coll matching filter into items, a pointer to a slice, and returns it
+// under name along with the total from count
 
-	c, err := m.C(models.TRANSFERS).Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(limit))
+func (m *MongoDB) page(page *Page, o order, coll string, filter bson.M, name string, items interface{}, count func() (int64, error)) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
 
+	p, err := newPager(page, o, DefaultPageSize)
 	if err != nil {
 		return result, err
 	}
 
-	err = c.All(context.Background(), &transfers)
-
-	result["transfers"] = transfers
-	result["total"] = status.TotalTokenTransfers
-
-	return result, err
-}
-
-func (m *MongoDB) LatestTransfersOfToken(hash string) (map[string]interface{}, error) {
-	var (
-		transfers = make([]models.TokenTransfer, 0)
-		result    = map[string]interface{}{}
-	)
-
-	c, err := m.C(models.TRANSFERS).Find(context.Background(), bson.M{"contract": hash}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(1000))
-
-	if err != nil {
+	if err := m.findPage(p, coll, filter, items, nil); err != nil {
 		return result, err
 	}
 
-	err = c.All(context.Background(), &transfers)
-
-	count, err := m.ContractTransferCount(hash)
-
+	total, err := p.total(count)
 	if err != nil {
 		return result, err
 	}
 
-	result["transfers"] = transfers
-	result["total"] = count
+	p.setResult(result, name, reflect.ValueOf(items).Elem().Interface(), total)
 
-	return result, err
+	return result, nil
 }
 
-//Accounts
+// findPage reads the documents of coll matching filter that make up the page of p into items
 
-func (m *MongoDB) LatestTransactionsByAccount(hash string) (map[string]interface{}, error) {
-	var (
-		txns   = make([]models.Transaction, 0)
-		result = map[string]interface{}{}
-	)
+func (m *MongoDB) findPage(p *pager, coll string, filter bson.M, items interface{}, projection bson.M) error {
+	ctx := context.Background()
 
-	c, err := m.C(models.TRANSACTIONS).Find(context.Background(), bson.M{"$or": []bson.M{{"from": hash}, {"to": hash}}}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(100))
+	if p.after != nil {
+		after, err := p.mongoAfter(p.after)
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		return result, err
+		filter = bson.M{"$and": []bson.M{filter, after}}
 	}
 
-	err = c.All(context.Background(), &txns)
-
-	count, err := m.TxnCount(hash)
+	opts := options.Find().SetSort(p.mongoSort()).SetLimit(p.limit + 1)
 
-	if err != nil {
-		return result, err
+	if projection != nil {
+		opts.SetProjection(projection)
 	}
 
-	result["txns"] = txns
-	result["total"] = count
-
-	return result, err
-}
-
-func (m *MongoDB) LatestITransactionsByAccount(hash string) (map[string]interface{}, error) {
-	var (
-		txns   = make([]models.ITransaction, 0)
-		result = map[string]interface{}{}
-	)
-
-	c, err := m.C(models.ITRANSACTIONS).Find(context.Background(), bson.M{"$or": []bson.M{{"from": hash}, {"to": hash}}}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(100))
-
-	if err != nil {
-		return result, err
+	if p.largest {
+		opts.SetCollation(numericCollation)
 	}
 
-	err = c.All(context.Background(), &txns)
-
-	count, err := m.ITxnCount(hash)
-
+	c, err := m.C(coll).Find(ctx, filter, opts)
 	if err != nil {
-		return result, err
+		return err
 	}
 
-	result["itxns"] = txns
-	result["total"] = count
+	var docs []bson.Raw
+	if err := c.All(ctx, &docs); err != nil {
+		return err
+	}
 
-	return result, err
+	return p.decode(docs, items)
 }
 
-func (m *MongoDB) LatestTokenTransfersByAccount(account string) (map[string]interface{}, error) {
-	var (
-		transfers = make([]models.TokenTransfer, 0)
-		result    = map[string]interface{}{}
-	)
-
-	c, err := m.C(models.TRANSFERS).Find(context.Background(), bson.M{"$or": []bson.M{{"from": account}, {"to": account}}}, options.Find().SetSort(bson.D{{"blockNumber", -1}}).SetLimit(100))
+func (o order) mongoSort() bson.D {
+	var sort bson.D
 
-	if err != nil {
-		return result, err
+	if o.number != "" {
+		sort = append(sort, bson.E{o.number, -1})
 	}
 
-	err = c.All(context.Background(), &transfers)
-
-	count, err := m.TokenTransfersByAccountCount(account)
-
-	if err != nil {
-		return result, err
+	if o.index != "" {
+		sort = append(sort, bson.E{o.index, -1})
 	}
 
-	result["transfers"] = transfers
-	result["total"] = count
-
-	return result, err
-}
-
-func (m *MongoDB) AccountsByBalance(limit int64) (map[string]interface{}, error) {
-	var (
-		accounts = make([]models.Account, 0)
-		result   = map[string]interface{}{}
-	)
-
-	var collation options.Collation
-	collation.Locale = "en_US"
-	collation.NumericOrdering = true
-
-	c, err := m.C(models.ACCOUNTS).Find(context.Background(), bson.M{}, options.Find().SetCollation(&collation).SetSort(bson.D{{"balance", -1}}).SetLimit(limit))
-
-	if err != nil {
-		return result, err
+	if o.value != "" && o.largest {
+		sort = append(sort, bson.E{o.value, -1})
+	} else if o.value != "" {
+		sort = append(sort, bson.E{o.value, 1})
 	}
 
-	err = c.All(context.Background(), &accounts)
-
-	count, err := m.TotalAccountCount()
-
-	if err != nil {
-		return result, err
+	if o.key != "" {
+		sort = append(sort, bson.E{o.key, 1})
 	}
 
-	result["accounts"] = accounts
-	result["total"] = count
-
-	return result, err
+	return sort
 }
 
-func (m *MongoDB) AccountsByLastSeen(limit int64) (map[string]interface{}, error) {
+// mongoAfter matches the documents that sort after c: those past it on the first field, or equal on the first
+// and past it on the second, and so on
+
+func (o order) mongoAfter(c *cursor) (bson.M, error) {
 	var (
-		accounts = make([]models.Account, 0)
-		result   = map[string]interface{}{}
+		after []bson.M
+		equal = bson.M{}
 	)
 
-	c, err := m.C(models.ACCOUNTS).Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{"block", -1}}).SetLimit(limit))
+	past := func(field string, op string, value interface{}) {
+		clause := bson.M{field: bson.M{op: value}}
+		for k, v := range equal {
+			clause[k] = v
+		}
 
-	if err != nil {
-		return result, err
+		after = append(after, clause)
+		equal[field] = value
 	}
 
-	err = c.All(context.Background(), &accounts)
+	if o.number != "" {
+		past(o.number, "$lt", c.Number)
+	}
 
-	count, err := m.TotalAccountCount()
+	if o.index != "" {
+		past(o.index, "$lt", c.Index)
+	}
 
-	if err != nil {
-		return result, err
+	if o.value != "" && o.largest {
+		past(o.value, "$lt", c.Value)
+	} else if o.value != "" {
+		past(o.value, "$gt", c.Value)
 	}
 
-	result["accounts"] = accounts
-	result["total"] = count
+	if o.key == "_id" {
+		id, err := primitive.ObjectIDFromHex(c.Key)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		past(o.key, "$gt", id)
+	} else if o.key != "" {
+		past(o.key, "$gt", c.Key)
+	}
 
-	return result, err
+	return bson.M{"$or": after}, nil
 }
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/rpc"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	BackendMongo    = "mongo"
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// ErrNotFound is returned by single document getters when there's no match, whatever the backend
+var ErrNotFound = mongo.ErrNoDocuments
+
+// Backend is everything the crawlers and the api need from the db. MongoDB and Postgres are the production backends,
+// Memory keeps everything in the process for tests and short-lived dev environments
+
+type Backend interface {
+	//lifecycle
+	Init(rpc *rpc.RPCClient)
+	IsFirstRun() bool
+	Ping() error
+	Migrate() error
+
+	//writes
+	CommitBlock(b *Batch) error
+	NewBulkWriter(size int, interval time.Duration) BatchWriter
+	PurgeBlock(height uint64) error
+	PurgeFrom(height uint64) error
+	Rollback(height uint64, checkpoint string) ([]models.Block, error)
+	Checkpoint(crawler string) (models.Checkpoint, error)
+	SetCheckpoint(crawler string, number uint64, hash string) error
+	AddReorg(r *models.Reorg) error
+	AddToken(t *models.Token) error
+	AddEnodes(e *models.Enode) error
+	AddNumberChart(name string, series []uint64, stamps []string) error
+	AddNumberStringChart(name string, series []string, stamps []string) error
+	AddMultiSeriesChart(name string, series map[string]map[string]uint, stamps []string) error
+	AddContractABI(address string, abiJSON string) error
+	AddVerifiedContract(c *models.VerifiedContract) error
+	ImportSignatures(sigs []models.Signature) (int64, error)
+	UpdateStore() error
+
+	//iterators
+	IterTransactions(from, to int64) (Iterator, error)
+	IterBlocks(from, to int64) (Iterator, error)
+	IterForkedBlocks(from, to int64) (Iterator, error)
+	IterUncles(from, to int64) (Iterator, error)
+	IterTokenTransfers(from, to int64) (Iterator, error)
+
+	//blocks
+	LatestBlock() (models.Block, error)
+	BlockByHash(hash string) (models.Block, error)
+	BlockByNumber(number uint64) (models.Block, error)
+	TransactionsByBlockNumber(number uint64) ([]models.Transaction, error)
+	TotalBlockCount() (int64, error)
+
+	//uncles
+	UncleByHash(hash string) (models.Uncle, error)
+	TotalUncleCount() (int64, error)
+
+	//reorgs
+	ForkedBlockByNumber(number uint64) (models.Block, error)
+	TotalForkedBlockCount() (int64, error)
+	TotalReorgCount() (int64, error)
+
+	//txs
+	TransactionByHash(hash string) (models.Transaction, error)
+	TransactionByContractAddress(address string) (models.Transaction, error)
+	TxnCount(address string) (int64, error)
+	ITxnCount(address string) (int64, error)
+	TotalTxnCount() (int64, error)
+	TxTrace(hash string) (models.ITransaction, error)
+	LatestTxTrace() (models.ITransaction, error)
+
+	//transfers
+	TokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error)
+	TokenTransfersByAccountCount(account string) (int64, error)
+	TransfersOfTokenByAccount(token string, account string, page *Page) (map[string]interface{}, error)
+	TransfersOfTokenByAccountCount(token string, account string) (int64, error)
+	TransfersByContract(address string, page *Page) (map[string]interface{}, error)
+	ContractTransferCount(address string) (int64, error)
+	TotalTransferCount() (int64, error)
+
+	//tokens
+	TokenInfo(address string) (models.Token, error)
+	TotalTokenCount() (int64, error)
+	TokenCandidates(before int64) ([]string, error)
+
+	//contracts
+	TotalContractCallsCount() (int64, error)
+	TotalContractsDeployedCount() (int64, error)
+	ContractCreation(address string) (models.ContractCreation, error)
+	ContractsByCreator(creator string, page *Page) (map[string]interface{}, error)
+	ContractsWithSameCode(address string, page *Page) (map[string]interface{}, error)
+
+	//abis
+	ContractABI(address string) (models.ContractABI, error)
+	DecodeTransaction(hash string) (models.DecodedTransaction, error)
+	LookupSelector(hash string) ([]models.Signature, error)
+	MethodSignature(input string) (string, error)
+	VerifiedContract(address string) (models.VerifiedContract, error)
+
+	//nfts
+	NFTOwner(contract string, tokenId string) (string, error)
+	TotalNFTTransferCount() (int64, error)
+
+	//accounts
+	TotalAccountCount() (int64, error)
+	BalanceAt(account string, block uint64) (models.BalanceRecord, error)
+
+	//charts
+	GetNumberChart(name string, limit int) (models.NumberChart, error)
+	GetNumberStringChart(name string, limit int) (models.NumberStringChart, error)
+	GetMultiSeriesChart(name string, limit int) (models.MultiSeriesChart, error)
+	ListCharts() ([]string, error)
+
+	//api-specific
+	LatestBlocks(page *Page) (map[string]interface{}, error)
+	LatestMinedBlocks(account string, page *Page) (map[string]interface{}, error)
+	LatestUncles(page *Page) (map[string]interface{}, error)
+	LatestForkedBlocks(page *Page) (map[string]interface{}, error)
+	LatestReorgs(page *Page) (map[string]interface{}, error)
+	LatestTransactions(page *Page) (map[string]interface{}, error)
+	LatestFailedTransactions(page *Page) (map[string]interface{}, error)
+	LatestContractCalls(page *Page) (map[string]interface{}, error)
+	LatestContractsDeployed(page *Page) (map[string]interface{}, error)
+	LatestTokenTransfers(page *Page) (map[string]interface{}, error)
+	LatestTransfersOfToken(hash string, page *Page) (map[string]interface{}, error)
+	LatestNFTTransfersByContract(contract string, page *Page) (map[string]interface{}, error)
+	LatestNFTTransfersByToken(contract string, tokenId string, page *Page) (map[string]interface{}, error)
+	LatestNFTTransfersByAccount(account string, page *Page) (map[string]interface{}, error)
+	ListTokens(page *Page) (map[string]interface{}, error)
+	TokenHolders(contract string, page *Page) (map[string]interface{}, error)
+	TokenBalancesByAccount(account string, page *Page) (map[string]interface{}, error)
+	LatestTransactionsByAccount(hash string, page *Page) (map[string]interface{}, error)
+	LatestITransactionsByAccount(hash string, page *Page) (map[string]interface{}, error)
+	LatestTokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error)
+	AccountsByBalance(page *Page) (map[string]interface{}, error)
+	AccountsByLastSeen(page *Page) (map[string]interface{}, error)
+	BalanceHistory(account string, page *Page) (map[string]interface{}, error)
+
+	//misc
+	Status() (models.Store, error)
+}
+
+// Iterator walks the documents returned by the Iter* methods in order, *mongo.Cursor implements it
+
+type Iterator interface {
+	Next(ctx context.Context) bool
+	Decode(val interface{}) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// BatchWriter queues batches and writes them a window at a time, see BulkWriter
+
+type BatchWriter interface {
+	Add(b *Batch) error
+	Flush() error
+	Pending() int
+}
+
+// New returns the backend selected in cfg, MongoDB unless another one is set
+
+func New(cfg *Config) (Backend, error) {
+	switch cfg.Type {
+	case "", BackendMongo:
+		m, err := NewConnection(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	case BackendPostgres:
+		p, err := NewPostgres(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case BackendMemory:
+		return NewMemory(cfg), nil
+	default:
+		return nil, errors.New("unknown storage type " + cfg.Type)
+	}
+}
+
+var (
+	_ Backend = (*MongoDB)(nil)
+	_ Backend = (*Postgres)(nil)
+	_ Backend = (*Memory)(nil)
+)
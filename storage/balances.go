@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lastBalances returns the latest recorded balance below block for each of addresses. Addresses without
+// history are left out
+
+func (m *MongoDB) lastBalances(ctx context.Context, addresses []string, block uint64) (map[string]*big.Int, error) {
+	var (
+		prev = make(map[string]*big.Int)
+		res  []models.BalanceRecord
+	)
+
+	if len(addresses) == 0 {
+		return prev, nil
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"address": bson.M{"$in": addresses}, "block": bson.M{"$lt": block}}},
+		bson.M{"$sort": bson.D{{"address", 1}, {"block", -1}}},
+		bson.M{"$group": bson.M{"_id": "$address", "address": bson.M{"$first": "$address"}, "balance": bson.M{"$first": "$balance"}}},
+	}
+
+	c, err := m.C(models.BALANCES).Aggregate(ctx, pipeline, options.Aggregate())
+	if err != nil {
+		return prev, err
+	}
+
+	if err = c.All(ctx, &res); err != nil {
+		return prev, err
+	}
+
+	for _, r := range res {
+		if balance, ok := new(big.Int).SetString(r.Balance, 10); ok {
+			prev[r.Address] = balance
+		}
+	}
+
+	return prev, nil
+}
+
+// balanceRecords returns the history records for accounts, with the change from the balances in prev,
+// which is then updated so it can be carried over to the next block
+
+func balanceRecords(accounts []*models.Account, prev map[string]*big.Int) []*models.BalanceRecord {
+	records := make([]*models.BalanceRecord, 0, len(accounts))
+
+	for _, a := range accounts {
+		balance, ok := new(big.Int).SetString(a.Balance, 10)
+		if !ok {
+			balance = new(big.Int)
+		}
+
+		delta := new(big.Int).Set(balance)
+		if p, ok := prev[a.Address]; ok {
+			delta.Sub(delta, p)
+		}
+		prev[a.Address] = balance
+
+		records = append(records, &models.BalanceRecord{Address: a.Address, Block: a.Block, Balance: a.Balance, Delta: delta.String(), Nonce: a.Nonce, HasCode: a.HasCode})
+	}
+
+	return records
+}
+
+func accountAddresses(accounts []*models.Account) []string {
+	addresses := make([]string, len(accounts))
+
+	for i, a := range accounts {
+		addresses[i] = a.Address
+	}
+
+	return addresses
+}
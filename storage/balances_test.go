@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+func TestBalanceRecords(t *testing.T) {
+	prev := map[string]*big.Int{"0xa": big.NewInt(100)}
+
+	blocks := [][]*models.Account{
+		{{Address: "0xa", Balance: "90", Block: 1}, {Address: "0xb", Balance: "10", Block: 1}},
+		{{Address: "0xb", Balance: "25", Block: 2}},
+	}
+
+	want := [][]string{{"-10", "10"}, {"15"}}
+
+	for i, accounts := range blocks {
+		records := balanceRecords(accounts, prev)
+
+		if len(records) != len(want[i]) {
+			t.Fatalf("block %d: got %d records, want %d", i+1, len(records), len(want[i]))
+		}
+
+		for j, r := range records {
+			if r.Delta != want[i][j] {
+				t.Errorf("block %d: delta of %v is %v, want %v", i+1, r.Address, r.Delta, want[i][j])
+			}
+		}
+	}
+}
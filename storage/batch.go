@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+const (
+	// returned by servers that don't support transactions (standalone mongod)
+	illegalOperationCode = 20
+)
+
+// Batch holds a block together with every document derived from it, so they can be written at once.
+// If Checkpoint is set, that crawler's checkpoint is moved to Block in the same write
+
+type Batch struct {
+	Block          *models.Block
+	Transactions   []*models.Transaction
+	ITransactions  []*models.ITransaction
+	TokenTransfers []*models.TokenTransfer
+	NFTTransfers   []*models.NFTTransfer
+	Uncles         []*models.Uncle
+	Accounts       []*models.Account
+	TokenBalances  []*models.TokenBalance
+	Contracts      []*models.Transaction
+	Creations      []*models.ContractCreation
+	ContractCalls  []*models.Transaction
+	Checkpoint     string
+
+	traces []*models.TraceChunk
+}
+
+// CommitBlock writes a batch to the db. When transactions are enabled the whole batch is written in a
+// single session transaction, which the driver retries on transient errors; otherwise documents are
+// written one collection at a time and the block (and checkpoint) last
+
+func (m *MongoDB) CommitBlock(b *Batch) error {
+	if err := b.splitTraces(m.traceThreshold); err != nil {
+		return err
+	}
+
+	return m.withTransaction(func(ctx context.Context) error {
+		return m.writeBatch(ctx, b)
+	})
+}
+
+// withTransaction runs fn inside a session transaction when they're enabled, or with a plain context otherwise.
+// fn may be retried, so it shouldn't have side effects outside the db
+
+func (m *MongoDB) withTransaction(fn func(ctx context.Context) error) error {
+
+	if !m.transactions {
+		return fn(context.Background())
+	}
+
+	session, err := m.client.StartSession(options.Session())
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	txnOpts := options.Transaction().SetReadConcern(readconcern.Snapshot()).SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+
+	_, err = session.WithTransaction(context.Background(), func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	}, txnOpts)
+
+	if se, ok := err.(mongo.ServerError); ok && se.HasErrorCode(illegalOperationCode) {
+		log.Warn("mongo doesn't support transactions, falling back to plain writes", "err", err)
+		m.transactions = false
+
+		return fn(context.Background())
+	}
+
+	return err
+}
+
+func (m *MongoDB) writeBatch(ctx context.Context, b *Batch) error {
+
+	if err := insertMany(ctx, m.C(models.TRANSACTIONS), b.Transactions); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.ITRANSACTIONS), b.ITransactions); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.TRANSFERS), b.TokenTransfers); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.NFTTRANSFERS), b.NFTTransfers); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.UNCLES), b.Uncles); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.CONTRACTS), b.Contracts); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.CONTRACTCALLS), b.ContractCalls); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.CREATIONS), b.Creations); err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.TRACES), b.traces); err != nil {
+		return err
+	}
+
+	for _, a := range b.Accounts {
+		if _, err := m.C(models.ACCOUNTS).UpdateOne(ctx, bson.M{"address": a.Address}, bson.D{{"$set", a}}, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+
+	prev, err := m.lastBalances(ctx, accountAddresses(b.Accounts), b.Block.Number)
+	if err != nil {
+		return err
+	}
+
+	if err := insertMany(ctx, m.C(models.BALANCES), balanceRecords(b.Accounts, prev)); err != nil {
+		return err
+	}
+
+	if err := m.writeTokenBalances(ctx, b.TokenBalances); err != nil {
+		return err
+	}
+
+	if _, err := m.C(models.BLOCKS).InsertOne(ctx, b.Block, options.InsertOne()); err != nil {
+		return err
+	}
+
+	if b.Checkpoint != "" {
+		if _, err := m.C(models.STORE).UpdateOne(ctx, bson.M{"crawler": b.Checkpoint}, bson.D{{"$set", &models.Checkpoint{
+			Crawler: b.Checkpoint,
+			Number:  b.Block.Number,
+			Hash:    b.Block.Hash,
+			Updated: time.Now().Unix(),
+		}}}, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertMany takes a slice of pointers to documents, empty slices are skipped as the driver refuses them
+
+func insertMany(ctx context.Context, c *mongo.Collection, docs interface{}) error {
+	v := reflect.ValueOf(docs)
+
+	if v.Len() == 0 {
+		return nil
+	}
+
+	d := make([]interface{}, v.Len())
+	for i := range d {
+		d[i] = v.Index(i).Interface()
+	}
+
+	_, err := c.InsertMany(ctx, d, options.InsertMany())
+	return err
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BulkWriter buffers batches for a window of blocks and writes them with one unordered BulkWrite per collection.
+// It's meant for catching up with the chain, where a round trip per document dominates sync time.
+// Blocks are only visible once flushed, and the checkpoint is moved to the last block of the window after
+// everything else has been written, so a failed flush is cleaned up by purging past the checkpoint
+
+type BulkWriter struct {
+	m        *MongoDB
+	size     int
+	interval time.Duration
+	pending  []*Batch
+	first    time.Time
+}
+
+// NewBulkWriter returns a writer that flushes every size blocks, or once the oldest pending block has waited for interval
+
+func (m *MongoDB) NewBulkWriter(size int, interval time.Duration) BatchWriter {
+	if size < 1 {
+		size = 1
+	}
+
+	return &BulkWriter{m: m, size: size, interval: interval}
+}
+
+func (w *BulkWriter) Add(b *Batch) error {
+
+	if err := b.splitTraces(w.m.traceThreshold); err != nil {
+		return err
+	}
+
+	if len(w.pending) == 0 {
+		w.first = time.Now()
+	}
+
+	w.pending = append(w.pending, b)
+
+	if len(w.pending) >= w.size || (w.interval > 0 && time.Since(w.first) >= w.interval) {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+func (w *BulkWriter) Pending() int {
+	return len(w.pending)
+}
+
+// Flush writes every pending batch. Pending batches are dropped even if the flush fails, callers are
+// expected to purge what was written past the checkpoint and sync those blocks again
+
+func (w *BulkWriter) Flush() error {
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	defer func() {
+		w.pending = nil
+	}()
+
+	var (
+		start                                            = time.Now()
+		ctx                                              = context.Background()
+		txns, itxns, transfers, uncles, contracts, calls []mongo.WriteModel
+		blocks, balances, traces, nfts, tokenHistory     []mongo.WriteModel
+		creations                                        []mongo.WriteModel
+		accounts                                         = make(map[string]*models.Account)
+		tokenBalances                                    = make(map[[2]string]*models.TokenBalance)
+		checkpoint                                       string
+		last                                             *models.Block
+	)
+
+	// balance deltas of the first block are taken from the db, then carried over from block to block
+	var addresses []string
+	for _, b := range w.pending {
+		addresses = append(addresses, accountAddresses(b.Accounts)...)
+	}
+
+	prev, err := w.m.lastBalances(ctx, addresses, w.pending[0].Block.Number)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range w.pending {
+		txns = append(txns, insertModels(b.Transactions)...)
+		itxns = append(itxns, insertModels(b.ITransactions)...)
+		transfers = append(transfers, insertModels(b.TokenTransfers)...)
+		nfts = append(nfts, insertModels(b.NFTTransfers)...)
+		uncles = append(uncles, insertModels(b.Uncles)...)
+		contracts = append(contracts, insertModels(b.Contracts)...)
+		calls = append(calls, insertModels(b.ContractCalls)...)
+		creations = append(creations, insertModels(b.Creations)...)
+		blocks = append(blocks, mongo.NewInsertOneModel().SetDocument(b.Block))
+		balances = append(balances, insertModels(balanceRecords(b.Accounts, prev))...)
+		traces = append(traces, insertModels(b.traces)...)
+
+		tokenHistory = append(tokenHistory, insertModels(b.TokenBalances)...)
+
+		// writes are unordered, so only the latest balance of each account and token holder is kept
+		for _, a := range b.Accounts {
+			accounts[a.Address] = a
+		}
+
+		for _, tb := range b.TokenBalances {
+			tokenBalances[[2]string{tb.Contract, tb.Address}] = tb
+		}
+
+		if b.Checkpoint != "" {
+			checkpoint = b.Checkpoint
+		}
+		last = b.Block
+	}
+
+	accountModels := make([]mongo.WriteModel, 0, len(accounts))
+	for _, a := range accounts {
+		accountModels = append(accountModels, mongo.NewUpdateOneModel().SetFilter(bson.M{"address": a.Address}).SetUpdate(bson.D{{"$set", a}}).SetUpsert(true))
+	}
+
+	tokenBalanceModels := make([]mongo.WriteModel, 0, len(tokenBalances))
+	for _, tb := range tokenBalances {
+		tokenBalanceModels = append(tokenBalanceModels, tokenBalanceModel(tb))
+	}
+
+	writes := []struct {
+		coll   string
+		models []mongo.WriteModel
+	}{
+		{models.TRANSACTIONS, txns},
+		{models.ITRANSACTIONS, itxns},
+		{models.TRANSFERS, transfers},
+		{models.NFTTRANSFERS, nfts},
+		{models.UNCLES, uncles},
+		{models.CONTRACTS, contracts},
+		{models.CONTRACTCALLS, calls},
+		{models.CREATIONS, creations},
+		{models.TRACES, traces},
+		{models.ACCOUNTS, accountModels},
+		{models.BALANCES, balances},
+		{models.TOKENBALANCES, tokenBalanceModels},
+		{models.TOKENHISTORY, tokenHistory},
+		{models.BLOCKS, blocks},
+	}
+
+	for _, wr := range writes {
+		if len(wr.models) == 0 {
+			continue
+		}
+
+		if _, err := w.m.C(wr.coll).BulkWrite(ctx, wr.models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	if checkpoint != "" {
+		if err := w.m.SetCheckpoint(checkpoint, last.Number, last.Hash); err != nil {
+			return err
+		}
+	}
+
+	log.Debug("flushed bulk writes", "blocks", len(w.pending), "head", last.Number, "took", time.Since(start))
+
+	return nil
+}
+
+// windowWriter queues batches and hands them to write a window at a time, with the same size and interval
+// rules as BulkWriter. It's used by the backends that can write a window in a single transaction
+
+type windowWriter struct {
+	write    func(pending []*Batch) error
+	size     int
+	interval time.Duration
+	pending  []*Batch
+	first    time.Time
+}
+
+func newWindowWriter(size int, interval time.Duration, write func(pending []*Batch) error) *windowWriter {
+	if size < 1 {
+		size = 1
+	}
+
+	return &windowWriter{write: write, size: size, interval: interval}
+}
+
+func (w *windowWriter) Add(b *Batch) error {
+
+	if len(w.pending) == 0 {
+		w.first = time.Now()
+	}
+
+	w.pending = append(w.pending, b)
+
+	if len(w.pending) >= w.size || (w.interval > 0 && time.Since(w.first) >= w.interval) {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+func (w *windowWriter) Pending() int {
+	return len(w.pending)
+}
+
+// Flush writes every pending batch in order. Pending batches are dropped even if the flush fails, as with BulkWriter
+
+func (w *windowWriter) Flush() error {
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	defer func() {
+		w.pending = nil
+	}()
+
+	return w.write(w.pending)
+}
+
+// insertModels takes a slice of pointers to documents and returns an insert for each of them
+
+func insertModels(docs interface{}) []mongo.WriteModel {
+	v := reflect.ValueOf(docs)
+
+	m := make([]mongo.WriteModel, v.Len())
+	for i := range m {
+		m[i] = mongo.NewInsertOneModel().SetDocument(v.Index(i).Interface())
+	}
+
+	return m
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Checkpoints live in the sysstores collection next to the Store document, they're told apart by the "crawler" field
+
+func (m *MongoDB) Checkpoint(crawler string) (models.Checkpoint, error) {
+	var cp models.Checkpoint
+
+	err := m.C(models.STORE).FindOne(context.Background(), bson.M{"crawler": crawler}, options.FindOne()).Decode(&cp)
+
+	return cp, err
+}
+
+func (m *MongoDB) SetCheckpoint(crawler string, number uint64, hash string) error {
+	collection := m.C(models.STORE)
+
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"crawler": crawler}, bson.D{{"$set", &models.Checkpoint{
+		Crawler: crawler,
+		Number:  number,
+		Hash:    hash,
+		Updated: time.Now().Unix(),
+	}}}, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -31,15 +32,22 @@ func (m *MongoDB) BlockByNumber(number uint64) (models.Block, error) {
 	var block models.Block
 
 	err := m.C(models.BLOCKS).FindOne(context.Background(), bson.M{"number": number}, options.FindOne()).Decode(&block)
+	if err != nil {
+		return block, err
+	}
 
-	return block, err
+	return block, m.restoreBlockTraces(&block)
 }
 
 func (m *MongoDB) BlockByHash(hash string) (models.Block, error) {
 	var block models.Block
 
 	err := m.C(models.BLOCKS).FindOne(context.Background(), bson.M{"hash": hash}, options.FindOne()).Decode(&block)
-	return block, err
+	if err != nil {
+		return block, err
+	}
+
+	return block, m.restoreBlockTraces(&block)
 }
 
 func (m *MongoDB) LatestBlock() (models.Block, error) {
@@ -85,12 +93,33 @@ func (m *MongoDB) TotalForkedBlockCount() (int64, error) {
 	return count, err
 }
 
+// Reorgs
+
+func (m *MongoDB) TotalReorgCount() (int64, error) {
+	count, err := m.C(models.REORGS).CountDocuments(context.Background(), bson.M{}, options.Count())
+
+	return count, err
+}
+
 // Transactions
 func (m *MongoDB) TransactionByHash(hash string) (models.Transaction, error) {
 	var txn models.Transaction
 
 	err := m.C(models.TRANSACTIONS).FindOne(context.Background(), bson.M{"hash": hash}, options.FindOne()).Decode(&txn)
-	return txn, err
+	if err != nil {
+		return txn, err
+	}
+
+	err = m.restoreTrace(&txn)
+	if err != nil {
+		return txn, err
+	}
+
+	if err := decodeTransaction(&txn, m.contractABIs); err != nil {
+		log.Warn("couldn't decode transaction", "hash", hash, "err", err)
+	}
+
+	return txn, nil
 }
 
 func (m *MongoDB) TransactionsByBlockNumber(number uint64) ([]models.Transaction, error) {
@@ -103,6 +132,15 @@ func (m *MongoDB) TransactionsByBlockNumber(number uint64) ([]models.Transaction
 	}
 
 	err = c.All(context.Background(), &txns)
+	if err != nil {
+		return txns, err
+	}
+
+	for i := range txns {
+		if err = m.restoreTrace(&txns[i]); err != nil {
+			return txns, err
+		}
+	}
 
 	return txns, err
 }
@@ -111,7 +149,11 @@ func (m *MongoDB) TransactionByContractAddress(address string) (models.Transacti
 	var txn models.Transaction
 
 	err := m.C(models.TRANSACTIONS).FindOne(context.Background(), bson.M{"contractAddress": address}, options.FindOne()).Decode(&txn)
-	return txn, err
+	if err != nil {
+		return txn, err
+	}
+
+	return txn, m.restoreTrace(&txn)
 }
 
 func (m *MongoDB) TxnCount(address string) (int64, error) {
@@ -131,17 +173,21 @@ func (m *MongoDB) TotalTxnCount() (int64, error) {
 
 // Tx trace
 
+// TxTrace returns the call trace of a transaction, reassembling it if it was too large to be stored with the transaction
+
 func (m *MongoDB) TxTrace(hash string) (models.ITransaction, error) {
-	var itxn models.ITransaction
+	var txn models.Transaction
 
-	c := m.C(models.ITRANSACTIONS).FindOne(context.Background(), bson.M{"parentHash": hash}, options.FindOne())
+	c := m.C(models.TRANSACTIONS).FindOne(context.Background(), bson.M{"hash": hash}, options.FindOne().SetProjection(bson.M{"hash": 1, "trace": 1, "traceChunks": 1}))
 
-	err := c.Decode(&itxn)
+	err := c.Decode(&txn)
 	if err != nil {
 		return models.ITransaction{}, err
 	}
 
-	return itxn, err
+	err = m.restoreTrace(&txn)
+
+	return txn.Trace, err
 }
 
 func (m *MongoDB) LatestTxTrace() (models.ITransaction, error) {
@@ -165,18 +211,12 @@ func (m *MongoDB) TotalContractsDeployedCount() (int64, error) {
 
 // Token transfers
 
-func (m *MongoDB) TransfersOfTokenByAccount(token string, account string) ([]models.TokenTransfer, error) {
-	var transfers = make([]models.TokenTransfer, 0)
-
-	c, err := m.C(models.TRANSFERS).Find(context.Background(), bson.M{"$or": []bson.M{{"$and": []bson.M{{"from": account}, {"contract": token}}}, {"$and": []bson.M{{"to": account}, {"contract": token}}}}}, options.Find().SetSort(bson.D{{"blockNumber", -1}}))
-
-	if err != nil {
-		return transfers, err
-	}
-
-	err = c.All(context.Background(), &transfers)
+func (m *MongoDB) TransfersOfTokenByAccount(token string, account string, page *Page) (map[string]interface{}, error) {
+	filter := bson.M{"$or": []bson.M{{"$and": []bson.M{{"from": account}, {"contract": token}}}, {"$and": []bson.M{{"to": account}, {"contract": token}}}}}
 
-	return transfers, err
+	return m.page(page, transferOrder, models.TRANSFERS, filter, "transfers", &[]models.TokenTransfer{}, func() (int64, error) {
+		return m.TransfersOfTokenByAccountCount(token, account)
+	})
 }
 
 func (m *MongoDB) TransfersOfTokenByAccountCount(token string, account string) (int64, error) {
@@ -184,49 +224,106 @@ func (m *MongoDB) TransfersOfTokenByAccountCount(token string, account string) (
 	return count, err
 }
 
-func (m *MongoDB) TokenTransfersByAccount(account string) ([]models.TokenTransfer, error) {
-	var transfers = make([]models.TokenTransfer, 0)
-
-	c, err := m.C(models.TRANSFERS).Find(context.Background(), bson.M{"$or": []bson.M{{"from": account}, {"to": account}}}, options.Find().SetSort(bson.D{{"blockNumber", -1}}))
+func (m *MongoDB) TokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.LatestTokenTransfersByAccount(account, page)
+}
 
-	if err != nil {
-		return transfers, err
-	}
+func (m *MongoDB) TokenTransfersByAccountCount(account string) (int64, error) {
+	count, err := m.C(models.TRANSFERS).CountDocuments(context.Background(), bson.M{"$or": []bson.M{{"from": account}, {"to": account}}}, options.Count())
+	return count, err
+}
 
-	err = c.All(context.Background(), &transfers)
+func (m *MongoDB) TransfersByContract(address string, page *Page) (map[string]interface{}, error) {
+	return m.LatestTransfersOfToken(address, page)
+}
 
-	return transfers, err
+func (m *MongoDB) ContractTransferCount(address string) (int64, error) {
+	count, err := m.C(models.TRANSFERS).CountDocuments(context.Background(), bson.M{"contract": address}, options.Count())
+	return count, err
 }
 
-func (m *MongoDB) TokenTransfersByAccountCount(account string) (int64, error) {
-	count, err := m.C(models.TRANSFERS).CountDocuments(context.Background(), bson.M{"$or": []bson.M{{"from": account}, {"to": account}}}, options.Count())
+func (m *MongoDB) TotalTransferCount() (int64, error) {
+	count, err := m.C(models.TRANSFERS).CountDocuments(context.Background(), bson.M{}, options.Count())
 	return count, err
 }
 
-func (m *MongoDB) TransfersByContract(address string) ([]models.TokenTransfer, error) {
-	var transfers = make([]models.TokenTransfer, 0)
+// NFT transfers
 
-	c, err := m.C(models.TRANSFERS).Find(context.Background(), bson.M{"contract": address}, options.Find().SetSort(bson.D{{"blockNumber", -1}}))
+// NFTOwner returns the current owner of an ERC-721 token, the recipient of its latest transfer
 
-	if err != nil {
-		return transfers, err
-	}
+func (m *MongoDB) NFTOwner(contract string, tokenId string) (string, error) {
+	var transfer models.NFTTransfer
 
-	err = c.All(context.Background(), &transfers)
+	filter := bson.M{"contract": contract, "tokenId": tokenId, "standard": models.ERC721}
 
-	return transfers, err
+	err := m.C(models.NFTTRANSFERS).FindOne(context.Background(), filter, options.FindOne().SetSort(bson.D{{"blockNumber", -1}, {"logIndex", -1}})).Decode(&transfer)
+	return transfer.To, err
 }
 
-func (m *MongoDB) ContractTransferCount(address string) (int64, error) {
-	count, err := m.C(models.TRANSFERS).CountDocuments(context.Background(), bson.M{"contract": address}, options.Count())
+func (m *MongoDB) TotalNFTTransferCount() (int64, error) {
+	count, err := m.C(models.NFTTRANSFERS).CountDocuments(context.Background(), bson.M{}, options.Count())
 	return count, err
 }
 
-func (m *MongoDB) TotalTransferCount() (int64, error) {
-	count, err := m.C(models.TRANSFERS).CountDocuments(context.Background(), bson.M{}, options.Count())
+// Tokens
+
+func (m *MongoDB) TokenInfo(address string) (models.Token, error) {
+	var token models.Token
+
+	err := m.C(models.TOKENS).FindOne(context.Background(), bson.M{"address": address, "isToken": true}, options.FindOne()).Decode(&token)
+	return token, err
+}
+
+func (m *MongoDB) TotalTokenCount() (int64, error) {
+	count, err := m.C(models.TOKENS).CountDocuments(context.Background(), bson.M{"isToken": true}, options.Count())
 	return count, err
 }
 
+// TokenCandidates returns the addresses of contracts that received token transfers or were deployed, and either
+// were never checked for token metadata or were last checked before the given timestamp
+
+func (m *MongoDB) TokenCandidates(before int64) ([]string, error) {
+	var (
+		candidates = make([]string, 0)
+		known      = make(map[string]bool)
+	)
+
+	c, err := m.C(models.TOKENS).Find(context.Background(), bson.M{"updated": bson.M{"$gte": before}}, options.Find().SetProjection(bson.M{"address": 1}))
+	if err != nil {
+		return candidates, err
+	}
+
+	var fresh []models.Token
+
+	err = c.All(context.Background(), &fresh)
+	if err != nil {
+		return candidates, err
+	}
+
+	for _, t := range fresh {
+		known[t.Address] = true
+	}
+
+	for _, src := range []struct{ coll, field string }{{models.TRANSFERS, "contract"}, {models.CONTRACTS, "contractAddress"}} {
+		addresses, err := m.C(src.coll).Distinct(context.Background(), src.field, bson.M{}, options.Distinct())
+		if err != nil {
+			return candidates, err
+		}
+
+		for _, v := range addresses {
+			address, ok := v.(string)
+			if !ok || address == "" || known[address] {
+				continue
+			}
+
+			known[address] = true
+			candidates = append(candidates, address)
+		}
+	}
+
+	return candidates, nil
+}
+
 // Charts
 // TODO: use multikey indexes to return part of the data
 
@@ -238,11 +335,7 @@ func (m *MongoDB) GetNumberChart(name string, limit int) (models.NumberChart, er
 		return models.NumberChart{}, err
 	}
 
-	if limit > 0 {
-		lastIdx := len(chart.Series) - 1
-		chart.Series = chart.Series[lastIdx-limit:]
-		chart.Timestamps = chart.Timestamps[lastIdx-limit:]
-	}
+	limitNumberChart(&chart, limit)
 
 	return chart, err
 }
@@ -255,11 +348,7 @@ func (m *MongoDB) GetNumberStringChart(name string, limit int) (models.NumberStr
 		return models.NumberStringChart{}, err
 	}
 
-	if limit > 0 {
-		lastIdx := len(chart.Series) - 1
-		chart.Series = chart.Series[lastIdx-limit:]
-		chart.Timestamps = chart.Timestamps[lastIdx-limit:]
-	}
+	limitNumberStringChart(&chart, limit)
 
 	return chart, err
 }
@@ -272,14 +361,37 @@ func (m *MongoDB) GetMultiSeriesChart(name string, limit int) (models.MultiSerie
 		return models.MultiSeriesChart{}, err
 	}
 
+	limitMultiSeriesChart(&chart, limit)
+
+	return chart, err
+}
+
+// limitNumberChart keeps the latest points of a chart, limitNumberStringChart and limitMultiSeriesChart do the same
+// for the other chart types
+
+func limitNumberChart(chart *models.NumberChart, limit int) {
+	if limit > 0 {
+		lastIdx := len(chart.Series) - 1
+		chart.Series = chart.Series[lastIdx-limit:]
+		chart.Timestamps = chart.Timestamps[lastIdx-limit:]
+	}
+}
+
+func limitNumberStringChart(chart *models.NumberStringChart, limit int) {
+	if limit > 0 {
+		lastIdx := len(chart.Series) - 1
+		chart.Series = chart.Series[lastIdx-limit:]
+		chart.Timestamps = chart.Timestamps[lastIdx-limit:]
+	}
+}
+
+func limitMultiSeriesChart(chart *models.MultiSeriesChart, limit int) {
 	if limit > 0 {
 		for _, series := range chart.Datasets {
 			series.SliceTime(limit)
 		}
 		chart.Timestamps = chart.Timestamps[len(chart.Timestamps)-limit : len(chart.Timestamps)-1]
 	}
-
-	return chart, err
 }
 
 func (m *MongoDB) ListCharts() ([]string, error) {
@@ -305,9 +417,77 @@ func (m *MongoDB) ListCharts() ([]string, error) {
 	return result, err
 }
 
+// Contracts
+
+// ContractCreation returns the latest creation of the contract at address, as CREATE2 addresses can be reused
+
+func (m *MongoDB) ContractCreation(address string) (models.ContractCreation, error) {
+	var creation models.ContractCreation
+
+	err := m.C(models.CREATIONS).FindOne(context.Background(), bson.M{"address": address}, options.FindOne().SetSort(bson.D{{"blockNumber", -1}})).Decode(&creation)
+	return creation, err
+}
+
+// ContractsByCreator returns the contracts created by an account or another contract, newest first and without their code
+
+func (m *MongoDB) ContractsByCreator(creator string, page *Page) (map[string]interface{}, error) {
+	return m.creations(bson.M{"creator": creator}, page)
+}
+
+// ContractsWithSameCode returns the other contracts whose runtime code is identical to the code at address,
+// newest first and without their code
+
+func (m *MongoDB) ContractsWithSameCode(address string, page *Page) (map[string]interface{}, error) {
+	creation, err := m.ContractCreation(address)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	return m.creations(bson.M{"codeHash": creation.CodeHash, "address": bson.M{"$ne": address}}, page)
+}
+
+// creations returns a page of the contract creations matching filter, without their code
+
+func (m *MongoDB) creations(filter bson.M, page *Page) (map[string]interface{}, error) {
+	var (
+		creations = make([]models.ContractCreation, 0)
+		result    = map[string]interface{}{}
+	)
+
+	p, err := newPager(page, creationOrder, DefaultPageSize)
+	if err != nil {
+		return result, err
+	}
+
+	if err := m.findPage(p, models.CREATIONS, filter, &creations, bson.M{"code": 0}); err != nil {
+		return result, err
+	}
+
+	count, err := p.total(func() (int64, error) {
+		return m.C(models.CREATIONS).CountDocuments(context.Background(), filter, options.Count())
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	p.setResult(result, "contracts", creations, count)
+
+	return result, nil
+}
+
 // Accounts
 
 func (m *MongoDB) TotalAccountCount() (int64, error) {
 	count, err := m.C(models.ACCOUNTS).CountDocuments(context.Background(), bson.M{}, options.Count())
 	return count, err
 }
+
+// BalanceAt returns the balance of account as of the given block, from the last change at or below it
+
+func (m *MongoDB) BalanceAt(account string, block uint64) (models.BalanceRecord, error) {
+	var record models.BalanceRecord
+
+	err := m.C(models.BALANCES).FindOne(context.Background(), bson.M{"address": account, "block": bson.M{"$lte": block}}, options.FindOne().SetSort(bson.D{{"block", -1}})).Decode(&record)
+	return record, err
+}
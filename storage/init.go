@@ -17,30 +17,46 @@ import (
 
 func (m *MongoDB) Init(rpc *rpc.RPCClient) {
 
-	collection := m.C(models.TRANSACTIONS)
+	genesis, err := genesisBatch(rpc)
+	if err != nil {
+		log.Error("could not retrieve genesis block", "err", err)
+		os.Exit(1)
+	}
+
+	if err := m.CommitBlock(genesis); err != nil {
+		log.Error("could not init supply block", "err", err)
+		os.Exit(1)
+	}
+
+	if _, err := m.C(models.STORE).InsertOne(context.Background(), newStore(m.symbol, genesis.Block.Supply)); err != nil {
+		log.Error("could not init supply block", "err", err)
+	}
+}
+
+// genesisBatch returns the genesis block, with an internal transaction and an account for each premined balance
 
-	txnIndex := new(big.Int).SetUint64(0)
-	initialSupply := new(big.Int).SetUint64(0)
+func genesisBatch(rpc *rpc.RPCClient) (*Batch, error) {
+
+	initialSupply := new(big.Int)
 
 	genesis, err := rpc.GetBlockByHeight(0)
 	if err != nil {
-		log.Error("could not retrieve genesis block", "err", err)
-		os.Exit(1)
+		return nil, err
 	}
 
+	batch := &Batch{Block: &genesis}
+
 	// get genesis state
 	state, _ := rpc.GetState(0)
-	iTransactions := make([]models.ITransaction, len(state.Accounts))
+	iTransactions := make([]models.ITransaction, 0, len(state.Accounts))
 	for k, v := range state.Accounts {
 		switch a := v.(type) {
 		case map[string]interface{}:
-			collection = m.C(models.ITRANSACTIONS)
 			// add accounts balance to initial supply
 			balanceStr := fmt.Sprintf("%v", a["balance"])
 			balance, _ := new(big.Int).SetString(balanceStr, 10)
 			initialSupply = initialSupply.Add(initialSupply, balance)
 
-			// create txn and save to db
 			txn := models.ITransaction{
 				ParentHash:  "0x",
 				BlockNumber: 0,
@@ -53,22 +69,10 @@ func (m *MongoDB) Init(rpc *rpc.RPCClient) {
 				Input:       "0x",
 				Output:      "0x",
 			}
-			// save txn to db
-			if _, err := collection.InsertOne(context.Background(), &txn); err != nil {
-				log.Error("couldn't add txn", "err", err, "itxn", txn)
-			}
-
-			// add txn to transactions array
-			iTransactions[txnIndex.Uint64()] = txn
 
-			// save account to db
-			collection = m.C(models.ACCOUNTS)
-			account := models.Account{Address: k, Balance: balanceStr, Block: 0}
-			if _, err := collection.UpdateOne(context.Background(), bson.M{"address": account.Address}, bson.D{{"$set", &account}}, options.Update().SetUpsert(true)); err != nil {
-				log.Error("couldn't add account", "err", err, "address", k)
-			}
-			// increment txnIndex
-			txnIndex.Add(txnIndex, new(big.Int).SetUint64(1))
+			iTransactions = append(iTransactions, txn)
+			batch.ITransactions = append(batch.ITransactions, &txn)
+			batch.Accounts = append(batch.Accounts, &models.Account{Address: k, Balance: balanceStr, Block: 0})
 		default:
 			// do nothing
 		}
@@ -86,32 +90,23 @@ func (m *MongoDB) Init(rpc *rpc.RPCClient) {
 	genesis.ITransactions = iTransactions
 	genesis.Trace = make([]models.BlockTrace, 0)
 
-	collection = m.C(models.BLOCKS)
-
-	if _, err := collection.InsertOne(context.Background(), genesis); err != nil {
-		log.Error("could not init supply block", "err", err)
-		os.Exit(1)
-	}
-
-	collection = m.C(models.STORE)
+	return batch, nil
+}
 
-	store := &models.Store{
+func newStore(symbol string, supply string) *models.Store {
+	return &models.Store{
 		Timestamp:           util.MakeTimestamp(),
-		Symbol:              m.symbol,
-		Supply:              initialSupply.String(),
+		Symbol:              symbol,
+		Supply:              supply,
 		TotalTransactions:   0,
 		TotalTokenTransfers: 0,
 		TotalUncles:         0,
 	}
-
-	if _, err := collection.InsertOne(context.Background(), store); err != nil {
-		log.Error("could not init supply block", "err", err)
-	}
-
-	m.initIndexes()
 }
 
-func (m *MongoDB) initIndexes() {
+// initIndexes creates the indexes of every collection, it stops at the first one that can't be created
+
+func (m *MongoDB) initIndexes() error {
 
 	iv := m.C(models.BLOCKS).Indexes()
 
@@ -123,12 +118,30 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for blocks", "err", err)
+		return err
 	}
 
 	iv = m.C(models.ACCOUNTS).Indexes()
 	accountAddressIdxModel := mongo.IndexModel{Keys: bson.M{"address": 1}, Options: options.Index().SetName("accountsAddressIndex").SetUnique(true)}
 	_, err = iv.CreateOne(context.Background(), accountAddressIdxModel, options.CreateIndexes())
 
+	if err != nil {
+		log.Error("could not init indexes for accounts", "err", err)
+		return err
+	}
+
+	iv = m.C(models.BALANCES).Indexes()
+
+	balanceAddressIdxModel := mongo.IndexModel{Keys: bson.D{{"address", 1}, {"block", -1}}, Options: options.Index().SetName("balancesAddressBlockIndex").SetUnique(true)}
+	balanceBlockIdxModel := mongo.IndexModel{Keys: bson.M{"block": 1}, Options: options.Index().SetName("balancesBlockIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{balanceAddressIdxModel, balanceBlockIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for balance history", "err", err)
+		return err
+	}
+
 	iv = m.C(models.FORKEDBLOCKS).Indexes()
 
 	rIdxModel := mongo.IndexModel{Keys: bson.M{"hash": 1}, Options: options.Index().SetName("reorgsIndex").SetUnique(true)}
@@ -137,16 +150,30 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init index", "name", rIdxModel.Options.Name, "err", err)
+		return err
+	}
+
+	iv = m.C(models.REORGS).Indexes()
+
+	reorgsIdxModel := mongo.IndexModel{Keys: bson.M{"number": 1}, Options: options.Index().SetName("reorgsNumberIndex")}
+
+	_, err = iv.CreateOne(context.Background(), reorgsIdxModel, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init index", "name", reorgsIdxModel.Options.Name, "err", err)
+		return err
 	}
 
 	iv = m.C(models.UNCLES).Indexes()
 
 	uIdxModel := mongo.IndexModel{Keys: bson.M{"hash": 1}, Options: options.Index().SetName("unclesIndex").SetUnique(true)}
+	uBNIdxModel := mongo.IndexModel{Keys: bson.M{"blockNumber": 1}, Options: options.Index().SetName("unclesBlockNumberIndex")}
 
-	_, err = iv.CreateOne(context.Background(), uIdxModel, options.CreateIndexes())
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{uIdxModel, uBNIdxModel}, options.CreateIndexes())
 
 	if err != nil {
-		log.Error("could not init index", "name", uIdxModel.Options.Name, "err", err)
+		log.Error("could not init indexes for uncles", "err", err)
+		return err
 	}
 
 	iv = m.C(models.TRANSACTIONS).Indexes()
@@ -162,16 +189,117 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for transactions", "err", err)
+		return err
+	}
+
+	iv = m.C(models.TOKENS).Indexes()
+
+	tokenAddressIdxModel := mongo.IndexModel{Keys: bson.M{"address": 1}, Options: options.Index().SetName("tokensAddressIndex").SetUnique(true)}
+	tokenSymbolIdxModel := mongo.IndexModel{Keys: bson.D{{"isToken", 1}, {"symbol", 1}}, Options: options.Index().SetName("tokensSymbolIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{tokenAddressIdxModel, tokenSymbolIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for tokens", "err", err)
+		return err
+	}
+
+	iv = m.C(models.TOKENBALANCES).Indexes()
+
+	tbHolderIdxModel := mongo.IndexModel{Keys: bson.D{{"contract", 1}, {"address", 1}}, Options: options.Index().SetName("tokenBalancesHolderIndex").SetUnique(true)}
+	tbAddressIdxModel := mongo.IndexModel{Keys: bson.M{"address": 1}, Options: options.Index().SetName("tokenBalancesAddressIndex")}
+	tbBalanceIdxModel := mongo.IndexModel{Keys: bson.D{{"contract", 1}, {"balance", -1}}, Options: options.Index().SetName("tokenBalancesBalanceIndex").SetCollation(numericCollation)}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{tbHolderIdxModel, tbAddressIdxModel, tbBalanceIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for token balances", "err", err)
+		return err
+	}
+
+	iv = m.C(models.TOKENHISTORY).Indexes()
+
+	thHolderIdxModel := mongo.IndexModel{Keys: bson.D{{"contract", 1}, {"address", 1}, {"block", -1}}, Options: options.Index().SetName("tokenHistoryHolderBlockIndex").SetUnique(true)}
+	thBlockIdxModel := mongo.IndexModel{Keys: bson.M{"block": 1}, Options: options.Index().SetName("tokenHistoryBlockIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{thHolderIdxModel, thBlockIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for token balance history", "err", err)
+		return err
+	}
+
+	iv = m.C(models.NFTTRANSFERS).Indexes()
+
+	nftBNIdxModel := mongo.IndexModel{Keys: bson.M{"blockNumber": 1}, Options: options.Index().SetName("nftBlockNumberIndex")}
+	nftHIdxModel := mongo.IndexModel{Keys: bson.D{{"hash", 1}, {"logIndex", 1}}, Options: options.Index().SetName("nftTxHashLogIndex")}
+	nftTokenIdxModel := mongo.IndexModel{Keys: bson.D{{"contract", 1}, {"tokenId", 1}, {"blockNumber", -1}, {"logIndex", -1}}, Options: options.Index().SetName("nftTokenIndex")}
+	nftFIdxModel := mongo.IndexModel{Keys: bson.M{"from": 1}, Options: options.Index().SetName("nftFromIndex")}
+	nftTIdxModel := mongo.IndexModel{Keys: bson.M{"to": 1}, Options: options.Index().SetName("nftToIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{nftBNIdxModel, nftHIdxModel, nftTokenIdxModel, nftFIdxModel, nftTIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for nft transfers", "err", err)
+		return err
+	}
+
+	iv = m.C(models.ABIS).Indexes()
+
+	abiAddressIdxModel := mongo.IndexModel{Keys: bson.M{"address": 1}, Options: options.Index().SetName("abisAddressIndex").SetUnique(true)}
+
+	_, err = iv.CreateOne(context.Background(), abiAddressIdxModel, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for abis", "err", err)
+		return err
+	}
+
+	iv = m.C(models.VERIFIED).Indexes()
+
+	verifiedAddressIdxModel := mongo.IndexModel{Keys: bson.M{"address": 1}, Options: options.Index().SetName("verifiedAddressIndex").SetUnique(true)}
+
+	_, err = iv.CreateOne(context.Background(), verifiedAddressIdxModel, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for verified contracts", "err", err)
+		return err
+	}
+
+	iv = m.C(models.SIGNATURES).Indexes()
+
+	sigHashIdxModel := mongo.IndexModel{Keys: bson.D{{"hash", 1}, {"text", 1}}, Options: options.Index().SetName("signaturesHashTextIndex").SetUnique(true)}
+	sigLookupIdxModel := mongo.IndexModel{Keys: bson.D{{"hash", 1}, {"builtin", -1}, {"_id", 1}}, Options: options.Index().SetName("signaturesLookupIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{sigHashIdxModel, sigLookupIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for signatures", "err", err)
+		return err
+	}
+
+	iv = m.C(models.TRACES).Indexes()
+
+	traceHashIdxModel := mongo.IndexModel{Keys: bson.D{{"hash", 1}, {"index", 1}}, Options: options.Index().SetName("tracesHashIndex").SetUnique(true)}
+	traceBNIdxModel := mongo.IndexModel{Keys: bson.M{"blockNumber": 1}, Options: options.Index().SetName("tracesBlockNumberIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{traceHashIdxModel, traceBNIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for traces", "err", err)
+		return err
 	}
 
 	iv = m.C(models.ITRANSACTIONS).Indexes()
 
 	iTxnFIdxModel := mongo.IndexModel{Keys: bson.M{"from": 1}, Options: options.Index().SetName("txFromIndex")}
 	iTxnTIdxModel := mongo.IndexModel{Keys: bson.M{"to": 1}, Options: options.Index().SetName("txToIndex")}
-	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{iTxnFIdxModel, iTxnTIdxModel}, options.CreateIndexes())
+	iTxnBNIdxModel := mongo.IndexModel{Keys: bson.M{"blockNumber": 1}, Options: options.Index().SetName("txBlockNumberIndex")}
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{iTxnFIdxModel, iTxnTIdxModel, iTxnBNIdxModel}, options.CreateIndexes())
 
 	if err != nil {
 		log.Error("could not init indexes for internal tx", "err", err)
+		return err
 	}
 
 	iv = m.C(models.CONTRACTS).Indexes()
@@ -187,6 +315,21 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for contracts", "err", err)
+		return err
+	}
+
+	iv = m.C(models.CREATIONS).Indexes()
+
+	creationsAddressIdxModel := mongo.IndexModel{Keys: bson.D{{"address", 1}, {"blockNumber", -1}}, Options: options.Index().SetName("creationsAddressIndex")}
+	creationsCreatorIdxModel := mongo.IndexModel{Keys: bson.D{{"creator", 1}, {"blockNumber", -1}}, Options: options.Index().SetName("creationsCreatorIndex")}
+	creationsCodeHashIdxModel := mongo.IndexModel{Keys: bson.D{{"codeHash", 1}, {"blockNumber", -1}}, Options: options.Index().SetName("creationsCodeHashIndex")}
+	creationsBNIdxModel := mongo.IndexModel{Keys: bson.M{"blockNumber": 1}, Options: options.Index().SetName("creationsBlockNumberIndex")}
+
+	_, err = iv.CreateMany(context.Background(), []mongo.IndexModel{creationsAddressIdxModel, creationsCreatorIdxModel, creationsCodeHashIdxModel, creationsBNIdxModel}, options.CreateIndexes())
+
+	if err != nil {
+		log.Error("could not init indexes for contract creations", "err", err)
+		return err
 	}
 
 	iv = m.C(models.CONTRACTCALLS).Indexes()
@@ -202,12 +345,13 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for contract calls", "err", err)
+		return err
 	}
 
 	iv = m.C(models.TRANSFERS).Indexes()
 
 	trBNIdxModel := mongo.IndexModel{Keys: bson.M{"blockNumber": 1}, Options: options.Index().SetName("trBlockNumberIndex")}
-	trHIdxModel := mongo.IndexModel{Keys: bson.M{"hash": 1}, Options: options.Index().SetName("trTxHashIndex")}
+	trHIdxModel := mongo.IndexModel{Keys: bson.D{{"hash", 1}, {"logIndex", 1}}, Options: options.Index().SetName("trTxHashLogIndex")}
 	trFIdxModel := mongo.IndexModel{Keys: bson.M{"from": 1}, Options: options.Index().SetName("trFromIndex")}
 	trTIdxModel := mongo.IndexModel{Keys: bson.M{"to": 1}, Options: options.Index().SetName("trToIndex")}
 	trCIdxModel := mongo.IndexModel{Keys: bson.M{"contractAddress": 1}, Options: options.Index().SetName("trContractIndex")}
@@ -217,6 +361,7 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for transfers", "err", err)
+		return err
 	}
 
 	iv = m.C(models.ENODES).Indexes()
@@ -227,6 +372,7 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for enodes", "err", err)
+		return err
 	}
 
 	iv = m.C(models.CHARTS).Indexes()
@@ -237,8 +383,10 @@ func (m *MongoDB) initIndexes() {
 
 	if err != nil {
 		log.Error("could not init indexes for enodes", "err", err)
+		return err
 	}
 
 	log.Warn("initialised database indexes")
 
+	return nil
 }
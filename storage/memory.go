@@ -0,0 +1,760 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/rpc"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Memory is a Backend that keeps every collection in the process. Documents are copied through bson on the
+// way in and out, so they come back exactly as they would from mongo and callers never share them.
+// Queries are linear scans, it's meant for tests and short-lived dev environments rather than a full sync
+
+type Memory struct {
+	mu     sync.RWMutex
+	symbol string
+
+	store       *models.Store
+	checkpoints map[string]models.Checkpoint
+
+	blocks       []*models.Block
+	forkedBlocks []*models.Block
+	reorgs       []*models.Reorg
+	uncles       []*models.Uncle
+	transactions []*models.Transaction
+	itxns        []*models.ITransaction
+	contracts    []*models.Transaction
+	calls        []*models.Transaction
+	transfers    []*models.TokenTransfer
+	nftTransfers []*models.NFTTransfer
+	creations    []*models.ContractCreation
+
+	accounts      map[string]*models.Account
+	balances      map[string][]*models.BalanceRecord // by address, oldest first
+	tokenBalances map[tokenHolder]*models.TokenBalance
+	tokenHistory  map[tokenHolder][]*models.TokenBalance // oldest first
+
+	tokens     map[string]*models.Token
+	abis       map[string]*models.ContractABI
+	verified   map[string]*models.VerifiedContract
+	signatures []*models.Signature // in import order, like _id
+	enodes     []*models.Enode
+	charts     map[string]bson.Raw
+}
+
+type tokenHolder struct {
+	contract, address string
+}
+
+func NewMemory(cfg *Config) *Memory {
+	return &Memory{
+		symbol:        cfg.Symbol,
+		checkpoints:   make(map[string]models.Checkpoint),
+		accounts:      make(map[string]*models.Account),
+		balances:      make(map[string][]*models.BalanceRecord),
+		tokenBalances: make(map[tokenHolder]*models.TokenBalance),
+		tokenHistory:  make(map[tokenHolder][]*models.TokenBalance),
+		tokens:        make(map[string]*models.Token),
+		abis:          make(map[string]*models.ContractABI),
+		verified:      make(map[string]*models.VerifiedContract),
+		charts:        make(map[string]bson.Raw),
+	}
+}
+
+// clone deep copies src into dst, which must be a pointer to a zero value
+
+func clone(dst, src interface{}) error {
+	raw, err := bson.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(raw, dst)
+}
+
+func (m *Memory) Init(rpc *rpc.RPCClient) {
+
+	genesis, err := genesisBatch(rpc)
+	if err != nil {
+		log.Error("could not retrieve genesis block", "err", err)
+		os.Exit(1)
+	}
+
+	if err := m.CommitBlock(genesis); err != nil {
+		log.Error("could not init supply block", "err", err)
+		os.Exit(1)
+	}
+
+	m.mu.Lock()
+	m.store = newStore(m.symbol, genesis.Block.Supply)
+	m.mu.Unlock()
+}
+
+func (m *Memory) IsFirstRun() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.store == nil
+}
+
+func (m *Memory) Ping() error {
+	return nil
+}
+
+// Migrate does nothing, a memory db always starts out with the latest schema
+
+func (m *Memory) Migrate() error {
+	return nil
+}
+
+// Writes
+
+// CommitBlock writes a batch at once, nothing is written if the block is already there
+
+func (m *Memory) CommitBlock(b *Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.writeBatch(b)
+}
+
+func (m *Memory) writeBatch(b *Batch) error {
+
+	for _, block := range m.blocks {
+		if block.Number == b.Block.Number || block.Hash == b.Block.Hash {
+			return fmt.Errorf("block %v (%v) is already stored", b.Block.Number, b.Block.Hash)
+		}
+	}
+
+	// the whole batch is copied at once, so a batch that can't be copied isn't partially written
+	var c batchDocuments
+
+	err := clone(&c, &batchDocuments{b.Block, b.Transactions, b.ITransactions, b.TokenTransfers, b.NFTTransfers, b.Uncles, b.Accounts, b.TokenBalances, b.Contracts, b.Creations, b.ContractCalls})
+	if err != nil {
+		return err
+	}
+
+	m.transactions = append(m.transactions, c.Transactions...)
+	m.itxns = append(m.itxns, c.ITransactions...)
+	m.transfers = append(m.transfers, c.TokenTransfers...)
+	m.nftTransfers = append(m.nftTransfers, c.NFTTransfers...)
+	m.uncles = append(m.uncles, c.Uncles...)
+	m.contracts = append(m.contracts, c.Contracts...)
+	m.calls = append(m.calls, c.ContractCalls...)
+	m.creations = append(m.creations, c.Creations...)
+
+	for _, r := range balanceRecords(c.Accounts, m.lastBalances(accountAddresses(c.Accounts), c.Block.Number)) {
+		m.addBalanceRecord(r)
+	}
+
+	for _, a := range c.Accounts {
+		m.accounts[a.Address] = a
+	}
+
+	for _, tb := range c.TokenBalances {
+		m.setTokenBalance(tb)
+
+		key := tokenHolder{tb.Contract, tb.Address}
+		m.tokenHistory[key] = append(m.tokenHistory[key], tb)
+	}
+
+	m.blocks = append(m.blocks, c.Block)
+
+	if b.Checkpoint != "" {
+		m.checkpoints[b.Checkpoint] = models.Checkpoint{Crawler: b.Checkpoint, Number: c.Block.Number, Hash: c.Block.Hash, Updated: time.Now().Unix()}
+	}
+
+	return nil
+}
+
+// batchDocuments are the documents of a Batch
+
+type batchDocuments struct {
+	Block          *models.Block
+	Transactions   []*models.Transaction
+	ITransactions  []*models.ITransaction
+	TokenTransfers []*models.TokenTransfer
+	NFTTransfers   []*models.NFTTransfer
+	Uncles         []*models.Uncle
+	Accounts       []*models.Account
+	TokenBalances  []*models.TokenBalance
+	Contracts      []*models.Transaction
+	Creations      []*models.ContractCreation
+	ContractCalls  []*models.Transaction
+}
+
+// lastBalances returns the latest recorded balance below block for each of addresses, see MongoDB.lastBalances
+
+func (m *Memory) lastBalances(addresses []string, block uint64) map[string]*big.Int {
+	prev := make(map[string]*big.Int)
+
+	for _, address := range addresses {
+		history := m.balances[address]
+
+		i := sort.Search(len(history), func(i int) bool { return history[i].Block >= block })
+		if i == 0 {
+			continue
+		}
+
+		if balance, ok := new(big.Int).SetString(history[i-1].Balance, 10); ok {
+			prev[address] = balance
+		}
+	}
+
+	return prev
+}
+
+// addBalanceRecord keeps the history of each address ordered by block, replacing a record of the same block
+
+func (m *Memory) addBalanceRecord(r *models.BalanceRecord) {
+	history := m.balances[r.Address]
+
+	i := sort.Search(len(history), func(i int) bool { return history[i].Block >= r.Block })
+
+	if i < len(history) && history[i].Block == r.Block {
+		history[i] = r
+		return
+	}
+
+	history = append(history, nil)
+	copy(history[i+1:], history[i:])
+	history[i] = r
+
+	m.balances[r.Address] = history
+}
+
+func (m *Memory) setTokenBalance(tb *models.TokenBalance) {
+	key := tokenHolder{tb.Contract, tb.Address}
+
+	if tb.Balance == "0" {
+		delete(m.tokenBalances, key)
+		return
+	}
+
+	m.tokenBalances[key] = tb
+}
+
+// NewBulkWriter returns a writer that commits its pending batches every size blocks, or once the oldest one has
+// waited for interval. Writes are cheap here, it only keeps the visibility rules of BulkWriter
+
+func (m *Memory) NewBulkWriter(size int, interval time.Duration) BatchWriter {
+	return newWindowWriter(size, interval, func(pending []*Batch) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		for _, b := range pending {
+			if err := m.writeBatch(b); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *Memory) PurgeBlock(height uint64) error {
+	return m.PurgeFrom(height)
+}
+
+func (m *Memory) PurgeFrom(height uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purgeFrom(height)
+
+	return nil
+}
+
+func (m *Memory) purgeFrom(height uint64) {
+
+	blocks := m.blocks[:0]
+	for _, b := range m.blocks {
+		if b.Number < height {
+			blocks = append(blocks, b)
+		}
+	}
+	m.blocks = blocks
+
+	m.transactions = transactionsBelow(m.transactions, height)
+	m.contracts = transactionsBelow(m.contracts, height)
+	m.calls = transactionsBelow(m.calls, height)
+
+	itxns := m.itxns[:0]
+	for _, t := range m.itxns {
+		if t.BlockNumber < height {
+			itxns = append(itxns, t)
+		}
+	}
+	m.itxns = itxns
+
+	transfers := m.transfers[:0]
+	for _, t := range m.transfers {
+		if t.BlockNumber < height {
+			transfers = append(transfers, t)
+		}
+	}
+	m.transfers = transfers
+
+	nfts := m.nftTransfers[:0]
+	for _, t := range m.nftTransfers {
+		if t.BlockNumber < height {
+			nfts = append(nfts, t)
+		}
+	}
+	m.nftTransfers = nfts
+
+	uncles := m.uncles[:0]
+	for _, u := range m.uncles {
+		if u.BlockNumber < height {
+			uncles = append(uncles, u)
+		}
+	}
+	m.uncles = uncles
+
+	creations := m.creations[:0]
+	for _, c := range m.creations {
+		if c.BlockNumber < height {
+			creations = append(creations, c)
+		}
+	}
+	m.creations = creations
+
+	// accounts and token holders go back to their last balance below height, or away if they had none
+	for address, history := range m.balances {
+		i := sort.Search(len(history), func(i int) bool { return history[i].Block >= height })
+		if i == len(history) {
+			continue
+		}
+
+		if i == 0 {
+			delete(m.balances, address)
+			delete(m.accounts, address)
+			continue
+		}
+
+		m.balances[address] = history[:i]
+
+		prev := history[i-1]
+		m.accounts[address] = &models.Account{Address: address, Balance: prev.Balance, Block: prev.Block, Nonce: prev.Nonce, HasCode: prev.HasCode}
+	}
+
+	for key, history := range m.tokenHistory {
+		i := sort.Search(len(history), func(i int) bool { return history[i].Block >= height })
+		if i == len(history) {
+			continue
+		}
+
+		if i == 0 {
+			delete(m.tokenHistory, key)
+			delete(m.tokenBalances, key)
+			continue
+		}
+
+		m.tokenHistory[key] = history[:i]
+		m.setTokenBalance(history[i-1])
+	}
+
+	log.Debug("purged blocks", "from", height)
+}
+
+func transactionsBelow(txns []*models.Transaction, height uint64) []*models.Transaction {
+	kept := txns[:0]
+
+	for _, t := range txns {
+		if t.BlockNumber < height {
+			kept = append(kept, t)
+		}
+	}
+
+	return kept
+}
+
+// Rollback removes every block above height, see MongoDB.Rollback
+
+func (m *Memory) Rollback(height uint64, checkpoint string) ([]models.Block, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ancestor *models.Block
+
+	orphaned := make([]*models.Block, 0)
+
+	for _, b := range m.blocks {
+		if b.Number > height {
+			orphaned = append(orphaned, b)
+		} else if b.Number == height {
+			ancestor = b
+		}
+	}
+
+	if checkpoint != "" && ancestor == nil {
+		return nil, ErrNotFound
+	}
+
+	sort.Slice(orphaned, func(i, j int) bool { return orphaned[i].Number > orphaned[j].Number })
+
+	rolledBack := make([]models.Block, len(orphaned))
+
+	for i, b := range orphaned {
+		if err := clone(&rolledBack[i], b); err != nil {
+			return nil, err
+		}
+
+		// a block may have been forked before
+		replaced := false
+		for j, f := range m.forkedBlocks {
+			if f.Hash == b.Hash {
+				m.forkedBlocks[j] = b
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			m.forkedBlocks = append(m.forkedBlocks, b)
+		}
+	}
+
+	m.purgeFrom(height + 1)
+
+	if checkpoint != "" {
+		m.checkpoints[checkpoint] = models.Checkpoint{Crawler: checkpoint, Number: ancestor.Number, Hash: ancestor.Hash, Updated: time.Now().Unix()}
+	}
+
+	return rolledBack, nil
+}
+
+func (m *Memory) Checkpoint(crawler string) (models.Checkpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cp, ok := m.checkpoints[crawler]
+	if !ok {
+		return models.Checkpoint{}, ErrNotFound
+	}
+
+	return cp, nil
+}
+
+func (m *Memory) SetCheckpoint(crawler string, number uint64, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpoints[crawler] = models.Checkpoint{Crawler: crawler, Number: number, Hash: hash, Updated: time.Now().Unix()}
+
+	return nil
+}
+
+func (m *Memory) AddReorg(r *models.Reorg) error {
+	var reorg models.Reorg
+
+	if err := clone(&reorg, r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reorgs = append(m.reorgs, &reorg)
+
+	return nil
+}
+
+func (m *Memory) AddToken(t *models.Token) error {
+	var token models.Token
+
+	if err := clone(&token, t); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[token.Address] = &token
+
+	return nil
+}
+
+func (m *Memory) AddEnodes(e *models.Enode) error {
+	enode := *e
+	enode.Ip = append(enode.Ip[:0:0], e.Ip...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enodes = append(m.enodes, &enode)
+
+	return nil
+}
+
+func (m *Memory) AddNumberChart(name string, series []uint64, stamps []string) error {
+	return m.setChart(name, &models.NumberChart{Name: name, Series: series, Timestamps: stamps})
+}
+
+func (m *Memory) AddNumberStringChart(name string, series []string, stamps []string) error {
+	return m.setChart(name, &models.NumberStringChart{Name: name, Series: series, Timestamps: stamps})
+}
+
+func (m *Memory) AddMultiSeriesChart(name string, series map[string]map[string]uint, stamps []string) error {
+	return m.setChart(name, &models.MultiSeriesChart{Name: name, Datasets: multiSeriesDatasets(series), Timestamps: stamps})
+}
+
+// charts are kept encoded, as they're read back as whatever chart type is asked for
+
+func (m *Memory) setChart(name string, chart interface{}) error {
+	raw, err := bson.Marshal(chart)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.charts[name] = raw
+
+	return nil
+}
+
+func (m *Memory) AddContractABI(address string, abiJSON string) error {
+	return m.addContractABI(address, abiJSON, false)
+}
+
+func (m *Memory) addContractABI(address string, abiJSON string, verified bool) error {
+	if _, err := abi.JSON(strings.NewReader(abiJSON)); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	address = strings.ToLower(address)
+
+	if prev, ok := m.abis[address]; ok && prev.Verified && !verified {
+		return ErrVerifiedABI
+	}
+
+	m.abis[address] = &models.ContractABI{Address: address, ABI: abiJSON, Updated: time.Now().Unix(), Verified: verified}
+
+	return nil
+}
+
+func (m *Memory) AddVerifiedContract(c *models.VerifiedContract) error {
+	var verified models.VerifiedContract
+
+	if err := clone(&verified, c); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.verified[verified.Address] = &verified
+	m.mu.Unlock()
+
+	return m.addContractABI(c.Address, c.ABI, true)
+}
+
+func (m *Memory) ImportSignatures(sigs []models.Signature) (int64, error) {
+	var added int64
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	known := make(map[[2]string]bool, len(m.signatures))
+	for _, s := range m.signatures {
+		known[[2]string{s.Hash, s.Text}] = true
+	}
+
+	for i := range sigs {
+		key := [2]string{sigs[i].Hash, sigs[i].Text}
+		if known[key] {
+			continue
+		}
+		known[key] = true
+
+		sig := sigs[i]
+		m.signatures = append(m.signatures, &sig)
+		added++
+	}
+
+	return added, nil
+}
+
+// UpdateStore refreshes the totals of the store, see MongoDB.UpdateStore
+
+func (m *Memory) UpdateStore() error {
+	latestBlock, err := m.LatestBlock()
+	if err != nil {
+		return err
+	}
+
+	latestTrace, err := m.LatestTxTrace()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store == nil {
+		return errors.New("didn't update " + m.symbol + " store")
+	}
+
+	m.store.Timestamp = time.Now().Unix()
+	m.store.Supply = latestBlock.Supply
+	m.store.LatestBlock = latestBlock
+	m.store.LatestTraceHash = latestTrace.ParentHash
+	m.store.TotalTransactions = int64(len(m.transactions))
+	m.store.TotalContractsDeployed = int64(len(m.contracts))
+	m.store.TotalContractCalls = int64(len(m.calls))
+	m.store.TotalTokenTransfers = int64(len(m.transfers))
+	m.store.TotalUncles = int64(len(m.uncles))
+	m.store.TotalForkedBlocks = int64(len(m.forkedBlocks))
+
+	return nil
+}
+
+// Iterators
+
+// memoryIterator walks a snapshot of stored documents, stored documents are never changed in place
+
+type memoryIterator struct {
+	docs []interface{}
+	cur  interface{}
+	err  error
+}
+
+func (it *memoryIterator) Next(ctx context.Context) bool {
+	if it.err = ctx.Err(); it.err != nil || len(it.docs) == 0 {
+		return false
+	}
+
+	it.cur, it.docs = it.docs[0], it.docs[1:]
+
+	return true
+}
+
+func (it *memoryIterator) Decode(val interface{}) error {
+	if it.cur == nil {
+		return errors.New("no current document")
+	}
+
+	return clone(val, it.cur)
+}
+
+func (it *memoryIterator) Err() error {
+	return it.err
+}
+
+func (it *memoryIterator) Close(ctx context.Context) error {
+	it.docs, it.cur = nil, nil
+	return nil
+}
+
+func inRange(timestamp uint64, from, to int64) bool {
+	return int64(timestamp) > from && int64(timestamp) < to
+}
+
+func (m *Memory) IterTransactions(from, to int64) (Iterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var txns []*models.Transaction
+	for _, t := range m.transactions {
+		if inRange(t.Timestamp, from, to) {
+			txns = append(txns, t)
+		}
+	}
+
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].BlockNumber < txns[j].BlockNumber })
+
+	it := &memoryIterator{docs: make([]interface{}, len(txns))}
+	for i := range txns {
+		it.docs[i] = txns[i]
+	}
+
+	return it, nil
+}
+
+func (m *Memory) IterBlocks(from, to int64) (Iterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return blockIterator(m.blocks, from, to), nil
+}
+
+func (m *Memory) IterForkedBlocks(from, to int64) (Iterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return blockIterator(m.forkedBlocks, from, to), nil
+}
+
+func blockIterator(stored []*models.Block, from, to int64) Iterator {
+	var blocks []*models.Block
+	for _, b := range stored {
+		if inRange(b.Timestamp, from, to) {
+			blocks = append(blocks, b)
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Number < blocks[j].Number })
+
+	it := &memoryIterator{docs: make([]interface{}, len(blocks))}
+	for i := range blocks {
+		it.docs[i] = blocks[i]
+	}
+
+	return it
+}
+
+func (m *Memory) IterUncles(from, to int64) (Iterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var uncles []*models.Uncle
+	for _, u := range m.uncles {
+		if inRange(u.Timestamp, from, to) {
+			uncles = append(uncles, u)
+		}
+	}
+
+	sort.SliceStable(uncles, func(i, j int) bool { return uncles[i].BlockNumber < uncles[j].BlockNumber })
+
+	it := &memoryIterator{docs: make([]interface{}, len(uncles))}
+	for i := range uncles {
+		it.docs[i] = uncles[i]
+	}
+
+	return it, nil
+}
+
+func (m *Memory) IterTokenTransfers(from, to int64) (Iterator, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var transfers []*models.TokenTransfer
+	for _, t := range m.transfers {
+		if inRange(t.Timestamp, from, to) {
+			transfers = append(transfers, t)
+		}
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool { return transfers[i].BlockNumber < transfers[j].BlockNumber })
+
+	it := &memoryIterator{docs: make([]interface{}, len(transfers))}
+	for i := range transfers {
+		it.docs[i] = transfers[i]
+	}
+
+	return it, nil
+}
@@ -0,0 +1,373 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The api methods of the memory backend, they return the same documents and totals as their mongo counterparts in api.go
+
+//Blocks
+
+func (m *Memory) LatestBlocks(page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total int64
+
+	for _, b := range m.blocks {
+		if int64(b.Number)+1 > total {
+			total = int64(b.Number) + 1
+		}
+	}
+
+	return memoryPage(page, blockOrder, m.blocks, "blocks", &[]models.Block{}, total)
+}
+
+func (m *Memory) LatestMinedBlocks(account string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mined := make([]*models.Block, 0)
+
+	for _, b := range m.blocks {
+		if b.Miner == account {
+			mined = append(mined, b)
+		}
+	}
+
+	return memoryPage(page, blockOrder, mined, "blocks", &[]models.Block{}, int64(len(mined)))
+}
+
+//Uncles
+
+func (m *Memory) LatestUncles(page *Page) (map[string]interface{}, error) {
+	status, err := m.Status()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, order{number: "blockNumber", index: "position"}, m.uncles, "uncles", &[]models.Uncle{}, status.TotalUncles)
+}
+
+//Forked Blocks
+
+func (m *Memory) LatestForkedBlocks(page *Page) (map[string]interface{}, error) {
+	status, err := m.Status()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, order{number: "number", key: "hash"}, m.forkedBlocks, "forkedBlocks", &[]models.Block{}, status.TotalForkedBlocks)
+}
+
+//Reorgs
+
+func (m *Memory) LatestReorgs(page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, order{number: "number", key: "_id"}, m.reorgs, "reorgs", &[]models.Reorg{}, int64(len(m.reorgs)))
+}
+
+//Transactions
+
+func (m *Memory) LatestTransactions(page *Page) (map[string]interface{}, error) {
+	status, err := m.Status()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, transactionOrder, m.transactions, "txns", &[]models.Transaction{}, status.TotalTransactions)
+}
+
+func (m *Memory) LatestFailedTransactions(page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	failed := filterTransactions(m.transactions, func(t *models.Transaction) bool { return t.BlockNumber >= 1075090 && !t.Status })
+
+	return memoryPage(page, transactionOrder, failed, "txns", &[]models.Transaction{}, int64(len(failed)))
+}
+
+//Contracts
+
+func (m *Memory) LatestContractCalls(page *Page) (map[string]interface{}, error) {
+	status, err := m.Status()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, transactionOrder, m.calls, "txns", &[]models.Transaction{}, status.TotalContractCalls)
+}
+
+func (m *Memory) LatestContractsDeployed(page *Page) (map[string]interface{}, error) {
+	status, err := m.Status()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, transactionOrder, m.contracts, "txns", &[]models.Transaction{}, status.TotalContractsDeployed)
+}
+
+//Tokens
+
+func (m *Memory) LatestTokenTransfers(page *Page) (map[string]interface{}, error) {
+	status, err := m.Status()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return memoryPage(page, transferOrder, m.transfers, "transfers", &[]models.TokenTransfer{}, status.TotalTokenTransfers)
+}
+
+func (m *Memory) LatestTransfersOfToken(hash string, page *Page) (map[string]interface{}, error) {
+	return m.latestTokenTransfers(func(t *models.TokenTransfer) bool { return t.Contract == hash }, page)
+}
+
+//NFTs
+
+func (m *Memory) LatestNFTTransfersByContract(contract string, page *Page) (map[string]interface{}, error) {
+	return m.latestNFTTransfers(func(t *models.NFTTransfer) bool { return t.Contract == contract }, page)
+}
+
+func (m *Memory) LatestNFTTransfersByToken(contract string, tokenId string, page *Page) (map[string]interface{}, error) {
+	return m.latestNFTTransfers(func(t *models.NFTTransfer) bool { return t.Contract == contract && t.TokenId == tokenId }, page)
+}
+
+func (m *Memory) LatestNFTTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.latestNFTTransfers(func(t *models.NFTTransfer) bool { return t.From == account || t.To == account }, page)
+}
+
+func (m *Memory) latestNFTTransfers(match func(*models.NFTTransfer) bool, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched := make([]*models.NFTTransfer, 0)
+
+	for _, t := range m.nftTransfers {
+		if match(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	return memoryPage(page, nftOrder, matched, "transfers", &[]models.NFTTransfer{}, int64(len(matched)))
+}
+
+//Tokens
+
+func (m *Memory) ListTokens(page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]*models.Token, 0)
+
+	for _, t := range m.tokens {
+		if t.IsToken {
+			tokens = append(tokens, t)
+		}
+	}
+
+	return memoryPage(page, order{value: "symbol", key: "address"}, tokens, "tokens", &[]models.Token{}, int64(len(tokens)))
+}
+
+func (m *Memory) TokenHolders(contract string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	balances := make([]*models.TokenBalance, 0)
+
+	for key, tb := range m.tokenBalances {
+		if key.contract == contract {
+			balances = append(balances, tb)
+		}
+	}
+
+	result, err := memoryPage(page, balanceOrder, balances, "holders", &[]models.TokenHolder{}, int64(len(balances)))
+	if err != nil {
+		return result, err
+	}
+
+	// percentages are left at 0 if the token metadata isn't known yet
+	if token, known := m.tokens[contract]; known && token.IsToken {
+		setHolderPercentages(result["holders"].([]models.TokenHolder), token.TotalSupply)
+	}
+
+	return result, nil
+}
+
+func (m *Memory) TokenBalancesByAccount(account string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	balances := make([]*models.TokenBalance, 0)
+
+	for key, tb := range m.tokenBalances {
+		if key.address == account {
+			balances = append(balances, tb)
+		}
+	}
+
+	return memoryPage(page, order{key: "contract"}, balances, "balances", &[]models.TokenBalance{}, int64(len(balances)))
+}
+
+//Accounts
+
+func (m *Memory) LatestTransactionsByAccount(hash string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched := filterTransactions(m.transactions, func(t *models.Transaction) bool { return t.From == hash || t.To == hash })
+
+	return memoryPage(page, transactionOrder, matched, "txns", &[]models.Transaction{}, int64(len(matched)))
+}
+
+func (m *Memory) LatestITransactionsByAccount(hash string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched := make([]*models.ITransaction, 0)
+
+	for _, t := range m.itxns {
+		if t.From == hash || t.To == hash {
+			matched = append(matched, t)
+		}
+	}
+
+	return memoryPage(page, order{number: "blockNumber", key: "_id"}, matched, "itxns", &[]models.ITransaction{}, int64(len(matched)))
+}
+
+func (m *Memory) LatestTokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.latestTokenTransfers(func(t *models.TokenTransfer) bool { return t.From == account || t.To == account }, page)
+}
+
+func (m *Memory) latestTokenTransfers(match func(*models.TokenTransfer) bool, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched := make([]*models.TokenTransfer, 0)
+
+	for _, t := range m.transfers {
+		if match(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	return memoryPage(page, transferOrder, matched, "transfers", &[]models.TokenTransfer{}, int64(len(matched)))
+}
+
+func (m *Memory) AccountsByBalance(page *Page) (map[string]interface{}, error) {
+	return m.latestAccounts(balanceOrder, page)
+}
+
+func (m *Memory) AccountsByLastSeen(page *Page) (map[string]interface{}, error) {
+	return m.latestAccounts(order{number: "block", key: "address"}, page)
+}
+
+func (m *Memory) latestAccounts(o order, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]*models.Account, 0, len(m.accounts))
+
+	for _, a := range m.accounts {
+		accounts = append(accounts, a)
+	}
+
+	return memoryPage(page, o, accounts, "accounts", &[]models.Account{}, int64(len(accounts)))
+}
+
+func (m *Memory) BalanceHistory(account string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.balances[account]
+
+	return memoryPage(page, order{number: "block"}, history, "history", &[]models.BalanceRecord{}, int64(len(history)))
+}
+
+// memoryPage reads the page of docs, a slice of pointers to the documents of a list, into items like
+// MongoDB.page does, and returns it under name along with total. Documents go through bson so their positions
+// are read from the mongo fields. There's no _id here, documents without a key are keyed by their place in
+// docs instead, which is the order they were written in
+
+func memoryPage(page *Page, o order, docs interface{}, name string, items interface{}, total int64) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+
+	p, err := newPager(page, o, DefaultPageSize)
+	if err != nil {
+		return result, err
+	}
+
+	type positioned struct {
+		raw bson.Raw
+		at  cursor
+	}
+
+	var (
+		v       = reflect.ValueOf(docs)
+		matched = make([]positioned, 0, v.Len())
+	)
+
+	for i := 0; i < v.Len(); i++ {
+		doc, err := bson.Marshal(v.Index(i).Interface())
+		if err != nil {
+			return result, err
+		}
+
+		raw := bson.Raw(doc)
+		at := o.position(raw)
+
+		if _, err := raw.LookupErr(o.key); o.key != "" && err != nil {
+			at.Key = fmt.Sprintf("%020d", i)
+		}
+
+		if p.after == nil || o.less(*p.after, at) {
+			matched = append(matched, positioned{raw, at})
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool { return o.less(matched[i].at, matched[j].at) })
+
+	raws := make([]bson.Raw, limited(len(matched), p.limit+1))
+
+	for i := range raws {
+		raws[i] = matched[i].raw
+	}
+
+	if err := p.decode(raws, items); err != nil {
+		return result, err
+	}
+
+	for i := range raws {
+		p.positions[i] = matched[i].at
+	}
+
+	total, _ = p.total(func() (int64, error) { return total, nil })
+
+	p.setResult(result, name, reflect.ValueOf(items).Elem().Interface(), total)
+
+	return result, nil
+}
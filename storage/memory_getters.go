@@ -0,0 +1,708 @@
+package storage
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Store
+
+func (m *Memory) Status() (models.Store, error) {
+	var store models.Store
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.store == nil {
+		return store, ErrNotFound
+	}
+
+	err := clone(&store, m.store)
+	return store, err
+}
+
+// Blocks
+
+func (m *Memory) BlockByNumber(number uint64) (models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, b := range m.blocks {
+		if b.Number == number {
+			return cloneBlock(b)
+		}
+	}
+
+	return models.Block{}, ErrNotFound
+}
+
+func (m *Memory) BlockByHash(hash string) (models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, b := range m.blocks {
+		if b.Hash == hash {
+			return cloneBlock(b)
+		}
+	}
+
+	return models.Block{}, ErrNotFound
+}
+
+func (m *Memory) LatestBlock() (models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var latest *models.Block
+
+	for _, b := range m.blocks {
+		if latest == nil || b.Number > latest.Number {
+			latest = b
+		}
+	}
+
+	if latest == nil {
+		return models.Block{}, ErrNotFound
+	}
+
+	return cloneBlock(latest)
+}
+
+func (m *Memory) TotalBlockCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.blocks)), nil
+}
+
+func cloneBlock(b *models.Block) (models.Block, error) {
+	var block models.Block
+
+	err := clone(&block, b)
+	return block, err
+}
+
+// Uncles
+
+func (m *Memory) UncleByHash(hash string) (models.Uncle, error) {
+	var uncle models.Uncle
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.uncles {
+		if u.Hash == hash {
+			err := clone(&uncle, u)
+			return uncle, err
+		}
+	}
+
+	return uncle, ErrNotFound
+}
+
+func (m *Memory) TotalUncleCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.uncles)), nil
+}
+
+// Forked blocks
+
+func (m *Memory) ForkedBlockByNumber(number uint64) (models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, b := range m.forkedBlocks {
+		if b.Number == number {
+			return cloneBlock(b)
+		}
+	}
+
+	return models.Block{}, ErrNotFound
+}
+
+func (m *Memory) TotalForkedBlockCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.forkedBlocks)), nil
+}
+
+// Reorgs
+
+func (m *Memory) TotalReorgCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.reorgs)), nil
+}
+
+// Transactions
+
+func (m *Memory) TransactionByHash(hash string) (models.Transaction, error) {
+	txn, err := m.findTransaction(func(t *models.Transaction) bool { return t.Hash == hash })
+	if err != nil {
+		return txn, err
+	}
+
+	if err := decodeTransaction(&txn, m.contractABIs); err != nil {
+		log.Warn("couldn't decode transaction", "hash", hash, "err", err)
+	}
+
+	return txn, nil
+}
+
+func (m *Memory) findTransaction(match func(*models.Transaction) bool) (models.Transaction, error) {
+	var txn models.Transaction
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.transactions {
+		if match(t) {
+			err := clone(&txn, t)
+			return txn, err
+		}
+	}
+
+	return txn, ErrNotFound
+}
+
+func (m *Memory) TransactionsByBlockNumber(number uint64) ([]models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return cloneTransactions(filterTransactions(m.transactions, func(t *models.Transaction) bool { return t.BlockNumber == number }))
+}
+
+func (m *Memory) TransactionByContractAddress(address string) (models.Transaction, error) {
+	return m.findTransaction(func(t *models.Transaction) bool { return t.ContractAddress == address })
+}
+
+func (m *Memory) TxnCount(address string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(filterTransactions(m.transactions, func(t *models.Transaction) bool { return t.From == address || t.To == address }))), nil
+}
+
+func (m *Memory) ITxnCount(address string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count int64
+
+	for _, t := range m.itxns {
+		if t.From == address || t.To == address {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *Memory) TotalTxnCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.transactions)), nil
+}
+
+// filterTransactions returns the transactions that match, in the order they were written
+
+func filterTransactions(txns []*models.Transaction, match func(*models.Transaction) bool) []*models.Transaction {
+	matched := make([]*models.Transaction, 0)
+
+	for _, t := range txns {
+		if match(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	return matched
+}
+
+func cloneTransactions(txns []*models.Transaction) ([]models.Transaction, error) {
+	cloned := make([]models.Transaction, len(txns))
+
+	for i := range txns {
+		if err := clone(&cloned[i], txns[i]); err != nil {
+			return cloned, err
+		}
+	}
+
+	return cloned, nil
+}
+
+// limited returns how many of n documents a query with limit returns, a limit below 1 returns all of them like in mongo
+
+func limited(n int, limit int64) int {
+	if limit > 0 && limit < int64(n) {
+		return int(limit)
+	}
+
+	return n
+}
+
+// Tx trace
+
+func (m *Memory) TxTrace(hash string) (models.ITransaction, error) {
+	txn, err := m.findTransaction(func(t *models.Transaction) bool { return t.Hash == hash })
+
+	return txn.Trace, err
+}
+
+func (m *Memory) LatestTxTrace() (models.ITransaction, error) {
+	var trace models.ITransaction
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var latest *models.ITransaction
+
+	for _, t := range m.itxns {
+		if latest == nil || t.BlockNumber >= latest.BlockNumber {
+			latest = t
+		}
+	}
+
+	if latest == nil {
+		return trace, ErrNotFound
+	}
+
+	err := clone(&trace, latest)
+	return trace, err
+}
+
+// Contracts
+
+func (m *Memory) TotalContractCallsCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.calls)), nil
+}
+
+func (m *Memory) TotalContractsDeployedCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.contracts)), nil
+}
+
+// Token transfers
+
+func (m *Memory) TransfersOfTokenByAccount(token string, account string, page *Page) (map[string]interface{}, error) {
+	return m.latestTokenTransfers(func(t *models.TokenTransfer) bool {
+		return t.Contract == token && (t.From == account || t.To == account)
+	}, page)
+}
+
+func (m *Memory) TransfersOfTokenByAccountCount(token string, account string) (int64, error) {
+	return m.tokenTransferCount(func(t *models.TokenTransfer) bool {
+		return t.Contract == token && (t.From == account || t.To == account)
+	})
+}
+
+func (m *Memory) TokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return m.LatestTokenTransfersByAccount(account, page)
+}
+
+func (m *Memory) TokenTransfersByAccountCount(account string) (int64, error) {
+	return m.tokenTransferCount(func(t *models.TokenTransfer) bool { return t.From == account || t.To == account })
+}
+
+func (m *Memory) TransfersByContract(address string, page *Page) (map[string]interface{}, error) {
+	return m.LatestTransfersOfToken(address, page)
+}
+
+func (m *Memory) ContractTransferCount(address string) (int64, error) {
+	return m.tokenTransferCount(func(t *models.TokenTransfer) bool { return t.Contract == address })
+}
+
+func (m *Memory) TotalTransferCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.transfers)), nil
+}
+
+func (m *Memory) tokenTransferCount(match func(*models.TokenTransfer) bool) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count int64
+
+	for _, t := range m.transfers {
+		if match(t) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// NFT transfers
+
+func (m *Memory) NFTOwner(contract string, tokenId string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var latest *models.NFTTransfer
+
+	for _, t := range m.nftTransfers {
+		if t.Contract != contract || t.TokenId != tokenId || t.Standard != models.ERC721 {
+			continue
+		}
+
+		if latest == nil || t.BlockNumber > latest.BlockNumber || (t.BlockNumber == latest.BlockNumber && t.LogIndex > latest.LogIndex) {
+			latest = t
+		}
+	}
+
+	if latest == nil {
+		return "", ErrNotFound
+	}
+
+	return latest.To, nil
+}
+
+func (m *Memory) TotalNFTTransferCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.nftTransfers)), nil
+}
+
+// Tokens
+
+func (m *Memory) TokenInfo(address string) (models.Token, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if t, ok := m.tokens[address]; ok && t.IsToken {
+		return *t, nil
+	}
+
+	return models.Token{}, ErrNotFound
+}
+
+func (m *Memory) TotalTokenCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count int64
+
+	for _, t := range m.tokens {
+		if t.IsToken {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// TokenCandidates returns the addresses of contracts that may be tokens and are due a check, see MongoDB.TokenCandidates
+
+func (m *Memory) TokenCandidates(before int64) ([]string, error) {
+	var (
+		candidates = make([]string, 0)
+		known      = make(map[string]bool)
+	)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.tokens {
+		if t.Updated >= before {
+			known[t.Address] = true
+		}
+	}
+
+	add := func(address string) {
+		if address != "" && !known[address] {
+			known[address] = true
+			candidates = append(candidates, address)
+		}
+	}
+
+	for _, t := range m.transfers {
+		add(t.Contract)
+	}
+
+	for _, c := range m.contracts {
+		add(c.ContractAddress)
+	}
+
+	return candidates, nil
+}
+
+// Charts
+
+func (m *Memory) GetNumberChart(name string, limit int) (models.NumberChart, error) {
+	var chart models.NumberChart
+
+	if err := m.chart(name, &chart); err != nil {
+		return models.NumberChart{}, err
+	}
+
+	limitNumberChart(&chart, limit)
+
+	return chart, nil
+}
+
+func (m *Memory) GetNumberStringChart(name string, limit int) (models.NumberStringChart, error) {
+	var chart models.NumberStringChart
+
+	if err := m.chart(name, &chart); err != nil {
+		return models.NumberStringChart{}, err
+	}
+
+	limitNumberStringChart(&chart, limit)
+
+	return chart, nil
+}
+
+func (m *Memory) GetMultiSeriesChart(name string, limit int) (models.MultiSeriesChart, error) {
+	var chart models.MultiSeriesChart
+
+	if err := m.chart(name, &chart); err != nil {
+		return models.MultiSeriesChart{}, err
+	}
+
+	limitMultiSeriesChart(&chart, limit)
+
+	return chart, nil
+}
+
+func (m *Memory) chart(name string, chart interface{}) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	raw, ok := m.charts[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	return bson.Unmarshal(raw, chart)
+}
+
+func (m *Memory) ListCharts() ([]string, error) {
+	var result []string
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name := range m.charts {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// Contracts
+
+func (m *Memory) ContractCreation(address string) (models.ContractCreation, error) {
+	var creation models.ContractCreation
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	latest := m.latestCreation(address)
+	if latest == nil {
+		return creation, ErrNotFound
+	}
+
+	err := clone(&creation, latest)
+	return creation, err
+}
+
+func (m *Memory) latestCreation(address string) *models.ContractCreation {
+	var latest *models.ContractCreation
+
+	for _, c := range m.creations {
+		if c.Address == address && (latest == nil || c.BlockNumber >= latest.BlockNumber) {
+			latest = c
+		}
+	}
+
+	return latest
+}
+
+func (m *Memory) ContractsByCreator(creator string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.contractCreations(func(c *models.ContractCreation) bool { return c.Creator == creator }, page)
+}
+
+func (m *Memory) ContractsWithSameCode(address string, page *Page) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	creation := m.latestCreation(address)
+	if creation == nil {
+		return map[string]interface{}{}, ErrNotFound
+	}
+
+	return m.contractCreations(func(c *models.ContractCreation) bool { return c.CodeHash == creation.CodeHash && c.Address != address }, page)
+}
+
+// contractCreations returns a page of the creations that match, newest first and without their code
+
+func (m *Memory) contractCreations(match func(*models.ContractCreation) bool, page *Page) (map[string]interface{}, error) {
+	matched := make([]*models.ContractCreation, 0)
+
+	for _, c := range m.creations {
+		if match(c) {
+			matched = append(matched, c)
+		}
+	}
+
+	result, err := memoryPage(page, creationOrder, matched, "contracts", &[]models.ContractCreation{}, int64(len(matched)))
+	if err != nil {
+		return result, err
+	}
+
+	creations := result["contracts"].([]models.ContractCreation)
+	for i := range creations {
+		creations[i].Code = ""
+	}
+
+	return result, nil
+}
+
+// Accounts
+
+func (m *Memory) TotalAccountCount() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.accounts)), nil
+}
+
+func (m *Memory) BalanceAt(account string, block uint64) (models.BalanceRecord, error) {
+	var record models.BalanceRecord
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.balances[account]
+
+	i := sort.Search(len(history), func(i int) bool { return history[i].Block > block })
+	if i == 0 {
+		return record, ErrNotFound
+	}
+
+	err := clone(&record, history[i-1])
+	return record, err
+}
+
+// ABIs
+
+func (m *Memory) ContractABI(address string) (models.ContractABI, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if a, ok := m.abis[strings.ToLower(address)]; ok {
+		return *a, nil
+	}
+
+	return models.ContractABI{}, ErrNotFound
+}
+
+func (m *Memory) DecodeTransaction(hash string) (models.DecodedTransaction, error) {
+	return decodedTransaction(hash, m.TransactionByHash)
+}
+
+func (m *Memory) contractABIs(addresses []string) (map[string]*abi.ABI, error) {
+	var stored []models.ContractABI
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range abiAddresses(addresses) {
+		if s, ok := m.abis[a]; ok {
+			stored = append(stored, *s)
+		}
+	}
+
+	return parseABIs(stored), nil
+}
+
+func (m *Memory) VerifiedContract(address string) (models.VerifiedContract, error) {
+	var c models.VerifiedContract
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v, ok := m.verified[strings.ToLower(address)]
+	if !ok {
+		return c, ErrNotFound
+	}
+
+	err := clone(&c, v)
+	return c, err
+}
+
+// Signatures
+
+func (m *Memory) LookupSelector(hash string) ([]models.Signature, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.lookupSelector(strings.ToLower(hash)), nil
+}
+
+// lookupSelector returns the signatures of hash, built in first and then in import order
+
+func (m *Memory) lookupSelector(hash string) []models.Signature {
+	sigs := make([]models.Signature, 0)
+
+	for _, s := range m.signatures {
+		if s.Hash == hash {
+			sigs = append(sigs, *s)
+		}
+	}
+
+	sort.SliceStable(sigs, func(i, j int) bool { return sigs[i].Builtin && !sigs[j].Builtin })
+
+	return sigs
+}
+
+func (m *Memory) MethodSignature(input string) (string, error) {
+	if len(input) < 10 {
+		return "", nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if sigs := m.lookupSelector(strings.ToLower(input[:10])); len(sigs) > 0 {
+		return sigs[0].Text, nil
+	}
+
+	return "", nil
+}
+
+// numericLess compares decimal strings by value, like numericCollation
+
+func numericLess(a, b string) bool {
+	x, okX := new(big.Int).SetString(a, 10)
+	y, okY := new(big.Int).SetString(b, 10)
+
+	if !okX || !okY {
+		return a < b
+	}
+
+	return x.Cmp(y) < 0
+}
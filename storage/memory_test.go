@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+func testMemory(t *testing.T) *Memory {
+	m := NewMemory(&Config{Symbol: "UBQ", Type: BackendMemory})
+
+	genesis := testGenesis()
+
+	if err := m.CommitBlock(genesis); err != nil {
+		t.Fatal("couldn't commit genesis", err)
+	}
+
+	m.store = newStore(m.symbol, genesis.Block.Supply)
+
+	return m
+}
+
+func testGenesis() *Batch {
+	return &Batch{Block: &models.Block{Number: 0, Hash: "0xb0", Supply: "100"}, Accounts: []*models.Account{{Address: "0xa", Balance: "100", Block: 0}}, Checkpoint: "blocks"}
+}
+
+// state reads back everything the api serves about the test batches
+
+func state(t *testing.T, b Backend) map[string]interface{} {
+	res := make(map[string]interface{})
+
+	calls := map[string]func() (interface{}, error){
+		"blocks":       func() (interface{}, error) { return b.LatestBlocks(nil) },
+		"txns":         func() (interface{}, error) { return b.LatestTransactionsByAccount("0xa", nil) },
+		"itxns":        func() (interface{}, error) { return b.LatestITransactionsByAccount("0xb", nil) },
+		"transfers":    func() (interface{}, error) { return b.LatestTokenTransfersByAccount("0xa", nil) },
+		"accounts":     func() (interface{}, error) { return b.AccountsByBalance(nil) },
+		"holders":      func() (interface{}, error) { return b.TokenHolders("0xtoken", nil) },
+		"historyA":     func() (interface{}, error) { return b.BalanceHistory("0xa", nil) },
+		"historyC":     func() (interface{}, error) { return b.BalanceHistory("0xc", nil) },
+		"uncleCount":   func() (interface{}, error) { return b.TotalUncleCount() },
+		"contracts":    func() (interface{}, error) { return b.TotalContractsDeployedCount() },
+		"calls":        func() (interface{}, error) { return b.TotalContractCallsCount() },
+		"checkpoint":   func() (interface{}, error) { cp, err := b.Checkpoint("blocks"); return cp.Hash, err },
+		"tokenBalance": func() (interface{}, error) { return b.TokenBalancesByAccount("0xc", nil) },
+	}
+
+	for name, call := range calls {
+		v, err := call()
+		if err != nil {
+			t.Fatal("couldn't read", name, err)
+		}
+		res[name] = v
+	}
+
+	return res
+}
+
+func TestMemoryRollbackMatchesFreshSync(t *testing.T) {
+	testRollbackMatchesFreshSync(t, testMemory(t))
+}
+
+func TestMemoryBulkWriter(t *testing.T) {
+	testBulkWriter(t, testMemory(t))
+}
+
+func TestMemoryReads(t *testing.T) {
+	testReads(t, testMemory(t))
+}
+
+func TestMemoryPaging(t *testing.T) {
+	testPaging(t, testMemory(t))
+}
+
+func TestMemoryVerifiedABI(t *testing.T) {
+	testVerifiedABI(t, testMemory(t))
+}
+
+func TestMemoryDocumentsAreCopied(t *testing.T) {
+	m := testMemory(t)
+
+	b := testBatch(1, map[string]string{"0xa": "90"})
+
+	if err := m.CommitBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Transactions[0].From = "0xchanged"
+
+	txn, err := m.TransactionByHash("0xt1")
+	if err != nil || txn.From != "0xa" {
+		t.Error("stored transaction changed with the batch", txn.From, err)
+	}
+
+	txn.To = "0xchanged"
+
+	if again, _ := m.TransactionByHash("0xt1"); again.To != "0xc1" {
+		t.Error("stored transaction changed with a read", again.To)
+	}
+}
+
+// The tests below take a backend with only testGenesis committed, so they can run against every backend
+
+func testRollbackMatchesFreshSync(t *testing.T, m Backend) {
+
+	if err := m.CommitBlock(testBatch(1, map[string]string{"0xa": "90", "0xb": "10"})); err != nil {
+		t.Fatal("couldn't commit block 1", err)
+	}
+
+	fresh := state(t, m)
+
+	if err := m.CommitBlock(testBatch(2, map[string]string{"0xa": "80", "0xb": "5", "0xc": "15"})); err != nil {
+		t.Fatal("couldn't commit block 2", err)
+	}
+
+	if err := m.CommitBlock(testBatch(2, nil)); err == nil {
+		t.Error("committed block 2 twice")
+	}
+
+	orphaned, err := m.Rollback(1, "blocks")
+	if err != nil {
+		t.Fatal("couldn't roll back", err)
+	}
+
+	if len(orphaned) != 1 || orphaned[0].Hash != "0xb2" {
+		t.Error("unexpected rolled back blocks", orphaned)
+	}
+
+	if forked, err := m.ForkedBlockByNumber(2); err != nil || forked.Hash != "0xb2" {
+		t.Error("rolled back block not in forkedblocks", forked.Hash, err)
+	}
+
+	if after := state(t, m); !reflect.DeepEqual(fresh, after) {
+		t.Error("db after rollback doesn't match fresh sync", "\nfresh: ", fresh, "\nafter: ", after)
+	}
+
+	if err := m.CommitBlock(testBatch(2, map[string]string{"0xa": "70", "0xd": "1"})); err != nil {
+		t.Fatal("couldn't commit block 2", err)
+	}
+
+	if err := m.SetCheckpoint("blocks", 1, "0xb1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.PurgeBlock(2); err != nil {
+		t.Fatal("couldn't purge block", err)
+	}
+
+	if after := state(t, m); !reflect.DeepEqual(fresh, after) {
+		t.Error("db after purge doesn't match fresh sync", "\nfresh: ", fresh, "\nafter: ", after)
+	}
+
+	if _, err := m.Rollback(5, "blocks"); err != ErrNotFound {
+		t.Error("rolled back to a missing block", err)
+	}
+}
+
+func testBulkWriter(t *testing.T, m Backend) {
+	w := m.NewBulkWriter(2, time.Hour)
+
+	if err := w.Add(testBatch(1, map[string]string{"0xa": "90"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.BlockByNumber(1); err != ErrNotFound {
+		t.Error("pending block is visible", err)
+	}
+
+	if err := w.Add(testBatch(2, map[string]string{"0xa": "70"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Pending() != 0 {
+		t.Error("writer didn't flush when full", w.Pending())
+	}
+
+	if cp, err := m.Checkpoint("blocks"); err != nil || cp.Number != 2 {
+		t.Error("checkpoint not moved to the last flushed block", cp, err)
+	}
+
+	record, err := m.BalanceAt("0xa", 2)
+	if err != nil || record.Balance != "70" || record.Delta != "-20" {
+		t.Error("unexpected balance record", record, err)
+	}
+
+	if record, err := m.BalanceAt("0xa", 1); err != nil || record.Delta != "-10" {
+		t.Error("unexpected balance record", record, err)
+	}
+}
+
+func testReads(t *testing.T, m Backend) {
+	b := testBatch(1, map[string]string{"0xa": "9", "0xb": "10"})
+	b.Block.Timestamp = 100
+	b.Creations = []*models.ContractCreation{
+		{Address: "0xc1", Creator: "0xa", BlockNumber: 1, Code: "0x60", CodeHash: "0xh"},
+		{Address: "0xc2", Creator: "0xc1", BlockNumber: 1, Code: "0x60", CodeHash: "0xh", Internal: true},
+	}
+
+	if err := m.CommitBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.AddToken(&models.Token{Address: "0xtoken", Symbol: "TKN", TotalSupply: "20", IsToken: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := m.TokenHolders("0xtoken", &Page{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// balances are compared by value, not as strings
+	if holders := res["holders"].([]models.TokenHolder); len(holders) != 1 || holders[0].Address != "0xb" || holders[0].Percentage != 50 || res["total"] != int64(2) {
+		t.Error("unexpected token holders", res)
+	}
+
+	res, err = m.ContractsWithSameCode("0xc1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if same := res["contracts"].([]models.ContractCreation); len(same) != 1 || same[0].Address != "0xc2" || same[0].Code != "" {
+		t.Error("unexpected contracts with the same code", res)
+	}
+
+	if _, err := m.ImportSignatures([]models.Signature{{Hash: "0xa9059cbb", Text: "transfer_imported(address,uint256)"}, {Hash: "0xa9059cbb", Text: "transfer(address,uint256)", Builtin: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if added, _ := m.ImportSignatures([]models.Signature{{Hash: "0xa9059cbb", Text: "transfer(address,uint256)", Builtin: true}}); added != 0 {
+		t.Error("signature imported twice")
+	}
+
+	if sig, err := m.MethodSignature("0xa9059cbb0000"); err != nil || sig != "transfer(address,uint256)" {
+		t.Error("built in signature isn't the best guess", sig, err)
+	}
+
+	if err := m.AddNumberChart("blocks", []uint64{1, 2, 3}, []string{"a", "b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if chart, err := m.GetNumberChart("blocks", 0); err != nil || !reflect.DeepEqual(chart.Series, []uint64{1, 2, 3}) {
+		t.Error("unexpected chart", chart, err)
+	}
+
+	it, err := m.IterBlocks(0, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close(context.Background())
+
+	var numbers []uint64
+
+	for it.Next(context.Background()) {
+		var block models.Block
+
+		if err := it.Decode(&block); err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, block.Number)
+	}
+
+	// the genesis block has no timestamp, so it's out of range
+	if it.Err() != nil || !reflect.DeepEqual(numbers, []uint64{1}) {
+		t.Error("unexpected blocks from iterator", numbers, it.Err())
+	}
+
+	if err := m.UpdateStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := m.Status(); err != nil || status.TotalTransactions != 3 || status.LatestBlock.Number != 1 {
+		t.Error("unexpected store", status, err)
+	}
+}
+
+func testPaging(t *testing.T, m Backend) {
+	// balances sort by value, ties by address, and 0xa has 100 since genesis
+	balances := []string{"100", "9", "10", "10", "1000"}
+
+	for i, balance := range balances {
+		n := uint64(i + 1)
+		batch := testBatch(n, map[string]string{fmt.Sprintf("0x%d", n): balance})
+
+		// transfers indexed before log indexes were recorded all sit at 0, so pages split them within a block
+		for j := 0; j < 2; j++ {
+			transfer := *batch.TokenTransfers[0]
+			batch.TokenTransfers = append(batch.TokenTransfers, &transfer)
+		}
+
+		if err := m.CommitBlock(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// totals of whole collections are kept in the store
+	if err := m.UpdateStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	lists := map[string]func(page *Page) (map[string]interface{}, error){
+		"blocks":    m.LatestBlocks,
+		"uncles":    m.LatestUncles,
+		"contracts": m.LatestContractsDeployed,
+		"accounts":  m.AccountsByBalance,
+		"lastSeen":  m.AccountsByLastSeen,
+		"holders":   func(page *Page) (map[string]interface{}, error) { return m.TokenHolders("0xtoken", page) },
+		"transfers": func(page *Page) (map[string]interface{}, error) { return m.LatestTokenTransfersByAccount("0xa", page) },
+		"itxns":     func(page *Page) (map[string]interface{}, error) { return m.LatestITransactionsByAccount("0xb", page) },
+	}
+
+	names := map[string]string{"lastSeen": "accounts", "contracts": "txns"}
+
+	first := make(map[string]map[string]interface{})
+	next := make(map[string]string)
+	read := make(map[string][]interface{})
+
+	for list, call := range lists {
+		res, err := call(&Page{Limit: 2})
+		if err != nil {
+			t.Fatal("couldn't read", list, err)
+		}
+
+		first[list], next[list] = res, res["next"].(string)
+	}
+
+	// a block added in between comes before the cursors, or after for balances, as 0x6 has the most
+	if err := m.CommitBlock(testBatch(6, map[string]string{"0x6": "5000"})); err != nil {
+		t.Fatal(err)
+	}
+
+	for list, call := range lists {
+		name := names[list]
+		if name == "" {
+			name = list
+		}
+
+		res := first[list]
+
+		for {
+			items := reflect.ValueOf(res[name])
+			for i := 0; i < items.Len(); i++ {
+				read[list] = append(read[list], items.Index(i).Interface())
+			}
+
+			if res["total"] != first[list]["total"] {
+				t.Error(list, "total changed from page to page", res["total"], first[list]["total"])
+			}
+
+			if next[list] == "" {
+				break
+			}
+
+			var err error
+			if res, err = call(&Page{Limit: 2, Cursor: next[list]}); err != nil {
+				t.Fatal("couldn't read the next page of", list, err)
+			}
+
+			next[list] = res["next"].(string)
+		}
+
+		if int64(len(read[list])) != toInt64(first[list]["total"]) {
+			t.Error(list, "paged through", len(read[list]), "items of", first[list]["total"])
+		}
+	}
+
+	var order []string
+	for _, a := range read["accounts"] {
+		order = append(order, a.(models.Account).Address)
+	}
+
+	if want := []string{"0x5", "0x1", "0xa", "0x3", "0x4", "0x2"}; !reflect.DeepEqual(order, want) {
+		t.Error("accounts paged out of order", order, want)
+	}
+
+	if res, err := m.LatestBlocks(&Page{Cursor: "!"}); err != ErrInvalidCursor {
+		t.Error("invalid cursor was taken", res, err)
+	}
+}
+
+func toInt64(total interface{}) int64 {
+	return reflect.ValueOf(total).Int()
+}
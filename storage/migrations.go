@@ -0,0 +1,422 @@
+package storage
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// mongoSchema tells the schema document apart from the store and the checkpoints in sysstores
+	mongoSchema = "mongo"
+
+	backfillBatchSize = 1000
+)
+
+// mongoMigration is a step of the mongo schema. Steps are applied in order and the version of the last one is
+// kept in sysstores. There's no lock, so a crawler and an api starting at the same time may both run a step,
+// every step has to be safe to run again, or to resume after being interrupted
+
+type mongoMigration struct {
+	version int
+	name    string
+	run     func(m *MongoDB) error
+}
+
+var mongoMigrations = []mongoMigration{
+	{1, "create indexes", (*MongoDB).initIndexes},
+	{2, "backfill burned and totalBurned", (*MongoDB).backfillBurned},
+	{3, "create paging indexes", (*MongoDB).createPagingIndexes},
+	{4, "seed balance history from accounts", (*MongoDB).seedBalanceHistory},
+	{5, "flag verified abis", (*MongoDB).flagVerifiedABIs},
+}
+
+// schema returns the schema document, which is empty for a db that predates migrations
+
+func (m *MongoDB) schema(ctx context.Context) (models.Schema, error) {
+	var schema models.Schema
+
+	err := m.C(models.STORE).FindOne(ctx, bson.M{"schema": mongoSchema}, options.FindOne()).Decode(&schema)
+	if err == mongo.ErrNoDocuments {
+		return schema, nil
+	}
+
+	return schema, err
+}
+
+// SchemaVersion returns the version of the last migration applied, 0 for a db that predates migrations
+
+func (m *MongoDB) SchemaVersion() (int, error) {
+	schema, err := m.schema(context.Background())
+
+	return schema.Version, err
+}
+
+// Migrate applies the migrations the db doesn't have yet. It's run on every start, and on its own with -migrate
+
+func (m *MongoDB) Migrate() error {
+
+	version, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if latest := mongoMigrations[len(mongoMigrations)-1].version; version > latest {
+		log.Warn("mongo schema is newer than this build", "version", version, "latest", latest)
+		return nil
+	}
+
+	for _, step := range mongoMigrations {
+		if step.version <= version {
+			continue
+		}
+
+		log.Info("applying mongo migration", "version", step.version, "name", step.name)
+
+		start := time.Now()
+
+		if err := step.run(m); err != nil {
+			return err
+		}
+
+		if err := m.setSchemaVersion(step.version); err != nil {
+			return err
+		}
+
+		log.Info("applied mongo migration", "version", step.version, "name", step.name, "took", time.Since(start))
+	}
+
+	return nil
+}
+
+// setSchemaVersion only ever moves the version forward, in case another process finished a later step first
+
+func (m *MongoDB) setSchemaVersion(version int) error {
+	_, err := m.C(models.STORE).UpdateOne(context.Background(), bson.M{"schema": mongoSchema}, bson.D{
+		{"$max", bson.M{"version": version}},
+		{"$set", bson.M{"updated": time.Now().Unix()}},
+	}, options.Update().SetUpsert(true))
+
+	return err
+}
+
+// pagingIndexes cover the sorts of the paged lists in api.go, filter fields first, so a page is read off an index
+// wherever its cursor is
+
+var pagingIndexes = map[string][]mongo.IndexModel{
+	models.BLOCKS: {
+		{Keys: bson.D{{"miner", 1}, {"number", -1}}, Options: options.Index().SetName("blocksMinerPagingIndex")},
+	},
+	models.UNCLES: {
+		{Keys: bson.D{{"blockNumber", -1}, {"position", -1}}, Options: options.Index().SetName("unclesPagingIndex")},
+	},
+	models.FORKEDBLOCKS: {
+		{Keys: bson.D{{"number", -1}, {"hash", 1}}, Options: options.Index().SetName("forkedBlocksPagingIndex")},
+	},
+	models.REORGS: {
+		{Keys: bson.D{{"number", -1}, {"_id", 1}}, Options: options.Index().SetName("reorgsPagingIndex")},
+	},
+	models.TRANSACTIONS: {
+		{Keys: bson.D{{"blockNumber", -1}, {"transactionIndex", -1}}, Options: options.Index().SetName("txPagingIndex")},
+		{Keys: bson.D{{"from", 1}, {"blockNumber", -1}, {"transactionIndex", -1}}, Options: options.Index().SetName("txFromPagingIndex")},
+		{Keys: bson.D{{"to", 1}, {"blockNumber", -1}, {"transactionIndex", -1}}, Options: options.Index().SetName("txToPagingIndex")},
+		{Keys: bson.D{{"status", 1}, {"blockNumber", -1}, {"transactionIndex", -1}}, Options: options.Index().SetName("txFailedPagingIndex")},
+	},
+	models.CONTRACTS: {
+		{Keys: bson.D{{"blockNumber", -1}, {"transactionIndex", -1}}, Options: options.Index().SetName("contractPagingIndex")},
+	},
+	models.CONTRACTCALLS: {
+		{Keys: bson.D{{"blockNumber", -1}, {"transactionIndex", -1}}, Options: options.Index().SetName("contractCallsPagingIndex")},
+	},
+	models.ITRANSACTIONS: {
+		{Keys: bson.D{{"from", 1}, {"blockNumber", -1}, {"_id", 1}}, Options: options.Index().SetName("iTxFromPagingIndex")},
+		{Keys: bson.D{{"to", 1}, {"blockNumber", -1}, {"_id", 1}}, Options: options.Index().SetName("iTxToPagingIndex")},
+	},
+	models.TRANSFERS: {
+		{Keys: bson.D{{"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("trPagingIndex")},
+		{Keys: bson.D{{"contract", 1}, {"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("trContractPagingIndex")},
+		{Keys: bson.D{{"from", 1}, {"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("trFromPagingIndex")},
+		{Keys: bson.D{{"to", 1}, {"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("trToPagingIndex")},
+	},
+	models.NFTTRANSFERS: {
+		{Keys: bson.D{{"contract", 1}, {"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("nftContractPagingIndex")},
+		{Keys: bson.D{{"from", 1}, {"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("nftFromPagingIndex")},
+		{Keys: bson.D{{"to", 1}, {"blockNumber", -1}, {"logIndex", -1}, {"_id", 1}}, Options: options.Index().SetName("nftToPagingIndex")},
+	},
+	models.TOKENS: {
+		{Keys: bson.D{{"isToken", 1}, {"symbol", 1}, {"address", 1}}, Options: options.Index().SetName("tokensPagingIndex")},
+	},
+	models.TOKENBALANCES: {
+		{Keys: bson.D{{"contract", 1}, {"balance", -1}, {"address", 1}}, Options: options.Index().SetName("tokenBalancesPagingIndex").SetCollation(numericCollation)},
+		{Keys: bson.D{{"address", 1}, {"contract", 1}}, Options: options.Index().SetName("tokenBalancesAddressPagingIndex")},
+	},
+	models.ACCOUNTS: {
+		{Keys: bson.D{{"balance", -1}, {"address", 1}}, Options: options.Index().SetName("accountsBalancePagingIndex").SetCollation(numericCollation)},
+		{Keys: bson.D{{"block", -1}, {"address", 1}}, Options: options.Index().SetName("accountsBlockPagingIndex")},
+	},
+	models.CREATIONS: {
+		{Keys: bson.D{{"creator", 1}, {"blockNumber", -1}, {"address", 1}}, Options: options.Index().SetName("creationsCreatorPagingIndex")},
+		{Keys: bson.D{{"codeHash", 1}, {"blockNumber", -1}, {"address", 1}}, Options: options.Index().SetName("creationsCodeHashPagingIndex")},
+	},
+}
+
+// createPagingIndexes builds the indexes of paged lists, which takes a while on a synced db. Indexes that are
+// already there are left as they are
+
+func (m *MongoDB) createPagingIndexes() error {
+	for coll, indexes := range pagingIndexes {
+		if _, err := m.C(coll).Indexes().CreateMany(context.Background(), indexes, options.CreateIndexes()); err != nil {
+			return err
+		}
+
+		log.Info("created paging indexes", "collection", coll, "indexes", len(indexes))
+	}
+
+	return nil
+}
+
+// backfill hands the documents of coll matching filter to update, backfillBatchSize at a time in order of key,
+// which has to be unique and indexed. Each batch is written before the next one is read, so documents may stop
+// matching filter once updated, and an interrupted backfill carries on from where it stopped when it's run again
+
+func (m *MongoDB) backfill(coll string, filter bson.M, key string, projection bson.M, update func(docs []bson.Raw) ([]mongo.WriteModel, error)) error {
+
+	var (
+		ctx     = context.Background()
+		last    interface{}
+		written int
+	)
+
+	if projection != nil {
+		projection[key] = 1
+	}
+
+	for {
+		page := filter
+		if last != nil {
+			page = bson.M{"$and": bson.A{filter, bson.M{key: bson.M{"$gt": last}}}}
+		}
+
+		cursor, err := m.C(coll).Find(ctx, page, options.Find().SetSort(bson.M{key: 1}).SetLimit(backfillBatchSize).SetProjection(projection))
+		if err != nil {
+			return err
+		}
+
+		var docs []bson.Raw
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+
+		if len(docs) == 0 {
+			return nil
+		}
+
+		writes, err := update(docs)
+		if err != nil {
+			return err
+		}
+
+		if len(writes) > 0 {
+			if _, err := m.C(coll).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+		}
+
+		written += len(writes)
+		last = docs[len(docs)-1].Lookup(key)
+
+		log.Info("backfilling", "collection", coll, "written", written, key, last)
+	}
+}
+
+// backfillBurned fills in burned and totalBurned for blocks written before they were tracked, in order so each
+// total carries on from the parent block. Blocks that already have them are left alone, the crawler counted
+// their missing parents as burning nothing, which holds for every block before london
+
+func (m *MongoDB) backfillBurned() error {
+
+	var (
+		total  = new(big.Int)
+		parent = int64(-1)
+	)
+
+	missing := bson.M{"$or": bson.A{
+		bson.M{"burned": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"totalBurned": bson.M{"$in": bson.A{nil, ""}}},
+	}}
+
+	projection := bson.M{"gasUsed": 1, "baseFeePerGas": 1, "burned": 1}
+
+	return m.backfill(models.BLOCKS, missing, "number", projection, func(docs []bson.Raw) ([]mongo.WriteModel, error) {
+		writes := make([]mongo.WriteModel, 0, len(docs))
+
+		for _, raw := range docs {
+			var b models.Block
+			if err := bson.Unmarshal(raw, &b); err != nil {
+				return nil, err
+			}
+
+			// the total is read from the db again after a gap, or for the first block
+			if int64(b.Number)-1 != parent {
+				t, err := m.totalBurnedAt(b.Number)
+				if err != nil {
+					return nil, err
+				}
+				total = t
+			}
+
+			burned, ok := new(big.Int).SetString(b.Burned, 10)
+			if !ok {
+				burned = blockBurned(b.GasUsed, b.BaseFeePerGas)
+			}
+
+			total.Add(total, burned)
+			parent = int64(b.Number)
+
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"number": b.Number}).SetUpdate(bson.D{{"$set", bson.M{
+				"burned":      burned.String(),
+				"totalBurned": total.String(),
+			}}}))
+		}
+
+		return writes, nil
+	})
+}
+
+// totalBurnedAt returns the totalBurned of the parent of block number, 0 for genesis or a parent without one
+
+func (m *MongoDB) totalBurnedAt(number uint64) (*big.Int, error) {
+	if number == 0 {
+		return new(big.Int), nil
+	}
+
+	var parent models.Block
+
+	err := m.C(models.BLOCKS).FindOne(context.Background(), bson.M{"number": number - 1}, options.FindOne().SetProjection(bson.M{"totalBurned": 1})).Decode(&parent)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if total, ok := new(big.Int).SetString(parent.TotalBurned, 10); ok {
+		return total, nil
+	}
+
+	return new(big.Int), nil
+}
+
+// blockBurned is the base fee burned by a block, 0 before london when blocks have no base fee
+
+func blockBurned(gasUsed uint64, baseFeePerGas string) *big.Int {
+	baseFee, ok := new(big.Int).SetString(baseFeePerGas, 10)
+	if !ok {
+		return new(big.Int)
+	}
+
+	return baseFee.Mul(baseFee, new(big.Int).SetUint64(gasUsed))
+}
+
+// seedBalanceHistory gives accounts written before balance history was recorded a record at the block they were
+// last seen, so rolling back later blocks restores them instead of removing them. The block history starts
+// from is kept in the schema, and is set once so a step that's run again seeds the same accounts
+
+func (m *MongoDB) seedBalanceHistory() error {
+	ctx := context.Background()
+
+	var from uint64
+
+	if head, err := m.LatestBlock(); err == nil {
+		from = head.Number + 1
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	// $min only sets it when it's missing, or lower
+	if _, err := m.C(models.STORE).UpdateOne(ctx, bson.M{"schema": mongoSchema}, bson.M{"$min": bson.M{"balanceHistoryFrom": from}}, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	schema, err := m.schema(ctx)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := m.C(models.ACCOUNTS).Find(ctx, bson.M{"block": bson.M{"$lt": schema.BalanceHistoryFrom}}, options.Find())
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		writes  = make([]mongo.WriteModel, 0, backfillBatchSize)
+		written int
+	)
+
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+
+		if _, err := m.C(models.BALANCES).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+
+		written += len(writes)
+		writes = writes[:0]
+
+		log.Info("seeding balance history", "written", written)
+
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var a models.Account
+		if err := cursor.Decode(&a); err != nil {
+			return err
+		}
+
+		// records the crawler wrote for the same block are left as they are
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"address": a.Address, "block": a.Block}).
+			SetUpdate(bson.M{"$setOnInsert": &models.BalanceRecord{Address: a.Address, Block: a.Block, Balance: a.Balance, Delta: a.Balance, Nonce: a.Nonce, HasCode: a.HasCode}}).
+			SetUpsert(true))
+
+		if len(writes) == backfillBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+// flagVerifiedABIs marks the ABIs of verified contracts, which were stored like any upload before, so uploads
+// can't replace them
+
+func (m *MongoDB) flagVerifiedABIs() error {
+	ctx := context.Background()
+
+	addresses, err := m.C(models.VERIFIED).Distinct(ctx, "address", bson.M{}, options.Distinct())
+	if err != nil || len(addresses) == 0 {
+		return err
+	}
+
+	r, err := m.C(models.ABIS).UpdateMany(ctx, bson.M{"address": bson.M{"$in": addresses}}, bson.M{"$set": bson.M{"verified": true}}, options.Update())
+	if err != nil {
+		return err
+	}
+
+	log.Info("flagged verified abis", "count", r.ModifiedCount)
+
+	return nil
+}
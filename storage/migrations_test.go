@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMigrateBackfillsBurned(t *testing.T) {
+	m := testDB(t)
+
+	// blocks from before burned was tracked, and one written since on top of them
+	blocks := []interface{}{
+		bson.M{"number": int64(0), "hash": "0xb0"},
+		bson.M{"number": int64(1), "hash": "0xb1", "gasUsed": int64(10), "baseFeePerGas": ""},
+		bson.M{"number": int64(2), "hash": "0xb2", "gasUsed": int64(10), "baseFeePerGas": "3", "burned": "30"},
+		bson.M{"number": int64(3), "hash": "0xb3", "gasUsed": int64(10), "baseFeePerGas": "", "burned": "0", "totalBurned": "0"},
+		bson.M{"number": int64(4), "hash": "0xb4", "gasUsed": int64(2), "baseFeePerGas": "5"},
+	}
+
+	if _, err := m.C(models.BLOCKS).InsertMany(context.Background(), blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	if version, err := m.SchemaVersion(); err != nil || version != 0 {
+		t.Fatal("unmigrated db has a schema version", version, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.Migrate(); err != nil {
+			t.Fatal("couldn't migrate", err)
+		}
+	}
+
+	if version, err := m.SchemaVersion(); err != nil || version != mongoMigrations[len(mongoMigrations)-1].version {
+		t.Error("schema version wasn't updated", version, err)
+	}
+
+	if !m.IsFirstRun() {
+		t.Error("the schema version was taken for a store")
+	}
+
+	want := map[uint64][2]string{
+		0: {"0", "0"},
+		1: {"0", "0"},
+		2: {"30", "30"},
+		3: {"0", "0"},
+		4: {"10", "10"},
+	}
+
+	for number, w := range want {
+		b, err := m.BlockByNumber(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b.Burned != w[0] || b.TotalBurned != w[1] {
+			t.Errorf("block %d burned %s total %s, want %s %s", number, b.Burned, b.TotalBurned, w[0], w[1])
+		}
+	}
+}
+
+func TestMigrateSeedsBalanceHistory(t *testing.T) {
+	m := testDB(t)
+
+	ctx := context.Background()
+
+	// an account and its block from before balance history was recorded
+	if _, err := m.C(models.BLOCKS).InsertOne(ctx, bson.M{"number": int64(1), "hash": "0xb1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.C(models.ACCOUNTS).InsertOne(ctx, &models.Account{Address: "0xa", Balance: "100", Block: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Migrate(); err != nil {
+		t.Fatal("couldn't migrate", err)
+	}
+
+	if err := m.CommitBlock(testBatch(2, map[string]string{"0xa": "90", "0xb": "10"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.PurgeFrom(2); err != nil {
+		t.Fatal(err)
+	}
+
+	account := func(address string) (a models.Account, err error) {
+		err = m.C(models.ACCOUNTS).FindOne(ctx, bson.M{"address": address}).Decode(&a)
+		return
+	}
+
+	if a, err := account("0xa"); err != nil || a.Balance != "100" {
+		t.Error("account from before the history wasn't restored", a, err)
+	}
+
+	if _, err := account("0xb"); err == nil {
+		t.Error("account first seen in a purged block wasn't removed")
+	}
+
+	// rolling back past the start of the history leaves accounts without a record as they are
+	if err := m.CommitBlock(testBatch(2, map[string]string{"0xa": "90"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.PurgeFrom(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := account("0xa"); err != nil {
+		t.Error("account from before the history was removed", err)
+	}
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Lists are paged with cursors rather than offsets. A cursor is the position of the last item of a page, so
+// the next page starts right after it however many blocks were added in between, and reading deep into a
+// list doesn't get slower the deeper it goes
+
+const (
+	// DefaultPageSize is the size of a page when none is asked for
+	DefaultPageSize = 100
+	// MaxPageSize caps the size of a page, longer lists have to be paged through
+	MaxPageSize = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page asks for up to Limit items following Cursor, which is the "next" of the previous page, or for the first
+// page when it's empty. The api takes either an object or a number, the limit, like before lists were paged
+
+type Page struct {
+	Limit  int64  `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+func (p *Page) UnmarshalJSON(data []byte) error {
+	var limit int64
+
+	if err := json.Unmarshal(data, &limit); err == nil {
+		*p = Page{Limit: limit}
+		return nil
+	}
+
+	type page Page
+
+	return json.Unmarshal(data, (*page)(p))
+}
+
+// cursor holds the position of an item in its list, which fields are set depends on the list's order. Cursors
+// handed out also carry the total of the first page, so it stays the same from page to page
+
+type cursor struct {
+	Number uint64 `json:"n,omitempty"`
+	Index  uint64 `json:"i,omitempty"`
+	Value  string `json:"v,omitempty"`
+	Key    string `json:"k,omitempty"`
+	Total  int64  `json:"t,omitempty"`
+}
+
+func (c cursor) String() string {
+	raw, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// order is how a list is sorted, by the field behind each slot of its cursors that's set: number, then index,
+// descending, then value, then key ascending. Value is sorted descending as a number instead when largest is
+// set. The last field has to be unique within the list so every item has its own position.
+// Field names are the ones of the backend, the memory backend uses the mongo ones
+
+type order struct {
+	number, index, value, key string
+	largest                   bool
+}
+
+// less tells whether an item at a sorts before one at b
+
+func (o order) less(a, b cursor) bool {
+	if o.number != "" && a.Number != b.Number {
+		return a.Number > b.Number
+	}
+
+	if o.index != "" && a.Index != b.Index {
+		return a.Index > b.Index
+	}
+
+	if o.value != "" {
+		if o.largest && numericLess(b.Value, a.Value) {
+			return true
+		}
+		if o.largest && numericLess(a.Value, b.Value) {
+			return false
+		}
+		if !o.largest && a.Value != b.Value {
+			return a.Value < b.Value
+		}
+	}
+
+	return o.key != "" && a.Key < b.Key
+}
+
+// position reads the position of a document from its fields
+
+func (o order) position(raw bson.Raw) cursor {
+	var c cursor
+
+	if v, err := raw.LookupErr(o.number); o.number != "" && err == nil {
+		n, _ := v.AsInt64OK()
+		c.Number = uint64(n)
+	}
+
+	if v, err := raw.LookupErr(o.index); o.index != "" && err == nil {
+		i, _ := v.AsInt64OK()
+		c.Index = uint64(i)
+	}
+
+	if v, err := raw.LookupErr(o.value); o.value != "" && err == nil {
+		c.Value, _ = v.StringValueOK()
+	}
+
+	if v, err := raw.LookupErr(o.key); o.key != "" && err == nil {
+		if id, ok := v.ObjectIDOK(); ok {
+			c.Key = id.Hex()
+		} else {
+			c.Key, _ = v.StringValueOK()
+		}
+	}
+
+	return c
+}
+
+// pager reads a page of a list. Backends read up to limit+1 items after the cursor, in order, and record the
+// position of each, the extra item only tells there's a next page
+
+type pager struct {
+	order
+	limit     int64
+	after     *cursor
+	positions []cursor
+}
+
+// newPager returns a pager for page, whose size is def if none was asked for
+
+func newPager(page *Page, o order, def int64) (*pager, error) {
+	p := &pager{order: o, limit: def}
+
+	if page == nil {
+		return p, nil
+	}
+
+	if page.Limit > 0 {
+		p.limit = page.Limit
+	}
+
+	if p.limit > MaxPageSize {
+		p.limit = MaxPageSize
+	}
+
+	if page.Cursor != "" {
+		after, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		p.after = after
+	}
+
+	return p, nil
+}
+
+// decode unmarshals docs into items, a pointer to a slice, and records their positions
+
+func (p *pager) decode(docs []bson.Raw, items interface{}) error {
+	v := reflect.ValueOf(items).Elem()
+	v.Set(reflect.MakeSlice(v.Type(), len(docs), len(docs)))
+
+	p.positions = make([]cursor, len(docs))
+
+	for i, raw := range docs {
+		if err := bson.Unmarshal(raw, v.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+
+		p.positions[i] = p.position(raw)
+	}
+
+	return nil
+}
+
+// total counts the items of the list for the first page, and returns the count carried by the cursor after that
+
+func (p *pager) total(count func() (int64, error)) (int64, error) {
+	if p.after != nil {
+		return p.after.Total, nil
+	}
+
+	return count()
+}
+
+// setResult puts items, a slice of what was read, in result under name along with the total and the cursor
+// of the next page, which is empty on the last one
+
+func (p *pager) setResult(result map[string]interface{}, name string, items interface{}, total int64) {
+	v := reflect.ValueOf(items)
+	next := ""
+
+	if int64(v.Len()) > p.limit {
+		v = v.Slice(0, int(p.limit))
+
+		last := p.positions[p.limit-1]
+		last.Total = total
+		next = last.String()
+	}
+
+	result[name] = v.Interface()
+	result["total"] = total
+	result["next"] = next
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPageUnmarshalJSON(t *testing.T) {
+	cases := map[string]Page{
+		`25`:                          {Limit: 25},
+		`{"limit":10,"cursor":"abc"}`: {Limit: 10, Cursor: "abc"},
+		`{"cursor":"abc"}`:            {Cursor: "abc"},
+	}
+
+	for in, want := range cases {
+		var page Page
+
+		if err := json.Unmarshal([]byte(in), &page); err != nil || page != want {
+			t.Errorf("unmarshalling %s gave %+v, want %+v (%v)", in, page, want, err)
+		}
+	}
+
+	c := cursor{Number: 7, Index: 2, Key: "0xa", Total: 40}
+
+	if decoded, err := decodeCursor(c.String()); err != nil || *decoded != c {
+		t.Error("cursor didn't round trip", decoded, err)
+	}
+}
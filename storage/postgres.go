@@ -0,0 +1,899 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/rpc"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// any number will do, as long as no other app takes the same advisory lock on the db
+const migrationLock = 0x5bec7
+
+// Postgres is a Backend on top of a PostgreSQL database, see postgresMigrations for the schema.
+// Every write of a batch, bulk window or rollback happens in a single transaction
+
+type Postgres struct {
+	symbol string
+	db     *sql.DB
+}
+
+func NewPostgres(cfg *Config) (*Postgres, error) {
+
+	if cfg.Symbol == "" {
+		return nil, errors.New("symbol not set")
+	}
+
+	db, err := sql.Open("postgres", cfg.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Postgres{cfg.Symbol, db}
+
+	if err := p.Migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Migrate applies the migrations the db doesn't have yet, it's run when connecting so the tables exist before
+// anything else. The advisory lock keeps a crawler and an api starting at the same time from applying the same step twice
+
+func (p *Postgres) Migrate() error {
+
+	_, err := p.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version integer PRIMARY KEY,
+		name    text NOT NULL,
+		applied bigint NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range postgresMigrations {
+		err := p.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+				return err
+			}
+
+			var applied bool
+
+			if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&applied); err != nil || applied {
+				return err
+			}
+
+			for _, stmt := range m.statements {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+
+			if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied) VALUES ($1, $2, $3)`, m.version, m.name, time.Now().Unix()); err != nil {
+				return err
+			}
+
+			log.Info("applied postgres migration", "version", m.version, "name", m.name)
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inTx runs fn in a transaction, which is committed if fn doesn't fail
+
+func (p *Postgres) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Documents are stored as relaxed extended json, which keeps the bson field names and types of the models.
+// They're passed as strings, as the driver sends []byte parameters as bytea
+
+func encodeDoc(v interface{}) (string, error) {
+	doc, err := bson.MarshalExtJSON(v, false, false)
+	if err != nil {
+		return "", err
+	}
+
+	return string(doc), nil
+}
+
+func decodeDoc(doc []byte, v interface{}) error {
+	return bson.UnmarshalExtJSON(doc, false, v)
+}
+
+// stripNulls removes NUL characters, which postgres refuses in text and jsonb. Mongo takes them, so they do show
+// up in strings decoded from the chain, e.g. padded token names. It's only for such display strings, raw chain
+// data is hex and never has any
+
+func stripNulls(s string) string {
+	return strings.Replace(s, "\x00", "", -1)
+}
+
+// numeric returns a decimal string for a numeric column, or NULL if it isn't one
+
+func numeric(s string) interface{} {
+	if _, ok := new(big.Int).SetString(s, 10); !ok {
+		return nil
+	}
+
+	return s
+}
+
+// queryer is either the db or a transaction
+
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// all decodes the documents returned by query into results, which must be a pointer to a slice
+
+func all(q queryer, results interface{}, query string, args ...interface{}) error {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	sv := reflect.ValueOf(results).Elem()
+	docs := reflect.MakeSlice(sv.Type(), 0, 0)
+
+	for rows.Next() {
+		var doc []byte
+
+		if err := rows.Scan(&doc); err != nil {
+			return err
+		}
+
+		v := reflect.New(sv.Type().Elem())
+		if err := decodeDoc(doc, v.Interface()); err != nil {
+			return err
+		}
+
+		docs = reflect.Append(docs, v.Elem())
+	}
+
+	sv.Set(docs)
+
+	return rows.Err()
+}
+
+// one decodes the first document returned by query into result, it returns ErrNotFound if there's none
+
+func one(q queryer, result interface{}, query string, args ...interface{}) error {
+	var doc []byte
+
+	err := q.QueryRow(query, args...).Scan(&doc)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return decodeDoc(doc, result)
+}
+
+func count(q queryer, query string, args ...interface{}) (int64, error) {
+	var n int64
+
+	err := q.QueryRow(query, args...).Scan(&n)
+	return n, err
+}
+
+// blockDoc selects the document of block b along with its transactions
+
+const blockDoc = `b.doc || jsonb_build_object('transactions', COALESCE((
+	SELECT jsonb_agg(t.doc ORDER BY t.transaction_index) FROM transactions t WHERE t.block_number = b.number
+), '[]'::jsonb))`
+
+func (p *Postgres) Init(rpc *rpc.RPCClient) {
+
+	genesis, err := genesisBatch(rpc)
+	if err != nil {
+		log.Error("could not retrieve genesis block", "err", err)
+		os.Exit(1)
+	}
+
+	if err := p.CommitBlock(genesis); err != nil {
+		log.Error("could not init supply block", "err", err)
+		os.Exit(1)
+	}
+
+	if err := p.setStore(newStore(p.symbol, genesis.Block.Supply)); err != nil {
+		log.Error("could not init sysStores", "err", err)
+		os.Exit(1)
+	}
+}
+
+func (p *Postgres) IsFirstRun() bool {
+	var exists bool
+
+	if err := p.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM store WHERE symbol = $1)`, p.symbol).Scan(&exists); err != nil {
+		log.Error("Error during initialization", "err", err)
+		return false
+	}
+
+	return !exists
+}
+
+func (p *Postgres) Ping() error {
+	return p.db.Ping()
+}
+
+func (p *Postgres) setStore(store *models.Store) error {
+	doc, err := encodeDoc(store)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(`INSERT INTO store (symbol, doc) VALUES ($1, $2) ON CONFLICT (symbol) DO UPDATE SET doc = excluded.doc`, store.Symbol, doc)
+	return err
+}
+
+// Writes
+
+// CommitBlock writes a batch in a single transaction, nothing is written if the block is already there
+
+func (p *Postgres) CommitBlock(b *Batch) error {
+	return p.inTx(func(tx *sql.Tx) error {
+		return p.writeBatch(tx, b)
+	})
+}
+
+func (p *Postgres) writeBatch(tx *sql.Tx, b *Batch) error {
+
+	// the block goes first, so a block that's already there fails on the unique keys before anything else is written
+	block := *b.Block
+	block.Transactions = nil
+
+	doc, err := encodeDoc(&block)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO blocks (number, hash, parent_hash, miner, timestamp, doc) VALUES ($1, $2, $3, $4, $5, $6)`,
+		block.Number, block.Hash, block.ParentHash, block.Miner, block.Timestamp, doc); err != nil {
+		return err
+	}
+
+	deploys := make(map[string]bool, len(b.Contracts))
+	for _, c := range b.Contracts {
+		deploys[c.Hash] = true
+	}
+
+	calls := make(map[string]bool, len(b.ContractCalls))
+	for _, c := range b.ContractCalls {
+		calls[c.Hash] = true
+	}
+
+	err = insertRows(tx, `INSERT INTO transactions (hash, block_number, transaction_index, timestamp, from_address, to_address, contract_address, value, status, contract_deploy, contract_call, doc)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`, len(b.Transactions), func(i int) ([]interface{}, error) {
+		t := b.Transactions[i]
+		doc, err := encodeDoc(t)
+		return []interface{}{t.Hash, t.BlockNumber, t.TransactionIndex, t.Timestamp, t.From, t.To, t.ContractAddress, numeric(t.Value), t.Status, deploys[t.Hash], calls[t.Hash], doc}, err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = insertRows(tx, `INSERT INTO internal_transactions (parent_hash, block_number, type, from_address, to_address, value, doc) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		len(b.ITransactions), func(i int) ([]interface{}, error) {
+			t := b.ITransactions[i]
+			doc, err := encodeDoc(t)
+			return []interface{}{t.ParentHash, t.BlockNumber, t.Type, t.From, t.To, numeric(t.Value), doc}, err
+		})
+	if err != nil {
+		return err
+	}
+
+	err = insertRows(tx, `INSERT INTO token_transfers (block_number, hash, log_index, timestamp, contract, from_address, to_address, value, doc) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		len(b.TokenTransfers), func(i int) ([]interface{}, error) {
+			t := b.TokenTransfers[i]
+			doc, err := encodeDoc(t)
+			return []interface{}{t.BlockNumber, t.Hash, t.LogIndex, t.Timestamp, t.Contract, t.From, t.To, numeric(t.Value), doc}, err
+		})
+	if err != nil {
+		return err
+	}
+
+	err = insertRows(tx, `INSERT INTO nft_transfers (block_number, hash, log_index, timestamp, contract, standard, token_id, from_address, to_address, doc) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		len(b.NFTTransfers), func(i int) ([]interface{}, error) {
+			t := b.NFTTransfers[i]
+			doc, err := encodeDoc(t)
+			return []interface{}{t.BlockNumber, t.Hash, t.LogIndex, t.Timestamp, t.Contract, t.Standard, t.TokenId, t.From, t.To, doc}, err
+		})
+	if err != nil {
+		return err
+	}
+
+	err = insertRows(tx, `INSERT INTO uncles (hash, block_number, number, miner, timestamp, doc) VALUES ($1, $2, $3, $4, $5, $6)`,
+		len(b.Uncles), func(i int) ([]interface{}, error) {
+			u := b.Uncles[i]
+			doc, err := encodeDoc(u)
+			return []interface{}{u.Hash, u.BlockNumber, u.Number, u.Miner, u.Timestamp, doc}, err
+		})
+	if err != nil {
+		return err
+	}
+
+	err = insertRows(tx, `INSERT INTO contract_creations (block_number, hash, address, creator, code_hash, internal, doc) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		len(b.Creations), func(i int) ([]interface{}, error) {
+			c := b.Creations[i]
+			doc, err := encodeDoc(c)
+			return []interface{}{c.BlockNumber, c.Hash, c.Address, c.Creator, c.CodeHash, c.Internal, doc}, err
+		})
+	if err != nil {
+		return err
+	}
+
+	if err := p.writeAccounts(tx, b.Accounts, b.Block.Number); err != nil {
+		return err
+	}
+
+	if err := p.writeTokenBalances(tx, b.TokenBalances); err != nil {
+		return err
+	}
+
+	if b.Checkpoint != "" {
+		return setCheckpoint(tx, b.Checkpoint, b.Block.Number, b.Block.Hash)
+	}
+
+	return nil
+}
+
+// insertRows runs a prepared insert with the values of each of n rows
+
+func insertRows(tx *sql.Tx, query string, n int, row func(i int) ([]interface{}, error)) error {
+	if n == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		values, err := row(i)
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.Exec(values...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeAccounts sets the current balance of accounts and appends them to their balance history, with the change
+// from their last balance below block
+
+func (p *Postgres) writeAccounts(tx *sql.Tx, accounts []*models.Account, block uint64) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	prev, err := lastBalances(tx, accountAddresses(accounts), block)
+	if err != nil {
+		return err
+	}
+
+	err = insertRows(tx, `INSERT INTO accounts (address, balance, block, doc) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (address) DO UPDATE SET balance = excluded.balance, block = excluded.block, doc = excluded.doc`, len(accounts), func(i int) ([]interface{}, error) {
+		a := accounts[i]
+		doc, err := encodeDoc(a)
+		return []interface{}{a.Address, numeric(a.Balance), a.Block, doc}, err
+	})
+	if err != nil {
+		return err
+	}
+
+	records := balanceRecords(accounts, prev)
+
+	return insertRows(tx, `INSERT INTO balance_history (address, block, balance, doc) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (address, block) DO UPDATE SET balance = excluded.balance, doc = excluded.doc`, len(records), func(i int) ([]interface{}, error) {
+		r := records[i]
+		doc, err := encodeDoc(r)
+		return []interface{}{r.Address, r.Block, numeric(r.Balance), doc}, err
+	})
+}
+
+// lastBalances returns the latest recorded balance below block for each of addresses, see MongoDB.lastBalances
+
+func lastBalances(q queryer, addresses []string, block uint64) (map[string]*big.Int, error) {
+	prev := make(map[string]*big.Int)
+
+	rows, err := q.Query(`SELECT DISTINCT ON (address) address, doc->>'balance' FROM balance_history
+		WHERE address = ANY($1) AND block < $2 ORDER BY address, block DESC`, pq.Array(addresses), block)
+	if err != nil {
+		return prev, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address, balance string
+
+		if err := rows.Scan(&address, &balance); err != nil {
+			return prev, err
+		}
+
+		if b, ok := new(big.Int).SetString(balance, 10); ok {
+			prev[address] = b
+		}
+	}
+
+	return prev, rows.Err()
+}
+
+// writeTokenBalances sets the balance of token holders in order, holders left with nothing are removed
+
+func (p *Postgres) writeTokenBalances(tx *sql.Tx, balances []*models.TokenBalance) error {
+	for _, tb := range balances {
+		if err := setTokenBalance(tx, tb); err != nil {
+			return err
+		}
+	}
+
+	return insertRows(tx, `INSERT INTO token_balance_history (contract, address, block, doc) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (contract, address, block) DO UPDATE SET doc = excluded.doc`, len(balances), func(i int) ([]interface{}, error) {
+		tb := balances[i]
+		doc, err := encodeDoc(tb)
+		return []interface{}{tb.Contract, tb.Address, tb.Block, doc}, err
+	})
+}
+
+func setTokenBalance(q queryer, tb *models.TokenBalance) error {
+	if tb.Balance == "0" {
+		_, err := q.Exec(`DELETE FROM token_balances WHERE contract = $1 AND address = $2`, tb.Contract, tb.Address)
+		return err
+	}
+
+	doc, err := encodeDoc(tb)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`INSERT INTO token_balances (contract, address, balance, block, doc) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (contract, address) DO UPDATE SET balance = excluded.balance, block = excluded.block, doc = excluded.doc`,
+		tb.Contract, tb.Address, numeric(tb.Balance), tb.Block, doc)
+	return err
+}
+
+// NewBulkWriter returns a writer that commits its pending batches in a single transaction every size blocks,
+// or once the oldest one has waited for interval. Unlike BulkWriter a failed flush leaves nothing behind
+
+func (p *Postgres) NewBulkWriter(size int, interval time.Duration) BatchWriter {
+	return newWindowWriter(size, interval, func(pending []*Batch) error {
+		start := time.Now()
+
+		err := p.inTx(func(tx *sql.Tx) error {
+			for _, b := range pending {
+				if err := p.writeBatch(tx, b); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err == nil {
+			log.Debug("flushed bulk writes", "blocks", len(pending), "head", pending[len(pending)-1].Block.Number, "took", time.Since(start))
+		}
+
+		return err
+	})
+}
+
+func (p *Postgres) PurgeBlock(height uint64) error {
+	return p.PurgeFrom(height)
+}
+
+// PurgeFrom removes every block from height up, see MongoDB.PurgeFrom
+
+func (p *Postgres) PurgeFrom(height uint64) error {
+	return p.inTx(func(tx *sql.Tx) error {
+		return purgeFrom(tx, height)
+	})
+}
+
+// purgeFrom removes the blocks from height up, the documents derived from them go with them through their
+// foreign keys. Accounts and token holders go back to their last balance below height, or away if they had none
+
+func purgeFrom(tx *sql.Tx, height uint64) error {
+
+	r, err := tx.Exec(`DELETE FROM blocks WHERE number >= $1`, height)
+	if err != nil {
+		return err
+	}
+
+	purged, _ := r.RowsAffected()
+
+	_, err = tx.Exec(`WITH purged AS (
+		DELETE FROM balance_history WHERE block >= $1 RETURNING address
+	), touched AS (
+		SELECT DISTINCT address FROM purged
+	), restored AS (
+		SELECT DISTINCT ON (h.address) h.address, h.balance, h.block, h.doc FROM balance_history h
+		JOIN touched USING (address) WHERE h.block < $1 ORDER BY h.address, h.block DESC
+	), removed AS (
+		DELETE FROM accounts a USING touched t WHERE a.address = t.address AND NOT EXISTS (SELECT 1 FROM restored r WHERE r.address = t.address)
+	)
+	UPDATE accounts a SET balance = r.balance, block = r.block, doc = jsonb_strip_nulls(jsonb_build_object(
+		'address', r.address, 'balance', r.doc->'balance', 'block', r.doc->'block', 'nonce', r.doc->'nonce', 'hasCode', r.doc->'hasCode'
+	)) FROM restored r WHERE a.address = r.address`, height)
+	if err != nil {
+		return err
+	}
+
+	var touched []models.TokenBalance
+
+	err = all(tx, &touched, `SELECT DISTINCT jsonb_build_object('contract', contract, 'address', address) FROM token_balance_history WHERE block >= $1`, height)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM token_balance_history WHERE block >= $1`, height); err != nil {
+		return err
+	}
+
+	for _, t := range touched {
+		prev := models.TokenBalance{Contract: t.Contract, Address: t.Address, Balance: "0"}
+
+		err := one(tx, &prev, `SELECT doc FROM token_balance_history WHERE contract = $1 AND address = $2 ORDER BY block DESC LIMIT 1`, t.Contract, t.Address)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+
+		if err := setTokenBalance(tx, &prev); err != nil {
+			return err
+		}
+	}
+
+	log.Debug("purged blocks", "from", height, "count", purged, "token holders", len(touched))
+
+	return nil
+}
+
+// Rollback removes every block above height, see MongoDB.Rollback
+
+func (p *Postgres) Rollback(height uint64, checkpoint string) ([]models.Block, error) {
+	var orphaned []models.Block
+
+	err := p.inTx(func(tx *sql.Tx) error {
+
+		var ancestor models.Block
+
+		if checkpoint != "" {
+			if err := one(tx, &ancestor, `SELECT doc FROM blocks WHERE number = $1`, height); err != nil {
+				return err
+			}
+		}
+
+		if err := all(tx, &orphaned, `SELECT `+blockDoc+` FROM blocks b WHERE b.number > $1 ORDER BY b.number DESC`, height); err != nil {
+			return err
+		}
+
+		// a block may have been forked before
+		for i := range orphaned {
+			b := &orphaned[i]
+
+			doc, err := encodeDoc(b)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(`INSERT INTO forked_blocks (hash, number, timestamp, doc) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (hash) DO UPDATE SET number = excluded.number, timestamp = excluded.timestamp, doc = excluded.doc`, b.Hash, b.Number, b.Timestamp, doc); err != nil {
+				return err
+			}
+		}
+
+		if err := purgeFrom(tx, height+1); err != nil {
+			return err
+		}
+
+		if checkpoint == "" {
+			return nil
+		}
+
+		return setCheckpoint(tx, checkpoint, ancestor.Number, ancestor.Hash)
+	})
+
+	return orphaned, err
+}
+
+func (p *Postgres) Checkpoint(crawler string) (models.Checkpoint, error) {
+	var cp models.Checkpoint
+
+	err := p.db.QueryRow(`SELECT crawler, number, hash, updated FROM checkpoints WHERE crawler = $1`, crawler).Scan(&cp.Crawler, &cp.Number, &cp.Hash, &cp.Updated)
+	if err == sql.ErrNoRows {
+		return cp, ErrNotFound
+	}
+
+	return cp, err
+}
+
+func (p *Postgres) SetCheckpoint(crawler string, number uint64, hash string) error {
+	return setCheckpoint(p.db, crawler, number, hash)
+}
+
+func setCheckpoint(q queryer, crawler string, number uint64, hash string) error {
+	_, err := q.Exec(`INSERT INTO checkpoints (crawler, number, hash, updated) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (crawler) DO UPDATE SET number = excluded.number, hash = excluded.hash, updated = excluded.updated`, crawler, number, hash, time.Now().Unix())
+	return err
+}
+
+func (p *Postgres) AddReorg(r *models.Reorg) error {
+	doc, err := encodeDoc(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(`INSERT INTO reorgs (number, doc) VALUES ($1, $2)`, r.Number, doc)
+	return err
+}
+
+func (p *Postgres) AddToken(t *models.Token) error {
+	token := *t
+	token.Name, token.Symbol = stripNulls(t.Name), stripNulls(t.Symbol)
+
+	doc, err := encodeDoc(&token)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(`INSERT INTO tokens (address, symbol, is_token, updated, doc) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (address) DO UPDATE SET symbol = excluded.symbol, is_token = excluded.is_token, updated = excluded.updated, doc = excluded.doc`,
+		token.Address, token.Symbol, token.IsToken, token.Updated, doc)
+	return err
+}
+
+func (p *Postgres) AddEnodes(e *models.Enode) error {
+	doc, err := encodeDoc(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(`INSERT INTO enodes (doc) VALUES ($1)`, doc)
+	return err
+}
+
+func (p *Postgres) AddNumberChart(name string, series []uint64, stamps []string) error {
+	return p.setChart(name, &models.NumberChart{Name: name, Series: series, Timestamps: stamps})
+}
+
+func (p *Postgres) AddNumberStringChart(name string, series []string, stamps []string) error {
+	return p.setChart(name, &models.NumberStringChart{Name: name, Series: series, Timestamps: stamps})
+}
+
+func (p *Postgres) AddMultiSeriesChart(name string, series map[string]map[string]uint, stamps []string) error {
+	return p.setChart(name, &models.MultiSeriesChart{Name: name, Datasets: multiSeriesDatasets(series), Timestamps: stamps})
+}
+
+func (p *Postgres) setChart(name string, chart interface{}) error {
+	doc, err := encodeDoc(chart)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(`INSERT INTO charts (name, doc) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET doc = excluded.doc`, name, doc)
+	return err
+}
+
+func (p *Postgres) AddContractABI(address string, abiJSON string) error {
+	return p.addContractABI(address, abiJSON, false)
+}
+
+// addContractABI stores an ABI, one that came from verification replaces any other
+
+func (p *Postgres) addContractABI(address string, abiJSON string, verified bool) error {
+	if _, err := abi.JSON(strings.NewReader(abiJSON)); err != nil {
+		return err
+	}
+
+	r, err := p.db.Exec(`INSERT INTO abis (address, abi, updated, verified) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (address) DO UPDATE SET abi = excluded.abi, updated = excluded.updated, verified = excluded.verified
+		WHERE excluded.verified OR NOT abis.verified`, strings.ToLower(address), abiJSON, time.Now().Unix(), verified)
+	if err != nil {
+		return err
+	}
+
+	if n, err := r.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrVerifiedABI
+	}
+
+	return nil
+}
+
+func (p *Postgres) AddVerifiedContract(c *models.VerifiedContract) error {
+	doc, err := encodeDoc(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.db.Exec(`INSERT INTO verified_contracts (address, doc) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET doc = excluded.doc`, c.Address, doc); err != nil {
+		return err
+	}
+
+	return p.addContractABI(c.Address, c.ABI, true)
+}
+
+// ImportSignatures adds the signatures that aren't stored yet and returns how many were added
+
+func (p *Postgres) ImportSignatures(sigs []models.Signature) (int64, error) {
+	var added int64
+
+	err := p.inTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT INTO signatures (hash, text, type, builtin) VALUES ($1, $2, $3, $4) ON CONFLICT (hash, text) DO NOTHING`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, s := range sigs {
+			r, err := stmt.Exec(s.Hash, s.Text, s.Type, s.Builtin)
+			if err != nil {
+				return err
+			}
+
+			n, _ := r.RowsAffected()
+			added += n
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// UpdateStore refreshes the totals of the store, see MongoDB.UpdateStore
+
+func (p *Postgres) UpdateStore() error {
+	latestBlock, err := p.LatestBlock()
+	if err != nil {
+		return err
+	}
+
+	latestTrace, err := p.LatestTxTrace()
+	if err != nil {
+		return err
+	}
+
+	store, err := p.Status()
+	if err == ErrNotFound {
+		return errors.New("didn't update " + p.symbol + " store")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = p.db.QueryRow(`SELECT
+		(SELECT count(*) FROM transactions),
+		(SELECT count(*) FROM transactions WHERE contract_deploy),
+		(SELECT count(*) FROM transactions WHERE contract_call),
+		(SELECT count(*) FROM token_transfers),
+		(SELECT count(*) FROM uncles),
+		(SELECT count(*) FROM forked_blocks)`).Scan(&store.TotalTransactions, &store.TotalContractsDeployed, &store.TotalContractCalls, &store.TotalTokenTransfers, &store.TotalUncles, &store.TotalForkedBlocks)
+	if err != nil {
+		return err
+	}
+
+	store.Timestamp = time.Now().Unix()
+	store.Supply = latestBlock.Supply
+	store.LatestBlock = latestBlock
+	store.LatestTraceHash = latestTrace.ParentHash
+
+	return p.setStore(&store)
+}
+
+// Iterators
+
+// postgresIterator walks the documents of a query, it holds a connection until it's closed
+
+type postgresIterator struct {
+	rows *sql.Rows
+	cur  []byte
+	err  error
+}
+
+func (it *postgresIterator) Next(ctx context.Context) bool {
+	if it.err = ctx.Err(); it.err != nil || !it.rows.Next() {
+		return false
+	}
+
+	it.err = it.rows.Scan(&it.cur)
+
+	return it.err == nil
+}
+
+func (it *postgresIterator) Decode(val interface{}) error {
+	if it.cur == nil {
+		return errors.New("no current document")
+	}
+
+	return decodeDoc(it.cur, val)
+}
+
+func (it *postgresIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	return it.rows.Err()
+}
+
+func (it *postgresIterator) Close(ctx context.Context) error {
+	return it.rows.Close()
+}
+
+func (p *Postgres) iter(query string, from, to int64) (Iterator, error) {
+	rows, err := p.db.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresIterator{rows: rows}, nil
+}
+
+func (p *Postgres) IterTransactions(from, to int64) (Iterator, error) {
+	return p.iter(`SELECT doc FROM transactions WHERE timestamp > $1 AND timestamp < $2 ORDER BY block_number, transaction_index`, from, to)
+}
+
+func (p *Postgres) IterBlocks(from, to int64) (Iterator, error) {
+	return p.iter(`SELECT `+blockDoc+` FROM blocks b WHERE b.timestamp > $1 AND b.timestamp < $2 ORDER BY b.number`, from, to)
+}
+
+func (p *Postgres) IterForkedBlocks(from, to int64) (Iterator, error) {
+	return p.iter(`SELECT doc FROM forked_blocks WHERE timestamp > $1 AND timestamp < $2 ORDER BY number`, from, to)
+}
+
+func (p *Postgres) IterUncles(from, to int64) (Iterator, error) {
+	return p.iter(`SELECT doc FROM uncles WHERE timestamp > $1 AND timestamp < $2 ORDER BY block_number`, from, to)
+}
+
+func (p *Postgres) IterTokenTransfers(from, to int64) (Iterator, error) {
+	return p.iter(`SELECT doc FROM token_transfers WHERE timestamp > $1 AND timestamp < $2 ORDER BY block_number, id`, from, to)
+}
@@ -0,0 +1,397 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+// The api methods of the postgres backend, they return the same documents and totals as their mongo counterparts in api.go
+
+// The orders of the lists, by column. The position of an uncle is only in its document, see the expression index
+// of migration 3
+
+var (
+	pgBlockOrder       = order{number: "number"}
+	pgTransactionOrder = order{number: "block_number", index: "transaction_index"}
+	pgTransferOrder    = order{number: "block_number", index: "log_index", key: "id"}
+	pgNFTOrder         = order{number: "block_number", index: "log_index", key: "id"}
+	pgCreationOrder    = order{number: "block_number", key: "address"}
+	pgBalanceOrder     = order{value: "balance", key: "address", largest: true}
+)
+
+//Blocks
+
+func (p *Postgres) LatestBlocks(page *Page) (map[string]interface{}, error) {
+	return p.page(page, pgBlockOrder, blockDoc, `blocks b`, `TRUE`, nil, "blocks", &[]models.Block{}, func() (int64, error) {
+		return count(p.db, `SELECT COALESCE(max(number) + 1, 0) FROM blocks`)
+	})
+}
+
+func (p *Postgres) LatestMinedBlocks(account string, page *Page) (map[string]interface{}, error) {
+	return p.page(page, pgBlockOrder, blockDoc, `blocks b`, `b.miner = $1`, []interface{}{account}, "blocks", &[]models.Block{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM blocks WHERE miner = $1`, account)
+	})
+}
+
+//Uncles
+
+func (p *Postgres) LatestUncles(page *Page) (map[string]interface{}, error) {
+	o := order{number: "block_number", index: "(doc->>'position')::bigint"}
+
+	return p.page(page, o, `doc`, `uncles`, `TRUE`, nil, "uncles", &[]models.Uncle{}, func() (int64, error) {
+		status, err := p.Status()
+		return status.TotalUncles, err
+	})
+}
+
+//Forked Blocks
+
+func (p *Postgres) LatestForkedBlocks(page *Page) (map[string]interface{}, error) {
+	return p.page(page, order{number: "number", key: "hash"}, `doc`, `forked_blocks`, `TRUE`, nil, "forkedBlocks", &[]models.Block{}, func() (int64, error) {
+		status, err := p.Status()
+		return status.TotalForkedBlocks, err
+	})
+}
+
+//Reorgs
+
+func (p *Postgres) LatestReorgs(page *Page) (map[string]interface{}, error) {
+	return p.page(page, order{number: "number", key: "id"}, `doc`, `reorgs`, `TRUE`, nil, "reorgs", &[]models.Reorg{}, p.TotalReorgCount)
+}
+
+//Transactions
+
+func (p *Postgres) LatestTransactions(page *Page) (map[string]interface{}, error) {
+	return p.latestTransactionsWithTotal(`TRUE`, page, func(s models.Store) int64 { return s.TotalTransactions })
+}
+
+func (p *Postgres) LatestFailedTransactions(page *Page) (map[string]interface{}, error) {
+	const failed = `block_number >= 1075090 AND NOT status`
+
+	return p.page(page, pgTransactionOrder, `doc`, `transactions`, failed, nil, "txns", &[]models.Transaction{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM transactions WHERE `+failed)
+	})
+}
+
+//Contracts
+
+func (p *Postgres) LatestContractCalls(page *Page) (map[string]interface{}, error) {
+	return p.latestTransactionsWithTotal(`contract_call`, page, func(s models.Store) int64 { return s.TotalContractCalls })
+}
+
+func (p *Postgres) LatestContractsDeployed(page *Page) (map[string]interface{}, error) {
+	return p.latestTransactionsWithTotal(`contract_deploy`, page, func(s models.Store) int64 { return s.TotalContractsDeployed })
+}
+
+// latestTransactionsWithTotal returns a page of the transactions matching where, along with their total from the store
+
+func (p *Postgres) latestTransactionsWithTotal(where string, page *Page, total func(models.Store) int64) (map[string]interface{}, error) {
+	return p.page(page, pgTransactionOrder, `doc`, `transactions`, where, nil, "txns", &[]models.Transaction{}, func() (int64, error) {
+		status, err := p.Status()
+		return total(status), err
+	})
+}
+
+//Tokens
+
+func (p *Postgres) LatestTokenTransfers(page *Page) (map[string]interface{}, error) {
+	return p.page(page, pgTransferOrder, `doc`, `token_transfers`, `TRUE`, nil, "transfers", &[]models.TokenTransfer{}, func() (int64, error) {
+		status, err := p.Status()
+		return status.TotalTokenTransfers, err
+	})
+}
+
+func (p *Postgres) LatestTransfersOfToken(hash string, page *Page) (map[string]interface{}, error) {
+	return p.latestTokenTransfers(`contract = $1`, page, hash)
+}
+
+//NFTs
+
+func (p *Postgres) LatestNFTTransfersByContract(contract string, page *Page) (map[string]interface{}, error) {
+	return p.latestNFTTransfers(`contract = $1`, page, contract)
+}
+
+func (p *Postgres) LatestNFTTransfersByToken(contract string, tokenId string, page *Page) (map[string]interface{}, error) {
+	return p.latestNFTTransfers(`contract = $1 AND token_id = $2`, page, contract, tokenId)
+}
+
+func (p *Postgres) LatestNFTTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return p.latestNFTTransfers(`from_address = $1 OR to_address = $1`, page, account)
+}
+
+func (p *Postgres) latestNFTTransfers(where string, page *Page, args ...interface{}) (map[string]interface{}, error) {
+	return p.page(page, pgNFTOrder, `doc`, `nft_transfers`, where, args, "transfers", &[]models.NFTTransfer{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM nft_transfers WHERE `+where, args...)
+	})
+}
+
+//Tokens
+
+func (p *Postgres) ListTokens(page *Page) (map[string]interface{}, error) {
+	return p.page(page, order{value: "symbol", key: "address"}, `doc`, `tokens`, `is_token`, nil, "tokens", &[]models.Token{}, p.TotalTokenCount)
+}
+
+// TokenHolders returns the largest holders of a token, with their share of its total supply
+
+func (p *Postgres) TokenHolders(contract string, page *Page) (map[string]interface{}, error) {
+	var (
+		holders = make([]models.TokenHolder, 0)
+		result  = map[string]interface{}{}
+	)
+
+	pg, err := newPager(page, pgBalanceOrder, DefaultPageSize)
+	if err != nil {
+		return result, err
+	}
+
+	if err := p.findPage(pg, `doc`, `token_balances`, `contract = $1`, []interface{}{contract}, &holders); err != nil {
+		return result, err
+	}
+
+	// percentages are left at 0 if the token metadata isn't known yet
+	if token, err := p.TokenInfo(contract); err == nil {
+		setHolderPercentages(holders, token.TotalSupply)
+	}
+
+	total, err := pg.total(func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM token_balances WHERE contract = $1`, contract)
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	pg.setResult(result, "holders", holders, total)
+
+	return result, nil
+}
+
+// TokenBalancesByAccount returns the token balances held by account, by contract
+
+func (p *Postgres) TokenBalancesByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return p.page(page, order{key: "contract"}, `doc`, `token_balances`, `address = $1`, []interface{}{account}, "balances", &[]models.TokenBalance{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM token_balances WHERE address = $1`, account)
+	})
+}
+
+//Accounts
+
+func (p *Postgres) LatestTransactionsByAccount(hash string, page *Page) (map[string]interface{}, error) {
+	return p.page(page, pgTransactionOrder, `doc`, `transactions`, `from_address = $1 OR to_address = $1`, []interface{}{hash}, "txns", &[]models.Transaction{}, func() (int64, error) {
+		return p.TxnCount(hash)
+	})
+}
+
+// LatestITransactionsByAccount returns internal transactions newest first, those of a block in the order they were written
+
+func (p *Postgres) LatestITransactionsByAccount(hash string, page *Page) (map[string]interface{}, error) {
+	o := order{number: "block_number", key: "id"}
+
+	return p.page(page, o, `doc`, `internal_transactions`, `from_address = $1 OR to_address = $1`, []interface{}{hash}, "itxns", &[]models.ITransaction{}, func() (int64, error) {
+		return p.ITxnCount(hash)
+	})
+}
+
+func (p *Postgres) LatestTokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return p.latestTokenTransfers(`from_address = $1 OR to_address = $1`, page, account)
+}
+
+func (p *Postgres) latestTokenTransfers(where string, page *Page, args ...interface{}) (map[string]interface{}, error) {
+	return p.page(page, pgTransferOrder, `doc`, `token_transfers`, where, args, "transfers", &[]models.TokenTransfer{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM token_transfers WHERE `+where, args...)
+	})
+}
+
+func (p *Postgres) AccountsByBalance(page *Page) (map[string]interface{}, error) {
+	return p.page(page, pgBalanceOrder, `doc`, `accounts`, `TRUE`, nil, "accounts", &[]models.Account{}, p.TotalAccountCount)
+}
+
+func (p *Postgres) AccountsByLastSeen(page *Page) (map[string]interface{}, error) {
+	return p.page(page, order{number: "block", key: "address"}, `doc`, `accounts`, `TRUE`, nil, "accounts", &[]models.Account{}, p.TotalAccountCount)
+}
+
+func (p *Postgres) BalanceHistory(account string, page *Page) (map[string]interface{}, error) {
+	return p.page(page, order{number: "block"}, `doc`, `balance_history`, `address = $1`, []interface{}{account}, "history", &[]models.BalanceRecord{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM balance_history WHERE address = $1`, account)
+	})
+}
+
+// page reads a page of the documents selected by doc from the rows of from matching where into items, a pointer
+// to a slice, and returns it under name along with the total from count. Arguments of where start at $1
+
+func (p *Postgres) page(page *Page, o order, doc, from, where string, args []interface{}, name string, items interface{}, count func() (int64, error)) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+
+	pg, err := newPager(page, o, DefaultPageSize)
+	if err != nil {
+		return result, err
+	}
+
+	if err := p.findPage(pg, doc, from, where, args, items); err != nil {
+		return result, err
+	}
+
+	total, err := pg.total(count)
+	if err != nil {
+		return result, err
+	}
+
+	pg.setResult(result, name, reflect.ValueOf(items).Elem().Interface(), total)
+
+	return result, nil
+}
+
+// findPage reads the documents selected by doc that make up the page of pg into items, along with the columns
+// of their positions
+
+func (p *Postgres) findPage(pg *pager, doc, from, where string, args []interface{}, items interface{}) error {
+	query := `SELECT ` + doc + `, ` + strings.Join(pg.columns(), `, `) + ` FROM ` + from + ` WHERE (` + where + `)`
+
+	if pg.after != nil {
+		after, afterArgs := pg.sqlAfter(pg.after, len(args))
+
+		query += ` AND (` + after + `)`
+		args = append(args, afterArgs...)
+	}
+
+	args = append(args, pg.limit+1)
+	query += fmt.Sprintf(` ORDER BY %s LIMIT $%d`, pg.sqlSort(), len(args))
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	sv := reflect.ValueOf(items).Elem()
+	docs := reflect.MakeSlice(sv.Type(), 0, 0)
+
+	pg.positions = pg.positions[:0]
+
+	for rows.Next() {
+		var (
+			raw           []byte
+			number, index sql.NullInt64
+			value, key    sql.NullString
+		)
+
+		dest := []interface{}{&raw}
+
+		if pg.number != "" {
+			dest = append(dest, &number)
+		}
+
+		if pg.index != "" {
+			dest = append(dest, &index)
+		}
+
+		if pg.value != "" {
+			dest = append(dest, &value)
+		}
+
+		if pg.key != "" {
+			dest = append(dest, &key)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		v := reflect.New(sv.Type().Elem())
+		if err := decodeDoc(raw, v.Interface()); err != nil {
+			return err
+		}
+
+		docs = reflect.Append(docs, v.Elem())
+		pg.positions = append(pg.positions, cursor{Number: uint64(number.Int64), Index: uint64(index.Int64), Value: value.String, Key: key.String})
+	}
+
+	sv.Set(docs)
+
+	return rows.Err()
+}
+
+// columns are the columns of the positions of o, in order
+
+func (o order) columns() []string {
+	var columns []string
+
+	for _, c := range []string{o.number, o.index, o.value, o.key} {
+		if c != "" {
+			columns = append(columns, c)
+		}
+	}
+
+	return columns
+}
+
+func (o order) sqlSort() string {
+	var sort []string
+
+	if o.number != "" {
+		sort = append(sort, o.number+` DESC`)
+	}
+
+	if o.index != "" {
+		sort = append(sort, o.index+` DESC`)
+	}
+
+	if o.value != "" && o.largest {
+		sort = append(sort, o.value+` DESC NULLS LAST`)
+	} else if o.value != "" {
+		sort = append(sort, o.value)
+	}
+
+	if o.key != "" {
+		sort = append(sort, o.key)
+	}
+
+	return strings.Join(sort, `, `)
+}
+
+// sqlAfter matches the rows that sort after c, like mongoAfter. Its arguments are numbered from after n.
+// Balances may be null, nulls sort after every balance
+
+func (o order) sqlAfter(c *cursor, n int) (string, []interface{}) {
+	var (
+		after []string
+		equal []string
+		args  []interface{}
+	)
+
+	past := func(column string, op string, value interface{}) {
+		args = append(args, value)
+		arg := fmt.Sprintf("$%d", n+len(args))
+
+		clause := append(append([]string{}, equal...), column+` `+op+` `+arg)
+		after = append(after, `(`+strings.Join(clause, ` AND `)+`)`)
+		equal = append(equal, column+` = `+arg)
+	}
+
+	if o.number != "" {
+		past(o.number, `<`, int64(c.Number))
+	}
+
+	if o.index != "" {
+		past(o.index, `<`, int64(c.Index))
+	}
+
+	if o.value != "" && o.largest && c.Value == "" {
+		equal = append(equal, o.value+` IS NULL`)
+	} else if o.value != "" && o.largest {
+		after = append(after, `(`+strings.Join(append(append([]string{}, equal...), o.value+` IS NULL`), ` AND `)+`)`)
+		past(o.value, `<`, c.Value)
+	} else if o.value != "" {
+		past(o.value, `>`, c.Value)
+	}
+
+	if o.key != "" {
+		past(o.key, `>`, c.Key)
+	}
+
+	return strings.Join(after, ` OR `), args
+}
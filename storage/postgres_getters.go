@@ -0,0 +1,435 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/accounts/abi"
+	"github.com/ubiq/go-ubiq/v7/log"
+)
+
+// Store
+
+func (p *Postgres) Status() (models.Store, error) {
+	var store models.Store
+
+	err := one(p.db, &store, `SELECT doc FROM store WHERE symbol = $1`, p.symbol)
+	return store, err
+}
+
+// Blocks
+
+func (p *Postgres) BlockByNumber(number uint64) (models.Block, error) {
+	var block models.Block
+
+	err := one(p.db, &block, `SELECT `+blockDoc+` FROM blocks b WHERE b.number = $1`, number)
+	return block, err
+}
+
+func (p *Postgres) BlockByHash(hash string) (models.Block, error) {
+	var block models.Block
+
+	err := one(p.db, &block, `SELECT `+blockDoc+` FROM blocks b WHERE b.hash = $1`, hash)
+	return block, err
+}
+
+func (p *Postgres) LatestBlock() (models.Block, error) {
+	var block models.Block
+
+	err := one(p.db, &block, `SELECT `+blockDoc+` FROM blocks b ORDER BY b.number DESC LIMIT 1`)
+	return block, err
+}
+
+func (p *Postgres) TotalBlockCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM blocks`)
+}
+
+// Uncles
+
+func (p *Postgres) UncleByHash(hash string) (models.Uncle, error) {
+	var uncle models.Uncle
+
+	err := one(p.db, &uncle, `SELECT doc FROM uncles WHERE hash = $1`, hash)
+	return uncle, err
+}
+
+func (p *Postgres) TotalUncleCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM uncles`)
+}
+
+// Forked blocks
+
+func (p *Postgres) ForkedBlockByNumber(number uint64) (models.Block, error) {
+	var block models.Block
+
+	err := one(p.db, &block, `SELECT doc FROM forked_blocks WHERE number = $1 LIMIT 1`, number)
+	return block, err
+}
+
+func (p *Postgres) TotalForkedBlockCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM forked_blocks`)
+}
+
+// Reorgs
+
+func (p *Postgres) TotalReorgCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM reorgs`)
+}
+
+// Transactions
+
+func (p *Postgres) TransactionByHash(hash string) (models.Transaction, error) {
+	var txn models.Transaction
+
+	if err := one(p.db, &txn, `SELECT doc FROM transactions WHERE hash = $1`, hash); err != nil {
+		return txn, err
+	}
+
+	if err := decodeTransaction(&txn, p.contractABIs); err != nil {
+		log.Warn("couldn't decode transaction", "hash", hash, "err", err)
+	}
+
+	return txn, nil
+}
+
+func (p *Postgres) TransactionsByBlockNumber(number uint64) ([]models.Transaction, error) {
+	var txns []models.Transaction
+
+	err := all(p.db, &txns, `SELECT doc FROM transactions WHERE block_number = $1 ORDER BY transaction_index`, number)
+	return txns, err
+}
+
+func (p *Postgres) TransactionByContractAddress(address string) (models.Transaction, error) {
+	var txn models.Transaction
+
+	err := one(p.db, &txn, `SELECT doc FROM transactions WHERE contract_address = $1 LIMIT 1`, address)
+	return txn, err
+}
+
+func (p *Postgres) TxnCount(address string) (int64, error) {
+	return count(p.db, `SELECT count(*) FROM transactions WHERE from_address = $1 OR to_address = $1`, address)
+}
+
+func (p *Postgres) ITxnCount(address string) (int64, error) {
+	return count(p.db, `SELECT count(*) FROM internal_transactions WHERE from_address = $1 OR to_address = $1`, address)
+}
+
+func (p *Postgres) TotalTxnCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM transactions`)
+}
+
+// Tx trace
+
+func (p *Postgres) TxTrace(hash string) (models.ITransaction, error) {
+	var trace models.ITransaction
+
+	err := one(p.db, &trace, `SELECT COALESCE(doc->'trace', '{}') FROM transactions WHERE hash = $1`, hash)
+	return trace, err
+}
+
+func (p *Postgres) LatestTxTrace() (models.ITransaction, error) {
+	var trace models.ITransaction
+
+	err := one(p.db, &trace, `SELECT doc FROM internal_transactions ORDER BY block_number DESC, id DESC LIMIT 1`)
+	return trace, err
+}
+
+// Contracts
+
+func (p *Postgres) TotalContractCallsCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM transactions WHERE contract_call`)
+}
+
+func (p *Postgres) TotalContractsDeployedCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM transactions WHERE contract_deploy`)
+}
+
+// Token transfers
+
+func (p *Postgres) TransfersOfTokenByAccount(token string, account string, page *Page) (map[string]interface{}, error) {
+	return p.latestTokenTransfers(`contract = $1 AND (from_address = $2 OR to_address = $2)`, page, token, account)
+}
+
+func (p *Postgres) TransfersOfTokenByAccountCount(token string, account string) (int64, error) {
+	return count(p.db, `SELECT count(*) FROM token_transfers WHERE contract = $1 AND (from_address = $2 OR to_address = $2)`, token, account)
+}
+
+func (p *Postgres) TokenTransfersByAccount(account string, page *Page) (map[string]interface{}, error) {
+	return p.LatestTokenTransfersByAccount(account, page)
+}
+
+func (p *Postgres) TokenTransfersByAccountCount(account string) (int64, error) {
+	return count(p.db, `SELECT count(*) FROM token_transfers WHERE from_address = $1 OR to_address = $1`, account)
+}
+
+func (p *Postgres) TransfersByContract(address string, page *Page) (map[string]interface{}, error) {
+	return p.LatestTransfersOfToken(address, page)
+}
+
+func (p *Postgres) ContractTransferCount(address string) (int64, error) {
+	return count(p.db, `SELECT count(*) FROM token_transfers WHERE contract = $1`, address)
+}
+
+func (p *Postgres) TotalTransferCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM token_transfers`)
+}
+
+// NFT transfers
+
+func (p *Postgres) NFTOwner(contract string, tokenId string) (string, error) {
+	var owner string
+
+	err := p.db.QueryRow(`SELECT to_address FROM nft_transfers WHERE contract = $1 AND token_id = $2 AND standard = $3
+		ORDER BY block_number DESC, log_index DESC LIMIT 1`, contract, tokenId, models.ERC721).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+
+	return owner, err
+}
+
+func (p *Postgres) TotalNFTTransferCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM nft_transfers`)
+}
+
+// Tokens
+
+func (p *Postgres) TokenInfo(address string) (models.Token, error) {
+	var token models.Token
+
+	err := one(p.db, &token, `SELECT doc FROM tokens WHERE address = $1 AND is_token`, address)
+	return token, err
+}
+
+func (p *Postgres) TotalTokenCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM tokens WHERE is_token`)
+}
+
+// TokenCandidates returns the addresses of contracts that may be tokens and are due a check, see MongoDB.TokenCandidates
+
+func (p *Postgres) TokenCandidates(before int64) ([]string, error) {
+	candidates := make([]string, 0)
+
+	rows, err := p.db.Query(`SELECT address FROM (
+		SELECT contract AS address FROM token_transfers
+		UNION
+		SELECT contract_address FROM transactions WHERE contract_deploy
+	) c WHERE address <> '' AND NOT EXISTS (SELECT 1 FROM tokens t WHERE t.address = c.address AND t.updated >= $1)
+	ORDER BY address`, before)
+	if err != nil {
+		return candidates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address string
+
+		if err := rows.Scan(&address); err != nil {
+			return candidates, err
+		}
+
+		candidates = append(candidates, address)
+	}
+
+	return candidates, rows.Err()
+}
+
+// Charts
+
+func (p *Postgres) GetNumberChart(name string, limit int) (models.NumberChart, error) {
+	var chart models.NumberChart
+
+	if err := one(p.db, &chart, `SELECT doc FROM charts WHERE name = $1`, name); err != nil {
+		return models.NumberChart{}, err
+	}
+
+	limitNumberChart(&chart, limit)
+
+	return chart, nil
+}
+
+func (p *Postgres) GetNumberStringChart(name string, limit int) (models.NumberStringChart, error) {
+	var chart models.NumberStringChart
+
+	if err := one(p.db, &chart, `SELECT doc FROM charts WHERE name = $1`, name); err != nil {
+		return models.NumberStringChart{}, err
+	}
+
+	limitNumberStringChart(&chart, limit)
+
+	return chart, nil
+}
+
+func (p *Postgres) GetMultiSeriesChart(name string, limit int) (models.MultiSeriesChart, error) {
+	var chart models.MultiSeriesChart
+
+	if err := one(p.db, &chart, `SELECT doc FROM charts WHERE name = $1`, name); err != nil {
+		return models.MultiSeriesChart{}, err
+	}
+
+	limitMultiSeriesChart(&chart, limit)
+
+	return chart, nil
+}
+
+func (p *Postgres) ListCharts() ([]string, error) {
+	var result []string
+
+	rows, err := p.db.Query(`SELECT name FROM charts ORDER BY name`)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+
+		if err := rows.Scan(&name); err != nil {
+			return result, err
+		}
+
+		result = append(result, name)
+	}
+
+	return result, rows.Err()
+}
+
+// Contracts
+
+func (p *Postgres) ContractCreation(address string) (models.ContractCreation, error) {
+	var creation models.ContractCreation
+
+	err := one(p.db, &creation, `SELECT doc FROM contract_creations WHERE address = $1 ORDER BY block_number DESC, id DESC LIMIT 1`, address)
+	return creation, err
+}
+
+// ContractsByCreator returns the contracts deployed by creator, newest first and without their code
+
+func (p *Postgres) ContractsByCreator(creator string, page *Page) (map[string]interface{}, error) {
+	return p.creations(`creator = $1`, page, creator)
+}
+
+// ContractsWithSameCode returns the other contracts deployed with the same runtime code as address
+
+func (p *Postgres) ContractsWithSameCode(address string, page *Page) (map[string]interface{}, error) {
+	creation, err := p.ContractCreation(address)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	return p.creations(`code_hash = $1 AND address <> $2`, page, creation.CodeHash, address)
+}
+
+// creations returns a page of the contract creations matching where, without their code
+
+func (p *Postgres) creations(where string, page *Page, args ...interface{}) (map[string]interface{}, error) {
+	return p.page(page, pgCreationOrder, `doc - 'code'`, `contract_creations`, where, args, "contracts", &[]models.ContractCreation{}, func() (int64, error) {
+		return count(p.db, `SELECT count(*) FROM contract_creations WHERE `+where, args...)
+	})
+}
+
+// Accounts
+
+func (p *Postgres) TotalAccountCount() (int64, error) {
+	return count(p.db, `SELECT count(*) FROM accounts`)
+}
+
+func (p *Postgres) BalanceAt(account string, block uint64) (models.BalanceRecord, error) {
+	var record models.BalanceRecord
+
+	err := one(p.db, &record, `SELECT doc FROM balance_history WHERE address = $1 AND block <= $2 ORDER BY block DESC LIMIT 1`, account, block)
+	return record, err
+}
+
+// ABIs
+
+func (p *Postgres) ContractABI(address string) (models.ContractABI, error) {
+	var a models.ContractABI
+
+	err := p.db.QueryRow(`SELECT address, abi, updated, verified FROM abis WHERE address = $1`, strings.ToLower(address)).Scan(&a.Address, &a.ABI, &a.Updated, &a.Verified)
+	if err == sql.ErrNoRows {
+		return a, ErrNotFound
+	}
+
+	return a, err
+}
+
+func (p *Postgres) DecodeTransaction(hash string) (models.DecodedTransaction, error) {
+	return decodedTransaction(hash, p.TransactionByHash)
+}
+
+func (p *Postgres) contractABIs(addresses []string) (map[string]*abi.ABI, error) {
+	var stored []models.ContractABI
+
+	rows, err := p.db.Query(`SELECT address, abi, updated FROM abis WHERE address = ANY($1)`, pq.Array(abiAddresses(addresses)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.ContractABI
+
+		if err := rows.Scan(&a.Address, &a.ABI, &a.Updated); err != nil {
+			return nil, err
+		}
+
+		stored = append(stored, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return parseABIs(stored), nil
+}
+
+func (p *Postgres) VerifiedContract(address string) (models.VerifiedContract, error) {
+	var c models.VerifiedContract
+
+	err := one(p.db, &c, `SELECT doc FROM verified_contracts WHERE address = $1`, strings.ToLower(address))
+	return c, err
+}
+
+// Signatures
+
+// LookupSelector returns the signatures of a selector or event topic, built in first and then in import order
+
+func (p *Postgres) LookupSelector(hash string) ([]models.Signature, error) {
+	sigs := make([]models.Signature, 0)
+
+	rows, err := p.db.Query(`SELECT hash, text, type, builtin FROM signatures WHERE hash = $1 ORDER BY builtin DESC, id`, strings.ToLower(hash))
+	if err != nil {
+		return sigs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Signature
+
+		if err := rows.Scan(&s.Hash, &s.Text, &s.Type, &s.Builtin); err != nil {
+			return sigs, err
+		}
+
+		sigs = append(sigs, s)
+	}
+
+	return sigs, rows.Err()
+}
+
+func (p *Postgres) MethodSignature(input string) (string, error) {
+	if len(input) < 10 {
+		return "", nil
+	}
+
+	var text string
+
+	err := p.db.QueryRow(`SELECT text FROM signatures WHERE hash = $1 ORDER BY builtin DESC, id LIMIT 1`, strings.ToLower(input[:10])).Scan(&text)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return text, err
+}
@@ -0,0 +1,266 @@
+package storage
+
+// migration is a step of the postgres schema. Steps are applied in order, each in its own transaction, and
+// recorded in schema_migrations so they run once. Released steps must never change, add a new one instead
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// Every table keeps the columns we filter, sort or join on, plus the whole document as written by the
+// crawlers in doc, so reads return exactly what mongo would. Block documents are stored without their
+// transactions, which are joined back from the transactions table. Contracts and contract calls are flags on
+// transactions rather than copies, and there's no traces table as rows aren't limited to 16MB
+
+var postgresMigrations = []migration{
+	{1, "create tables", []string{
+		`CREATE TABLE store (
+			symbol text PRIMARY KEY,
+			doc    jsonb NOT NULL
+		)`,
+		`CREATE TABLE checkpoints (
+			crawler text PRIMARY KEY,
+			number  bigint NOT NULL,
+			hash    text NOT NULL,
+			updated bigint NOT NULL
+		)`,
+		`CREATE TABLE blocks (
+			number      bigint PRIMARY KEY,
+			hash        text NOT NULL UNIQUE,
+			parent_hash text NOT NULL,
+			miner       text NOT NULL,
+			timestamp   bigint NOT NULL,
+			doc         jsonb NOT NULL
+		)`,
+		`CREATE TABLE transactions (
+			hash              text PRIMARY KEY,
+			block_number      bigint NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+			transaction_index bigint NOT NULL,
+			timestamp         bigint NOT NULL,
+			from_address      text NOT NULL,
+			to_address        text NOT NULL,
+			contract_address  text NOT NULL,
+			value             numeric,
+			status            boolean NOT NULL,
+			contract_deploy   boolean NOT NULL DEFAULT false,
+			contract_call     boolean NOT NULL DEFAULT false,
+			doc               jsonb NOT NULL
+		)`,
+		`CREATE TABLE internal_transactions (
+			id           bigserial PRIMARY KEY,
+			parent_hash  text NOT NULL,
+			block_number bigint NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+			type         text NOT NULL,
+			from_address text NOT NULL,
+			to_address   text NOT NULL,
+			value        numeric,
+			doc          jsonb NOT NULL
+		)`,
+		`CREATE TABLE token_transfers (
+			id           bigserial PRIMARY KEY,
+			block_number bigint NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+			hash         text NOT NULL,
+			log_index    bigint NOT NULL,
+			timestamp    bigint NOT NULL,
+			contract     text NOT NULL,
+			from_address text NOT NULL,
+			to_address   text NOT NULL,
+			value        numeric,
+			doc          jsonb NOT NULL
+		)`,
+		`CREATE TABLE nft_transfers (
+			id           bigserial PRIMARY KEY,
+			block_number bigint NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+			hash         text NOT NULL,
+			log_index    bigint NOT NULL,
+			timestamp    bigint NOT NULL,
+			contract     text NOT NULL,
+			standard     text NOT NULL,
+			token_id     text NOT NULL,
+			from_address text NOT NULL,
+			to_address   text NOT NULL,
+			doc          jsonb NOT NULL
+		)`,
+		`CREATE TABLE uncles (
+			hash         text PRIMARY KEY,
+			block_number bigint NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+			number       bigint NOT NULL,
+			miner        text NOT NULL,
+			timestamp    bigint NOT NULL,
+			doc          jsonb NOT NULL
+		)`,
+		`CREATE TABLE contract_creations (
+			id           bigserial PRIMARY KEY,
+			block_number bigint NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+			hash         text NOT NULL,
+			address      text NOT NULL,
+			creator      text NOT NULL,
+			code_hash    text NOT NULL,
+			internal     boolean NOT NULL,
+			doc          jsonb NOT NULL
+		)`,
+		`CREATE TABLE accounts (
+			address text PRIMARY KEY,
+			balance numeric,
+			block   bigint NOT NULL,
+			doc     jsonb NOT NULL
+		)`,
+		`CREATE TABLE balance_history (
+			address text NOT NULL,
+			block   bigint NOT NULL,
+			balance numeric,
+			doc     jsonb NOT NULL,
+			PRIMARY KEY (address, block)
+		)`,
+		`CREATE TABLE token_balances (
+			contract text NOT NULL,
+			address  text NOT NULL,
+			balance  numeric,
+			block    bigint NOT NULL,
+			doc      jsonb NOT NULL,
+			PRIMARY KEY (contract, address)
+		)`,
+		`CREATE TABLE token_balance_history (
+			contract text NOT NULL,
+			address  text NOT NULL,
+			block    bigint NOT NULL,
+			doc      jsonb NOT NULL,
+			PRIMARY KEY (contract, address, block)
+		)`,
+		`CREATE TABLE forked_blocks (
+			hash      text PRIMARY KEY,
+			number    bigint NOT NULL,
+			timestamp bigint NOT NULL,
+			doc       jsonb NOT NULL
+		)`,
+		`CREATE TABLE reorgs (
+			id     bigserial PRIMARY KEY,
+			number bigint NOT NULL,
+			doc    jsonb NOT NULL
+		)`,
+		`CREATE TABLE tokens (
+			address  text PRIMARY KEY,
+			symbol   text NOT NULL,
+			is_token boolean NOT NULL,
+			updated  bigint NOT NULL,
+			doc      jsonb NOT NULL
+		)`,
+		`CREATE TABLE abis (
+			address text PRIMARY KEY,
+			abi     text NOT NULL,
+			updated bigint NOT NULL
+		)`,
+		`CREATE TABLE verified_contracts (
+			address text PRIMARY KEY,
+			doc     jsonb NOT NULL
+		)`,
+		`CREATE TABLE signatures (
+			id      bigserial PRIMARY KEY,
+			hash    text NOT NULL,
+			text    text NOT NULL,
+			type    text NOT NULL,
+			builtin boolean NOT NULL,
+			UNIQUE (hash, text)
+		)`,
+		`CREATE TABLE enodes (
+			id  bigserial PRIMARY KEY,
+			doc jsonb NOT NULL
+		)`,
+		`CREATE TABLE charts (
+			name text PRIMARY KEY,
+			doc  jsonb NOT NULL
+		)`,
+	}},
+	// the indexes of initIndexes, unique ones are part of the tables above, plus timestamps for the Iter* ranges
+	{2, "create indexes", []string{
+		`CREATE INDEX blocks_miner_idx ON blocks (miner, number DESC)`,
+		`CREATE INDEX blocks_timestamp_idx ON blocks (timestamp)`,
+
+		`CREATE INDEX transactions_block_number_idx ON transactions (block_number, transaction_index)`,
+		`CREATE INDEX transactions_from_idx ON transactions (from_address, block_number DESC)`,
+		`CREATE INDEX transactions_to_idx ON transactions (to_address, block_number DESC)`,
+		`CREATE INDEX transactions_contract_address_idx ON transactions (contract_address)`,
+		`CREATE INDEX transactions_failed_idx ON transactions (block_number DESC) WHERE NOT status`,
+		`CREATE INDEX transactions_contract_deploy_idx ON transactions (block_number DESC) WHERE contract_deploy`,
+		`CREATE INDEX transactions_contract_call_idx ON transactions (block_number DESC) WHERE contract_call`,
+		`CREATE INDEX transactions_timestamp_idx ON transactions (timestamp)`,
+
+		`CREATE INDEX internal_transactions_block_number_idx ON internal_transactions (block_number)`,
+		`CREATE INDEX internal_transactions_from_idx ON internal_transactions (from_address, block_number DESC)`,
+		`CREATE INDEX internal_transactions_to_idx ON internal_transactions (to_address, block_number DESC)`,
+
+		`CREATE INDEX token_transfers_block_number_idx ON token_transfers (block_number)`,
+		`CREATE INDEX token_transfers_hash_idx ON token_transfers (hash, log_index)`,
+		`CREATE INDEX token_transfers_from_idx ON token_transfers (from_address, block_number DESC)`,
+		`CREATE INDEX token_transfers_to_idx ON token_transfers (to_address, block_number DESC)`,
+		`CREATE INDEX token_transfers_contract_idx ON token_transfers (contract, block_number DESC)`,
+		`CREATE INDEX token_transfers_timestamp_idx ON token_transfers (timestamp)`,
+
+		`CREATE INDEX nft_transfers_block_number_idx ON nft_transfers (block_number)`,
+		`CREATE INDEX nft_transfers_hash_idx ON nft_transfers (hash, log_index)`,
+		`CREATE INDEX nft_transfers_token_idx ON nft_transfers (contract, token_id, block_number DESC, log_index DESC)`,
+		`CREATE INDEX nft_transfers_from_idx ON nft_transfers (from_address)`,
+		`CREATE INDEX nft_transfers_to_idx ON nft_transfers (to_address)`,
+
+		`CREATE INDEX uncles_block_number_idx ON uncles (block_number)`,
+		`CREATE INDEX uncles_timestamp_idx ON uncles (timestamp)`,
+
+		`CREATE INDEX contract_creations_address_idx ON contract_creations (address, block_number DESC)`,
+		`CREATE INDEX contract_creations_creator_idx ON contract_creations (creator, block_number DESC)`,
+		`CREATE INDEX contract_creations_code_hash_idx ON contract_creations (code_hash, block_number DESC)`,
+		`CREATE INDEX contract_creations_block_number_idx ON contract_creations (block_number)`,
+
+		`CREATE INDEX accounts_balance_idx ON accounts (balance DESC NULLS LAST, address)`,
+		`CREATE INDEX accounts_block_idx ON accounts (block DESC, address)`,
+		`CREATE INDEX balance_history_block_idx ON balance_history (block)`,
+
+		`CREATE INDEX token_balances_address_idx ON token_balances (address)`,
+		`CREATE INDEX token_balances_balance_idx ON token_balances (contract, balance DESC NULLS LAST, address)`,
+		`CREATE INDEX token_balance_history_block_idx ON token_balance_history (block)`,
+
+		`CREATE INDEX forked_blocks_number_idx ON forked_blocks (number)`,
+		`CREATE INDEX forked_blocks_timestamp_idx ON forked_blocks (timestamp)`,
+		`CREATE INDEX reorgs_number_idx ON reorgs (number)`,
+
+		`CREATE INDEX tokens_symbol_idx ON tokens (symbol) WHERE is_token`,
+		`CREATE INDEX signatures_lookup_idx ON signatures (hash, builtin DESC, id)`,
+	}},
+	// the sorts of the paged lists in postgres_api.go, with the columns that tell rows of a block apart
+	{3, "create paging indexes", []string{
+		`CREATE INDEX uncles_position_idx ON uncles (block_number DESC, ((doc->>'position')::bigint) DESC)`,
+		`CREATE INDEX forked_blocks_paging_idx ON forked_blocks (number DESC, hash)`,
+		`CREATE INDEX reorgs_paging_idx ON reorgs (number DESC, id)`,
+
+		`CREATE INDEX transactions_paging_idx ON transactions (block_number DESC, transaction_index DESC)`,
+		`CREATE INDEX transactions_from_paging_idx ON transactions (from_address, block_number DESC, transaction_index DESC)`,
+		`CREATE INDEX transactions_to_paging_idx ON transactions (to_address, block_number DESC, transaction_index DESC)`,
+		`CREATE INDEX transactions_failed_paging_idx ON transactions (block_number DESC, transaction_index DESC) WHERE NOT status`,
+		`CREATE INDEX transactions_contract_deploy_paging_idx ON transactions (block_number DESC, transaction_index DESC) WHERE contract_deploy`,
+		`CREATE INDEX transactions_contract_call_paging_idx ON transactions (block_number DESC, transaction_index DESC) WHERE contract_call`,
+
+		`CREATE INDEX internal_transactions_from_paging_idx ON internal_transactions (from_address, block_number DESC, id)`,
+		`CREATE INDEX internal_transactions_to_paging_idx ON internal_transactions (to_address, block_number DESC, id)`,
+
+		`CREATE INDEX token_transfers_paging_idx ON token_transfers (block_number DESC, log_index DESC, id)`,
+		`CREATE INDEX token_transfers_contract_paging_idx ON token_transfers (contract, block_number DESC, log_index DESC, id)`,
+		`CREATE INDEX token_transfers_from_paging_idx ON token_transfers (from_address, block_number DESC, log_index DESC, id)`,
+		`CREATE INDEX token_transfers_to_paging_idx ON token_transfers (to_address, block_number DESC, log_index DESC, id)`,
+
+		`CREATE INDEX nft_transfers_contract_paging_idx ON nft_transfers (contract, block_number DESC, log_index DESC, id)`,
+		`CREATE INDEX nft_transfers_from_paging_idx ON nft_transfers (from_address, block_number DESC, log_index DESC, id)`,
+		`CREATE INDEX nft_transfers_to_paging_idx ON nft_transfers (to_address, block_number DESC, log_index DESC, id)`,
+
+		`CREATE INDEX contract_creations_creator_paging_idx ON contract_creations (creator, block_number DESC, address)`,
+		`CREATE INDEX contract_creations_code_hash_paging_idx ON contract_creations (code_hash, block_number DESC, address)`,
+
+		`CREATE INDEX token_balances_address_paging_idx ON token_balances (address, contract)`,
+		`CREATE INDEX tokens_paging_idx ON tokens (symbol, address) WHERE is_token`,
+	}},
+	// uploads can't replace the ABI of a verified contract
+	{4, "flag verified abis", []string{
+		`ALTER TABLE abis ADD COLUMN verified boolean NOT NULL DEFAULT false`,
+		`UPDATE abis SET verified = true WHERE address IN (SELECT address FROM verified_contracts)`,
+	}},
+}
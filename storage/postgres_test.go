@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+// testPostgres migrates a fresh schema on the server at SPECTRUM_TEST_POSTGRES, which is dropped once the test is done
+
+func testPostgres(t *testing.T) *Postgres {
+	dsn := os.Getenv("SPECTRUM_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("SPECTRUM_TEST_POSTGRES isn't set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("spectrum_test_%d", time.Now().UnixNano())
+
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal("couldn't create schema", err)
+	}
+
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal("SPECTRUM_TEST_POSTGRES should be a postgres:// url", err)
+	}
+
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	p := &Postgres{symbol: "UBQ", db: db}
+
+	if err := p.Migrate(); err != nil {
+		t.Fatal("couldn't migrate", err)
+	}
+
+	// migrations are recorded, so running them again is a no-op
+	if err := p.Migrate(); err != nil {
+		t.Fatal("couldn't migrate again", err)
+	}
+
+	genesis := testGenesis()
+
+	if err := p.CommitBlock(genesis); err != nil {
+		t.Fatal("couldn't commit genesis", err)
+	}
+
+	if err := p.setStore(newStore(p.symbol, genesis.Block.Supply)); err != nil {
+		t.Fatal("couldn't set store", err)
+	}
+
+	return p
+}
+
+func TestPostgresRollbackMatchesFreshSync(t *testing.T) {
+	testRollbackMatchesFreshSync(t, testPostgres(t))
+}
+
+func TestPostgresBulkWriter(t *testing.T) {
+	testBulkWriter(t, testPostgres(t))
+}
+
+func TestPostgresReads(t *testing.T) {
+	testReads(t, testPostgres(t))
+}
+
+func TestPostgresPaging(t *testing.T) {
+	testPaging(t, testPostgres(t))
+}
+
+func TestPostgresVerifiedABI(t *testing.T) {
+	testVerifiedABI(t, testPostgres(t))
+}
+
+func TestPostgresDocuments(t *testing.T) {
+	p := testPostgres(t)
+
+	b := testBatch(1, map[string]string{"0xa": "90"})
+	b.Transactions[0].Input = "0x0000a9059cbb00"
+
+	if err := p.CommitBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	txn, err := p.TransactionByHash("0xt1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if txn.Input != "0x0000a9059cbb00" || txn.From != "0xa" {
+		t.Error("transaction didn't round trip", txn.Input, txn.From)
+	}
+
+	block, err := p.BlockByNumber(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(block.Transactions) != len(b.Transactions) {
+		t.Error("block transactions weren't joined back", len(block.Transactions))
+	}
+}
+
+func TestPostgresTokenNulls(t *testing.T) {
+	p := testPostgres(t)
+
+	token := &models.Token{Address: "0xt", Name: "Token\x00\x00", Symbol: "T\x00K", IsToken: true}
+
+	if err := p.AddToken(token); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.TokenInfo("0xt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "Token" || got.Symbol != "TK" {
+		t.Errorf("expected the NULs stripped from name and symbol, got %q %q", got.Name, got.Symbol)
+	}
+
+	if token.Name != "Token\x00\x00" {
+		t.Error("AddToken changed the token it was given")
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Rollback removes every block above height together with the documents derived from them, copying the
+// blocks to forkedblocks and restoring the balances of the accounts they touched. If checkpoint is set,
+// that crawler's checkpoint is moved back to the block at height. It returns the rolled back blocks, highest first
+
+func (m *MongoDB) Rollback(height uint64, checkpoint string) ([]models.Block, error) {
+	var orphaned []models.Block
+
+	err := m.withTransaction(func(ctx context.Context) error {
+		orphaned = make([]models.Block, 0)
+
+		c, err := m.C(models.BLOCKS).Find(ctx, bson.M{"number": bson.M{"$gt": height}}, options.Find().SetSort(bson.D{{"number", -1}}))
+		if err != nil {
+			return err
+		}
+
+		err = c.All(ctx, &orphaned)
+		if err != nil {
+			return err
+		}
+
+		// a block may have been forked before, replace it instead of failing on the unique index
+		for _, b := range orphaned {
+			if _, err := m.C(models.FORKEDBLOCKS).ReplaceOne(ctx, bson.M{"hash": b.Hash}, b, options.Replace().SetUpsert(true)); err != nil {
+				return err
+			}
+		}
+
+		err = m.purgeFrom(ctx, height+1)
+		if err != nil {
+			return err
+		}
+
+		if checkpoint == "" {
+			return nil
+		}
+
+		var ancestor models.Block
+
+		err = m.C(models.BLOCKS).FindOne(ctx, bson.M{"number": height}, options.FindOne()).Decode(&ancestor)
+		if err != nil {
+			return err
+		}
+
+		_, err = m.C(models.STORE).UpdateOne(ctx, bson.M{"crawler": checkpoint}, bson.D{{"$set", &models.Checkpoint{
+			Crawler: checkpoint,
+			Number:  ancestor.Number,
+			Hash:    ancestor.Hash,
+			Updated: time.Now().Unix(),
+		}}}, options.Update().SetUpsert(true))
+
+		return err
+	})
+
+	return orphaned, err
+}
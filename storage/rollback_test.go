@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// These tests need a running mongod, e.g. SPECTRUM_TEST_MONGO=mongodb://127.0.0.1:27017
+// Each run uses a fresh database which is dropped afterwards
+
+func testDB(t *testing.T) *MongoDB {
+	uri := os.Getenv("SPECTRUM_TEST_MONGO")
+	if uri == "" {
+		t.Skip("SPECTRUM_TEST_MONGO not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal("couldn't connect to mongo", err)
+	}
+
+	name := "spectrum-test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	m := &MongoDB{"UBQ", client, client.Database(name), false, defaultTraceThreshold}
+	t.Cleanup(func() {
+		m.db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	if err := m.initIndexes(); err != nil {
+		t.Fatal("couldn't init indexes", err)
+	}
+
+	return m
+}
+
+func testBatch(number uint64, accounts map[string]string) *Batch {
+	var (
+		n      = strconv.FormatUint(number, 10)
+		hash   = "0xb" + n
+		txn    = &models.Transaction{BlockHash: hash, BlockNumber: number, Hash: "0xt" + n, From: "0xa", To: "0xc" + n, Input: "0xa9059cbb", Status: true}
+		call   = &models.Transaction{BlockHash: hash, BlockNumber: number, Hash: "0xcall" + n, From: "0xa", To: "0xc" + n, Input: "0x12345678", Status: true}
+		deploy = &models.Transaction{BlockHash: hash, BlockNumber: number, Hash: "0xd" + n, From: "0xa", ContractAddress: "0xc" + n, Input: "0x6060", Status: true}
+	)
+
+	b := &Batch{
+		Block:          &models.Block{Number: number, Hash: hash, ParentHash: "0xb" + strconv.FormatUint(number-1, 10), Supply: n, TotalBurned: "0"},
+		Transactions:   []*models.Transaction{txn, call, deploy},
+		ITransactions:  []*models.ITransaction{{ParentHash: txn.Hash, BlockNumber: number, Type: "CALL", From: "0xc" + n, To: "0xb", Value: "1"}},
+		TokenTransfers: []*models.TokenTransfer{{BlockNumber: number, Hash: txn.Hash, From: "0xa", To: "0xb", Value: "5", Contract: txn.To, Method: "transfer", Status: true}},
+		Uncles:         []*models.Uncle{{Number: number - 1, BlockNumber: number, Hash: "0xu" + n, Miner: "0xa", Reward: "1"}},
+		Contracts:      []*models.Transaction{deploy},
+		ContractCalls:  []*models.Transaction{call},
+		Checkpoint:     "blocks",
+	}
+
+	for address, balance := range accounts {
+		b.Accounts = append(b.Accounts, &models.Account{Address: address, Balance: balance, Block: number})
+		b.TokenBalances = append(b.TokenBalances, &models.TokenBalance{Contract: "0xtoken", Address: address, Balance: balance, Block: number})
+	}
+
+	return b
+}
+
+// dump returns every document in the db except forked blocks, in a form that can be compared between runs
+
+func dump(t *testing.T, m *MongoDB) map[string][]string {
+	names, err := m.db.ListCollectionNames(context.Background(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := make(map[string][]string)
+
+	for _, name := range names {
+		if name == models.FORKEDBLOCKS {
+			continue
+		}
+
+		var docs []bson.M
+
+		c, err := m.C(name).Find(context.Background(), bson.M{}, options.Find().SetProjection(bson.M{"_id": 0, "updated": 0}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = c.All(context.Background(), &docs); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range docs {
+			b, err := json.Marshal(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res[name] = append(res[name], string(b))
+		}
+
+		sort.Strings(res[name])
+	}
+
+	return res
+}
+
+func TestRollbackMatchesFreshSync(t *testing.T) {
+	m := testDB(t)
+
+	for _, b := range []*Batch{
+		{Block: &models.Block{Number: 0, Hash: "0xb0"}, Accounts: []*models.Account{{Address: "0xa", Balance: "100", Block: 0}}, Checkpoint: "blocks"},
+		testBatch(1, map[string]string{"0xa": "90", "0xb": "10"}),
+	} {
+		if err := m.CommitBlock(b); err != nil {
+			t.Fatal("couldn't commit block", b.Block.Number, err)
+		}
+	}
+
+	fresh := dump(t, m)
+
+	if err := m.CommitBlock(testBatch(2, map[string]string{"0xa": "80", "0xb": "5", "0xc": "15"})); err != nil {
+		t.Fatal("couldn't commit block 2", err)
+	}
+
+	orphaned, err := m.Rollback(1, "blocks")
+	if err != nil {
+		t.Fatal("couldn't roll back", err)
+	}
+
+	if len(orphaned) != 1 || orphaned[0].Hash != "0xb2" {
+		t.Error("unexpected rolled back blocks", orphaned)
+	}
+
+	if forked, err := m.ForkedBlockByNumber(2); err != nil || forked.Hash != "0xb2" {
+		t.Error("rolled back block not in forkedblocks", forked.Hash, err)
+	}
+
+	if after := dump(t, m); !reflect.DeepEqual(fresh, after) {
+		t.Error("db after rollback doesn't match fresh sync", "\nfresh: ", fresh, "\nafter: ", after)
+	}
+
+	// PurgeBlock on the head should get us to the same state
+	if err := m.CommitBlock(testBatch(2, map[string]string{"0xa": "70", "0xd": "1"})); err != nil {
+		t.Fatal("couldn't commit block 2", err)
+	}
+
+	if err := m.SetCheckpoint("blocks", 1, "0xb1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.PurgeBlock(2); err != nil {
+		t.Fatal("couldn't purge block", err)
+	}
+
+	if after := dump(t, m); !reflect.DeepEqual(fresh, after) {
+		t.Error("db after purge doesn't match fresh sync", "\nfresh: ", fresh, "\nafter: ", after)
+	}
+}
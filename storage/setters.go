@@ -75,6 +75,8 @@ func (m *MongoDB) AddBlock(b *models.Block) error {
 	return nil
 }
 
+// AddAccount sets the current balance of an account, and appends it to the account's balance history
+
 func (m *MongoDB) AddAccount(a *models.Account) error {
 	collection := m.C(models.ACCOUNTS)
 
@@ -82,11 +84,18 @@ func (m *MongoDB) AddAccount(a *models.Account) error {
 		Address: a.Address,
 		Balance: a.Balance,
 		Block:   a.Block,
+		Nonce:   a.Nonce,
+		HasCode: a.HasCode,
 	}}}, options.Update().SetUpsert(true)); err != nil {
 		return err
 	}
 
-	return nil
+	prev, err := m.lastBalances(context.Background(), []string{a.Address}, a.Block)
+	if err != nil {
+		return err
+	}
+
+	return insertMany(context.Background(), m.C(models.BALANCES), balanceRecords([]*models.Account{a}, prev))
 }
 
 func (m *MongoDB) AddForkedBlock(b *models.Block) error {
@@ -98,6 +107,24 @@ func (m *MongoDB) AddForkedBlock(b *models.Block) error {
 	return nil
 }
 
+func (m *MongoDB) AddReorg(r *models.Reorg) error {
+	collection := m.C(models.REORGS)
+
+	if _, err := collection.InsertOne(context.Background(), r, options.InsertOne()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *MongoDB) AddToken(t *models.Token) error {
+	collection := m.C(models.TOKENS)
+
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"address": t.Address}, bson.D{{"$set", t}}, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (m *MongoDB) AddEnodes(e *models.Enode) error {
 	collection := m.C(models.ENODES)
 
@@ -136,6 +163,19 @@ func (m *MongoDB) AddNumberStringChart(name string, series []string, stamps []st
 func (m *MongoDB) AddMultiSeriesChart(name string, series map[string]map[string]uint, stamps []string) error {
 	collection := m.C(models.CHARTS)
 
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"name": name}, bson.D{{"$set", &models.MultiSeriesChart{
+		Name:       name,
+		Datasets:   multiSeriesDatasets(series),
+		Timestamps: stamps,
+	}}}, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// multiSeriesDatasets turns series of values by date into datasets sorted by date, ordered by their numeric name
+
+func multiSeriesDatasets(series map[string]map[string]uint) []*models.MultiSeriesDataset {
 	datasets := make([]*models.MultiSeriesDataset, 0)
 
 	for k, v := range series {
@@ -177,12 +217,5 @@ func (m *MongoDB) AddMultiSeriesChart(name string, series map[string]map[string]
 		return sI.Cmp(sj) == -1
 	})
 
-	if _, err := collection.UpdateOne(context.Background(), bson.M{"name": name}, bson.D{{"$set", &models.MultiSeriesChart{
-		Name:       name,
-		Datasets:   datasets,
-		Timestamps: stamps,
-	}}}, options.Update().SetUpsert(true)); err != nil {
-		return err
-	}
-	return nil
+	return datasets
 }
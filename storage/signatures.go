@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const signatureBatchSize = 10000
+
+// ImportSignatures adds signatures that aren't in the db yet, and returns how many were added
+
+func (m *MongoDB) ImportSignatures(sigs []models.Signature) (int64, error) {
+	var added int64
+
+	for start := 0; start < len(sigs); start += signatureBatchSize {
+		end := start + signatureBatchSize
+		if end > len(sigs) {
+			end = len(sigs)
+		}
+
+		writes := make([]mongo.WriteModel, 0, end-start)
+
+		for i := range sigs[start:end] {
+			s := &sigs[start+i]
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"hash": s.Hash, "text": s.Text}).SetUpdate(bson.D{{"$setOnInsert", s}}).SetUpsert(true))
+		}
+
+		r, err := m.C(models.SIGNATURES).BulkWrite(context.Background(), writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return added, err
+		}
+
+		added += r.UpsertedCount
+	}
+
+	return added, nil
+}
+
+// LookupSelector returns the text signatures matching a 4-byte function selector or a 32-byte event topic,
+// best guess first: built in signatures, then the ones imported first
+
+func (m *MongoDB) LookupSelector(hash string) ([]models.Signature, error) {
+	var sigs = make([]models.Signature, 0)
+
+	c, err := m.C(models.SIGNATURES).Find(context.Background(), bson.M{"hash": strings.ToLower(hash)}, options.Find().SetSort(bson.D{{"builtin", -1}, {"_id", 1}}))
+	if err != nil {
+		return sigs, err
+	}
+
+	err = c.All(context.Background(), &sigs)
+	return sigs, err
+}
+
+// MethodSignature returns the best guess text signature for the function selector at the start of input,
+// or an empty string if there's none
+
+func (m *MongoDB) MethodSignature(input string) (string, error) {
+	var sig models.Signature
+
+	if len(input) < 10 {
+		return "", nil
+	}
+
+	err := m.C(models.SIGNATURES).FindOne(context.Background(), bson.M{"hash": strings.ToLower(input[:10])}, options.FindOne().SetSort(bson.D{{"builtin", -1}, {"_id", 1}})).Decode(&sig)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+
+	return sig.Text, err
+}
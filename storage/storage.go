@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/octanolabs/go-spectrum/models"
@@ -14,21 +15,42 @@ import (
 )
 
 type Config struct {
+	// Type is the database to use, "mongo" (the default), "postgres", or "memory" which keeps everything in the
+	// process and loses it on exit
+	Type     string `json:"type"`
 	Symbol   string `json:"symbol"`
 	User     string `json:"user"`
 	Password string `json:"password"`
 	Database string `json:"database"`
 	Address  string `json:"address"`
+	// Write each block and its documents in a single transaction, requires a replica set
+	Transactions bool `json:"transactions"`
+	// Traces larger than this many bytes are compressed and stored in the traces collection
+	TraceThreshold int `json:"trace_threshold"`
+	// SSLMode is passed on to postgres, e.g. "disable" for a local server
+	SSLMode string `json:"sslmode"`
 }
 
 func (c *Config) ConnectionString() string {
+	if c.Type == BackendPostgres {
+		u := url.URL{Scheme: "postgres", User: url.UserPassword(c.User, c.Password), Host: c.Address, Path: "/" + c.Database}
+
+		if c.SSLMode != "" {
+			u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+		}
+
+		return u.String()
+	}
+
 	return fmt.Sprint("mongodb://", c.User, ":", c.Password, "@", c.Address, "/", c.Database)
 }
 
 type MongoDB struct {
-	symbol string
-	client *mongo.Client
-	db     *mongo.Database
+	symbol         string
+	client         *mongo.Client
+	db             *mongo.Database
+	transactions   bool
+	traceThreshold int
 }
 
 func NewConnection(cfg *Config) (*MongoDB, error) {
@@ -51,7 +73,38 @@ func NewConnection(cfg *Config) (*MongoDB, error) {
 		log.Error("couldn't connect to mongo", "err", err)
 	}
 
-	return &MongoDB{cfg.Symbol, client, client.Database(cfg.Database, options.Database())}, nil
+	m := &MongoDB{cfg.Symbol, client, client.Database(cfg.Database, options.Database()), false, cfg.TraceThreshold}
+
+	if m.traceThreshold <= 0 {
+		m.traceThreshold = defaultTraceThreshold
+	}
+
+	if cfg.Transactions {
+		m.transactions = m.supportsTransactions()
+
+		if !m.transactions {
+			log.Warn("mongo is not a replica set, transactions disabled")
+		}
+	}
+
+	return m, nil
+}
+
+// supportsTransactions checks whether we're connected to a replica set member or mongos, standalone servers can't run transactions
+
+func (m *MongoDB) supportsTransactions() bool {
+	var res struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := m.db.RunCommand(context.Background(), bson.D{{"isMaster", 1}}).Decode(&res)
+	if err != nil {
+		log.Error("couldn't get server topology", "err", err)
+		return false
+	}
+
+	return res.SetName != "" || res.Msg == "isdbgrid"
 }
 
 func (m *MongoDB) C(coll string) *mongo.Collection {
@@ -60,7 +113,7 @@ func (m *MongoDB) C(coll string) *mongo.Collection {
 
 func (m *MongoDB) IsFirstRun() bool {
 
-	err := m.C(models.STORE).FindOne(context.Background(), bson.M{}, options.FindOne()).Err()
+	err := m.C(models.STORE).FindOne(context.Background(), bson.M{"symbol": m.symbol}, options.FindOne()).Err()
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -77,30 +130,104 @@ func (m *MongoDB) Ping() error {
 	return m.client.Ping(context.Background(), nil)
 }
 
+// PurgeBlock removes the block at height and every document derived from it, restoring the accounts it touched
+// to their previous balance. Blocks above height are built on top of it, so they are removed as well
+
 func (m *MongoDB) PurgeBlock(height uint64) error {
+	return m.PurgeFrom(height)
+}
+
+// PurgeFrom removes every document written for blocks at or above height, it's used to clean up
+// after a block that was only partially written and to roll back reorgs
 
-	r, err := m.C(models.BLOCKS).DeleteOne(context.Background(), bson.M{"number": height}, options.Delete())
+func (m *MongoDB) PurgeFrom(height uint64) error {
+	return m.withTransaction(func(ctx context.Context) error {
+		return m.purgeFrom(ctx, height)
+	})
+}
+
+func (m *MongoDB) purgeFrom(ctx context.Context, height uint64) error {
+
+	r, err := m.C(models.BLOCKS).DeleteMany(ctx, bson.M{"number": bson.M{"$gte": height}}, options.Delete())
 
 	if err != nil {
 		return err
 	}
-	log.Debug("purged %v blocks", "count", r.DeletedCount)
+	log.Debug("purged blocks", "from", height, "count", r.DeletedCount)
+
+	for _, coll := range []string{models.TRANSACTIONS, models.ITRANSACTIONS, models.TRANSFERS, models.UNCLES, models.CONTRACTS, models.CONTRACTCALLS, models.TRACES, models.NFTTRANSFERS, models.CREATIONS} {
+		r, err = m.C(coll).DeleteMany(ctx, bson.M{"blockNumber": bson.M{"$gte": height}}, options.Delete())
 
-	r, err = m.C(models.TRANSACTIONS).DeleteMany(context.Background(), bson.M{"blockNumber": height}, options.Delete())
+		if err != nil {
+			return err
+		}
+		log.Debug("purged documents", "collection", coll, "from", height, "count", r.DeletedCount)
+	}
 
+	err = m.restoreAccounts(ctx, height)
 	if err != nil {
 		return err
 	}
-	log.Debug("purged %v transactions", "count", r.DeletedCount)
 
-	r, err = m.C(models.TRANSFERS).DeleteMany(context.Background(), bson.M{"blockNumber": height}, options.Delete())
+	return m.restoreTokenBalances(ctx, height)
+}
+
+// restoreAccounts sets accounts touched at or above height back to their last balance below it,
+// accounts that weren't seen before height are removed. Below the block balance history starts from, an account
+// without a record may predate the history, so it's left as it is
+
+func (m *MongoDB) restoreAccounts(ctx context.Context, height uint64) error {
 
+	addresses, err := m.C(models.BALANCES).Distinct(ctx, "address", bson.M{"block": bson.M{"$gte": height}}, options.Distinct())
 	if err != nil {
 		return err
 	}
-	log.Debug("purged %v transfers", "count", r.DeletedCount)
-	return nil
 
+	r, err := m.C(models.BALANCES).DeleteMany(ctx, bson.M{"block": bson.M{"$gte": height}}, options.Delete())
+	if err != nil {
+		return err
+	}
+	log.Debug("purged documents", "collection", models.BALANCES, "from", height, "count", r.DeletedCount)
+
+	schema, err := m.schema(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range addresses {
+		var (
+			address = v.(string)
+			prev    models.BalanceRecord
+		)
+
+		err := m.C(models.BALANCES).FindOne(ctx, bson.M{"address": address}, options.FindOne().SetSort(bson.D{{"block", -1}})).Decode(&prev)
+
+		if err == mongo.ErrNoDocuments && height < schema.BalanceHistoryFrom {
+			log.Warn("no balance history to restore account from, leaving it as it is", "address", address, "from", height)
+			continue
+		} else if err == mongo.ErrNoDocuments {
+			if _, err := m.C(models.ACCOUNTS).DeleteOne(ctx, bson.M{"address": address}, options.Delete()); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := m.C(models.ACCOUNTS).UpdateOne(ctx, bson.M{"address": address}, bson.D{{"$set", &models.Account{
+			Address: address,
+			Balance: prev.Balance,
+			Block:   prev.Block,
+			Nonce:   prev.Nonce,
+			HasCode: prev.HasCode,
+		}}}, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+
+	log.Debug("restored accounts", "from", height, "count", len(addresses))
+
+	return nil
 }
 
 func (m *MongoDB) IsEnodePresent(id string) bool {
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/ubiq/go-ubiq/v7/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// balances are stored as decimal strings, this collation compares them by value
+var numericCollation = &options.Collation{Locale: "en", NumericOrdering: true}
+
+// tokenBalanceModel returns the write that sets a holder's balance, holders left with nothing are removed
+
+func tokenBalanceModel(tb *models.TokenBalance) mongo.WriteModel {
+	filter := bson.M{"contract": tb.Contract, "address": tb.Address}
+
+	if tb.Balance == "0" {
+		return mongo.NewDeleteOneModel().SetFilter(filter)
+	}
+
+	return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.D{{"$set", tb}}).SetUpsert(true)
+}
+
+func (m *MongoDB) writeTokenBalances(ctx context.Context, balances []*models.TokenBalance) error {
+	if len(balances) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, len(balances))
+	for i, tb := range balances {
+		writes[i] = tokenBalanceModel(tb)
+	}
+
+	// a holder may appear more than once, the last balance has to win
+	if _, err := m.C(models.TOKENBALANCES).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true)); err != nil {
+		return err
+	}
+
+	return insertMany(ctx, m.C(models.TOKENHISTORY), balances)
+}
+
+// restoreTokenBalances sets token holders touched at or above height back to their last balance below it,
+// holders that didn't hold the token before height are removed
+
+func (m *MongoDB) restoreTokenBalances(ctx context.Context, height uint64) error {
+	var touched []models.TokenBalance
+
+	filter := bson.M{"block": bson.M{"$gte": height}}
+
+	c, err := m.C(models.TOKENHISTORY).Find(ctx, filter, options.Find().SetProjection(bson.M{"contract": 1, "address": 1}))
+	if err != nil {
+		return err
+	}
+
+	if err = c.All(ctx, &touched); err != nil {
+		return err
+	}
+
+	r, err := m.C(models.TOKENHISTORY).DeleteMany(ctx, filter, options.Delete())
+	if err != nil {
+		return err
+	}
+	log.Debug("purged documents", "collection", models.TOKENHISTORY, "from", height, "count", r.DeletedCount)
+
+	seen := make(map[[2]string]bool)
+
+	for _, t := range touched {
+		key := [2]string{t.Contract, t.Address}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var prev models.TokenBalance
+
+		err := m.C(models.TOKENHISTORY).FindOne(ctx, bson.M{"contract": t.Contract, "address": t.Address}, options.FindOne().SetSort(bson.D{{"block", -1}})).Decode(&prev)
+
+		if err == mongo.ErrNoDocuments {
+			prev = models.TokenBalance{Contract: t.Contract, Address: t.Address, Balance: "0"}
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := m.C(models.TOKENBALANCES).BulkWrite(ctx, []mongo.WriteModel{tokenBalanceModel(&prev)}, options.BulkWrite()); err != nil {
+			return err
+		}
+	}
+
+	log.Debug("restored token balances", "from", height, "count", len(seen))
+
+	return nil
+}
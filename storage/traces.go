@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultTraceThreshold = 1 << 20
+	// well below the 16MB document limit
+	traceChunkSize = 4 << 20
+)
+
+// chunkedTrace is what's stored in the chunks of a transaction, its internal transactions each carry their
+// nested calls so they are as large as the trace
+
+type chunkedTrace struct {
+	Trace         models.ITransaction   `bson:"trace"`
+	ITransactions []models.ITransaction `bson:"iTransactions"`
+}
+
+// splitTraces moves the traces and internal transactions of transactions larger than threshold out of the batch
+// documents, into gzipped chunks written to the traces collection. The internal transactions written on their
+// own lose their nested calls. It's safe to call more than once on the same batch
+
+func (b *Batch) splitTraces(threshold int) error {
+	split := make(map[string]int)
+
+	for _, tx := range b.Transactions {
+		if tx.TraceChunks > 0 {
+			split[tx.Hash] = tx.TraceChunks
+			continue
+		}
+
+		raw, err := bson.Marshal(&chunkedTrace{tx.Trace, tx.ITransactions})
+		if err != nil {
+			return err
+		}
+
+		if len(raw) <= threshold {
+			continue
+		}
+
+		chunks, err := compressTrace(tx.Hash, tx.BlockNumber, raw)
+		if err != nil {
+			return err
+		}
+
+		b.traces = append(b.traces, chunks...)
+
+		// contracts and contract calls share these pointers
+		tx.Trace = models.ITransaction{}
+		tx.ITransactions = nil
+		tx.TraceChunks = len(chunks)
+
+		split[tx.Hash] = len(chunks)
+	}
+
+	if len(split) == 0 {
+		return nil
+	}
+
+	for _, itxn := range b.ITransactions {
+		if _, ok := split[itxn.ParentHash]; ok {
+			itxn.Calls = nil
+			itxn.CallsOmitted = true
+		}
+	}
+
+	if b.Block == nil {
+		return nil
+	}
+
+	for i := range b.Block.Transactions {
+		if n, ok := split[b.Block.Transactions[i].Hash]; ok {
+			b.Block.Transactions[i].Trace = models.ITransaction{}
+			b.Block.Transactions[i].ITransactions = nil
+			b.Block.Transactions[i].TraceChunks = n
+		}
+	}
+
+	kept := b.Block.ITransactions[:0]
+
+	for _, itxn := range b.Block.ITransactions {
+		if _, ok := split[itxn.ParentHash]; !ok {
+			kept = append(kept, itxn)
+		}
+	}
+
+	b.Block.ITransactions = kept
+
+	return nil
+}
+
+func compressTrace(hash string, blockNumber uint64, raw []byte) ([]*models.TraceChunk, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	var (
+		data   = buf.Bytes()
+		chunks []*models.TraceChunk
+	)
+
+	for i := 0; i < len(data); i += traceChunkSize {
+		end := i + traceChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunks = append(chunks, &models.TraceChunk{Hash: hash, BlockNumber: blockNumber, Index: len(chunks), Data: data[i:end]})
+	}
+
+	return chunks, nil
+}
+
+// loadTrace reassembles a trace stored in chunks
+
+func (m *MongoDB) loadTrace(hash string, count int) (chunkedTrace, error) {
+	var (
+		trace  chunkedTrace
+		chunks = make([]models.TraceChunk, 0, count)
+		data   []byte
+	)
+
+	c, err := m.C(models.TRACES).Find(context.Background(), bson.M{"hash": hash}, options.Find().SetSort(bson.D{{"index", 1}}))
+	if err != nil {
+		return trace, err
+	}
+
+	err = c.All(context.Background(), &chunks)
+	if err != nil {
+		return trace, err
+	}
+
+	if len(chunks) != count {
+		return trace, fmt.Errorf("trace of %v has %v chunks, expected %v", hash, len(chunks), count)
+	}
+
+	for _, chunk := range chunks {
+		data = append(data, chunk.Data...)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return trace, err
+	}
+
+	raw, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return trace, err
+	}
+
+	err = bson.Unmarshal(raw, &trace)
+
+	return trace, err
+}
+
+// restoreTrace puts back the trace and internal transactions of txn if they were stored in chunks
+
+func (m *MongoDB) restoreTrace(txn *models.Transaction) error {
+	if txn.TraceChunks == 0 {
+		return nil
+	}
+
+	trace, err := m.loadTrace(txn.Hash, txn.TraceChunks)
+	if err != nil {
+		return err
+	}
+
+	txn.Trace = trace.Trace
+	txn.ITransactions = trace.ITransactions
+	txn.TraceChunks = 0
+
+	return nil
+}
+
+// restoreBlockTraces puts back what was stored in chunks for the transactions of block, their internal
+// transactions go back in the block's after the others
+
+func (m *MongoDB) restoreBlockTraces(block *models.Block) error {
+	for i := range block.Transactions {
+		txn := &block.Transactions[i]
+
+		if txn.TraceChunks == 0 {
+			continue
+		}
+
+		if err := m.restoreTrace(txn); err != nil {
+			return err
+		}
+
+		block.ITransactions = append(block.ITransactions, txn.ITransactions...)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/octanolabs/go-spectrum/models"
+)
+
+func TestLargeTraceIsReassembled(t *testing.T) {
+	m := testDB(t)
+	m.traceThreshold = 1024
+
+	trace := models.ITransaction{Type: "CALL", From: "0xa", To: "0xc1", Gas: "0", GasUsed: "0", Input: "0x", Output: "0x"}
+	for i := 0; i < 100; i++ {
+		trace.Calls = append(trace.Calls, models.ITransaction{Type: "CALL", From: "0xc1", To: "0xd" + strconv.Itoa(i), Gas: "1", GasUsed: "1", Input: "0x12345678", Output: "0x"})
+	}
+
+	b := largeTraceBatch(trace)
+	itxns := append([]models.ITransaction{}, b.Transactions[0].ITransactions...)
+
+	if err := m.CommitBlock(b); err != nil {
+		t.Fatal("couldn't commit block", err)
+	}
+
+	if b.Transactions[0].TraceChunks == 0 || b.Block.Transactions[0].TraceChunks == 0 {
+		t.Fatal("trace wasn't moved out of the transaction")
+	}
+
+	got, err := m.TxTrace(b.Transactions[0].Hash)
+	if err != nil {
+		t.Fatal("couldn't get trace", err)
+	}
+
+	if !reflect.DeepEqual(got, trace) {
+		t.Error("reassembled trace doesn't match", got)
+	}
+
+	txn, err := m.TransactionByHash(b.Transactions[0].Hash)
+	if err != nil {
+		t.Fatal("couldn't get transaction", err)
+	}
+
+	if !reflect.DeepEqual(txn.Trace, trace) || !reflect.DeepEqual(txn.ITransactions, itxns) {
+		t.Error("transaction trace wasn't reassembled", txn.Trace, txn.ITransactions)
+	}
+
+	block, err := m.BlockByNumber(1)
+	if err != nil {
+		t.Fatal("couldn't get block", err)
+	}
+
+	if !reflect.DeepEqual(block.Transactions[0].Trace, trace) || !reflect.DeepEqual(block.ITransactions, itxns) {
+		t.Error("block trace wasn't reassembled", block.Transactions[0].Trace, block.ITransactions)
+	}
+}
+
+func TestSplitTraces(t *testing.T) {
+	trace := models.ITransaction{Type: "CALL", From: "0xa", To: "0xc1", Calls: []models.ITransaction{
+		{Type: "CALL", From: "0xc1", To: "0xd1", Calls: []models.ITransaction{{Type: "CALL", From: "0xd1", To: "0xe1"}}},
+	}}
+
+	b := largeTraceBatch(trace)
+
+	// the other transactions of the batch have no trace and stay as they are, splitting again changes nothing
+	for i := 0; i < 2; i++ {
+		if err := b.splitTraces(256); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(b.traces) != 1 {
+		t.Fatal("expected the trace in a single chunk, got", len(b.traces))
+	}
+
+	for _, txn := range []*models.Transaction{b.Transactions[0], &b.Block.Transactions[0]} {
+		if txn.TraceChunks != 1 || txn.Trace.Type != "" || txn.ITransactions != nil {
+			t.Error("trace and internal transactions weren't moved out of the transaction", txn)
+		}
+	}
+
+	if len(b.Block.ITransactions) != 0 {
+		t.Error("internal transactions were left in the block", b.Block.ITransactions)
+	}
+
+	for _, itxn := range b.ITransactions {
+		if itxn.Calls != nil || !itxn.CallsOmitted {
+			t.Error("internal transaction kept its calls", itxn)
+		}
+	}
+}
+
+// largeTraceBatch returns a block with a single transaction traced as trace, and its internal transactions
+func largeTraceBatch(trace models.ITransaction) *Batch {
+	b := testBatch(1, nil)
+	txn := b.Transactions[0]
+
+	txn.Trace = trace
+	txn.ITransactions = nil
+	b.ITransactions = nil
+
+	// every call below the root, with the calls nested under it, as the crawler flattens them
+	var flatten func(calls []models.ITransaction)
+	flatten = func(calls []models.ITransaction) {
+		for _, call := range calls {
+			call.ParentHash, call.BlockNumber = txn.Hash, txn.BlockNumber
+			txn.ITransactions = append(txn.ITransactions, call)
+			flatten(call.Calls)
+		}
+	}
+	flatten(trace.Calls)
+
+	for i := range txn.ITransactions {
+		b.ITransactions = append(b.ITransactions, &txn.ITransactions[i])
+	}
+
+	block := *b.Block
+	block.Transactions = []models.Transaction{*txn}
+	block.ITransactions = append([]models.ITransaction{}, txn.ITransactions...)
+	b.Block = &block
+
+	return b
+}
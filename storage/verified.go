@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AddVerifiedContract stores the sources of a verified contract, and its ABI in the registry so that calls to it get decoded
+
+func (m *MongoDB) AddVerifiedContract(c *models.VerifiedContract) error {
+	if _, err := m.C(models.VERIFIED).UpdateOne(context.Background(), bson.M{"address": c.Address}, bson.D{{"$set", c}}, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	return m.addContractABI(c.Address, c.ABI, true)
+}
+
+func (m *MongoDB) VerifiedContract(address string) (models.VerifiedContract, error) {
+	var c models.VerifiedContract
+
+	err := m.C(models.VERIFIED).FindOne(context.Background(), bson.M{"address": strings.ToLower(address)}, options.FindOne()).Decode(&c)
+	return c, err
+}
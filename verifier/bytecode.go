@@ -0,0 +1,73 @@
+package verifier
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"regexp"
+)
+
+// library addresses are left as placeholders in the compiler output until linking:
+// __$<34 hex chars of the name hash>$__ since 0.5, __<name padded with _>__ before
+var placeholderRegexp = regexp.MustCompile(`__.{36}__`)
+
+var errNoMatch = errors.New("compiled bytecode doesn't match the deployed code")
+
+// immutableRef is a byte range in the runtime code that the constructor fills in with an immutable value
+type immutableRef struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// splitMetadata separates the cbor encoded metadata solc appends to the code. Its length is stored in the
+// last two bytes; code without a valid length is returned whole
+func splitMetadata(code []byte) ([]byte, []byte) {
+	if len(code) < 2 {
+		return code, nil
+	}
+
+	n := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	if n == 0 || n+2 > len(code) {
+		return code, nil
+	}
+
+	return code[:len(code)-n-2], code[len(code)-n-2:]
+}
+
+// matchCode compares the hex object compiled by solc with the deployed runtime code. Library placeholders and
+// immutables are taken from the deployed code, then both are compared without their metadata.
+// It returns whether the metadata matched as well
+func matchCode(object string, deployed []byte, immutables map[string][]immutableRef) (bool, error) {
+	deployedHex := hex.EncodeToString(deployed)
+
+	if len(object) != len(deployedHex) {
+		return false, errNoMatch
+	}
+
+	for _, loc := range placeholderRegexp.FindAllStringIndex(object, -1) {
+		object = object[:loc[0]] + deployedHex[loc[0]:loc[1]] + object[loc[1]:]
+	}
+
+	compiled, err := hex.DecodeString(object)
+	if err != nil {
+		return false, err
+	}
+
+	for _, refs := range immutables {
+		for _, ref := range refs {
+			if ref.Start < 0 || ref.Start+ref.Length > len(compiled) {
+				return false, errNoMatch
+			}
+			copy(compiled[ref.Start:ref.Start+ref.Length], deployed[ref.Start:ref.Start+ref.Length])
+		}
+	}
+
+	compiledCode, compiledMeta := splitMetadata(compiled)
+	deployedCode, deployedMeta := splitMetadata(deployed)
+
+	if !bytes.Equal(compiledCode, deployedCode) {
+		return false, errNoMatch
+	}
+
+	return bytes.Equal(compiledMeta, deployedMeta), nil
+}
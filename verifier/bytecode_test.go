@@ -0,0 +1,39 @@
+package verifier
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMatchCode(t *testing.T) {
+	var (
+		body      = "6080604052" + "73" + strings.Repeat("ab", 20) + "7f" + strings.Repeat("11", 32) + "00"
+		meta      = "a264697066735822" + strings.Repeat("12", 34) + "0033"
+		otherMeta = "a264697066735822" + strings.Repeat("34", 34) + "0033"
+	)
+
+	deployed, _ := hex.DecodeString(body + meta)
+
+	// library address as a placeholder, immutable left as zeros
+	object := "6080604052" + "73" + "__$" + strings.Repeat("0", 34) + "$__" + "7f" + strings.Repeat("00", 32) + "00"
+	immutables := map[string][]immutableRef{"3": {{Start: 27, Length: 32}}}
+
+	full, err := matchCode(object+meta, deployed, immutables)
+	if err != nil || !full {
+		t.Errorf("expected full match, got %v %v", full, err)
+	}
+
+	full, err = matchCode(object+otherMeta, deployed, immutables)
+	if err != nil || full {
+		t.Errorf("expected partial match, got %v %v", full, err)
+	}
+
+	if _, err := matchCode(object+meta, deployed, nil); err != errNoMatch {
+		t.Errorf("matched without immutables: %v", err)
+	}
+
+	if _, err := matchCode(strings.Replace(object, "6080", "6081", 1)+meta, deployed, immutables); err != errNoMatch {
+		t.Errorf("matched different code: %v", err)
+	}
+}
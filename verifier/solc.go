@@ -0,0 +1,131 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// versions look like 0.8.19 or v0.8.19+commit.7dd6d404, which also keeps them from escaping the compiler directory
+var versionRegexp = regexp.MustCompile(`^v?\d+\.\d+\.\d+(\+commit\.[0-9a-f]{8})?$`)
+
+type solcOutput struct {
+	Errors []struct {
+		Severity         string `json:"severity"`
+		FormattedMessage string `json:"formattedMessage"`
+	} `json:"errors"`
+	Contracts map[string]map[string]struct {
+		ABI json.RawMessage `json:"abi"`
+		EVM struct {
+			DeployedBytecode struct {
+				Object              string                    `json:"object"`
+				ImmutableReferences map[string][]immutableRef `json:"immutableReferences"`
+			} `json:"deployedBytecode"`
+		} `json:"evm"`
+	} `json:"contracts"`
+}
+
+// findCompiler looks for a solc binary of the given version in the compiler directory. Binaries are named
+// solc-<version>, with or without the leading v and the commit, as in the official release lists
+
+func (v *Verifier) findCompiler(version string) (string, error) {
+	if !versionRegexp.MatchString(version) {
+		return "", fmt.Errorf("invalid compiler version %q", version)
+	}
+
+	version = strings.TrimPrefix(version, "v")
+
+	files, err := ioutil.ReadDir(v.cfg.SolcDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), "solc-") {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(f.Name(), "solc-"), "v")
+
+		if name == version || strings.HasPrefix(name, version+"+") {
+			return filepath.Join(v.cfg.SolcDir, f.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("compiler %v not found", version)
+}
+
+// compile runs solc with the standard json interface, asking only for what verification needs
+
+func (v *Verifier) compile(solc string, sources map[string]string, settings json.RawMessage) (*solcOutput, error) {
+	var (
+		input = map[string]interface{}{
+			"language": "Solidity",
+		}
+		s      = make(map[string]interface{})
+		files  = make(map[string]interface{})
+		out    solcOutput
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+	)
+
+	if len(settings) > 0 {
+		if err := json.Unmarshal(settings, &s); err != nil {
+			return nil, fmt.Errorf("invalid compiler settings: %v", err)
+		}
+	}
+
+	s["outputSelection"] = map[string]interface{}{
+		"*": map[string]interface{}{
+			"*": []string{"abi", "evm.deployedBytecode.object", "evm.deployedBytecode.immutableReferences"},
+		},
+	}
+
+	for name, content := range sources {
+		files[name] = map[string]string{"content": content}
+	}
+
+	input["sources"] = files
+	input["settings"] = s
+
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, solc, "--standard-json")
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("solc failed: %v %s", err, stderr.String())
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, err
+	}
+
+	var msgs []string
+	for _, e := range out.Errors {
+		if e.Severity == "error" {
+			msgs = append(msgs, e.FormattedMessage)
+		}
+	}
+
+	if len(msgs) > 0 {
+		return nil, errors.New(strings.Join(msgs, "\n"))
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,169 @@
+// Package verifier checks that solidity sources compile to the code deployed at an address, using solc
+// binaries from a local directory
+package verifier
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ubiq/go-ubiq/v7/common"
+	"github.com/ubiq/go-ubiq/v7/common/hexutil"
+	"github.com/ubiq/go-ubiq/v7/log"
+
+	"github.com/octanolabs/go-spectrum/models"
+	"github.com/octanolabs/go-spectrum/rpc"
+	"github.com/octanolabs/go-spectrum/storage"
+)
+
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// SolcDir holds the compiler binaries, named solc-<version>
+	SolcDir string `json:"solc_dir"`
+	// Timeout of a single compilation
+	Timeout string `json:"timeout"`
+}
+
+// Verifier is served by the api along with the storage methods, so its exported methods are api methods
+
+type Verifier struct {
+	backend storage.Backend
+	rpc     *rpc.RPCClient
+	cfg     *Config
+	logger  log.Logger
+	timeout time.Duration
+	// compiling is cpu heavy, requests are served one at a time
+	mu sync.Mutex
+}
+
+// VerifyRequest holds the sources of a contract and how they were compiled. ContractName can be prefixed
+// with the source file, as in "Token.sol:Token", when the name is used in more than one file
+
+type VerifyRequest struct {
+	Address         string            `json:"address"`
+	ContractName    string            `json:"contractName"`
+	CompilerVersion string            `json:"compilerVersion"`
+	Sources         map[string]string `json:"sources"`
+	Settings        json.RawMessage   `json:"settings"`
+}
+
+func NewVerifier(db storage.Backend, cfg *Config, logger log.Logger, rpc *rpc.RPCClient) *Verifier {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		logger.Error("can't parse compiler timeout, using 60s", "d", cfg.Timeout, "err", err)
+		timeout = time.Minute
+	}
+
+	return &Verifier{backend: db, rpc: rpc, cfg: cfg, logger: logger, timeout: timeout}
+}
+
+// VerifyContract compiles the sources in req and compares the named contract with the code deployed at
+// req.Address. On a match the sources are stored, and the ABI is added to the registry. A full match is never
+// replaced by a partial one
+
+func (v *Verifier) VerifyContract(req VerifyRequest) (*models.VerifiedContract, error) {
+	address := strings.ToLower(req.Address)
+
+	if !common.IsHexAddress(address) {
+		return nil, errors.New("invalid address")
+	}
+
+	if req.ContractName == "" || len(req.Sources) == 0 {
+		return nil, errors.New("contract name and sources are required")
+	}
+
+	prev, err := v.backend.VerifiedContract(address)
+	if err == nil && prev.Match == models.MatchFull {
+		return &prev, nil
+	} else if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+
+	solc, err := v.findCompiler(req.CompilerVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := v.deployedCode(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(code) == 0 {
+		return nil, errors.New("no contract deployed at address")
+	}
+
+	v.mu.Lock()
+	out, err := v.compile(solc, req.Sources, req.Settings)
+	v.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, name := "", req.ContractName
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		file, name = name[:i], name[i+1:]
+	}
+
+	found := false
+
+	for f, contracts := range out.Contracts {
+		c, ok := contracts[name]
+		if !ok || (file != "" && f != file) {
+			continue
+		}
+		found = true
+
+		full, err := matchCode(c.EVM.DeployedBytecode.Object, code, c.EVM.DeployedBytecode.ImmutableReferences)
+		if err != nil {
+			continue
+		}
+
+		verified := &models.VerifiedContract{
+			Address:         address,
+			ContractName:    f + ":" + name,
+			CompilerVersion: req.CompilerVersion,
+			Settings:        string(req.Settings),
+			Sources:         req.Sources,
+			ABI:             string(c.ABI),
+			Match:           models.MatchPartial,
+			Verified:        time.Now().Unix(),
+		}
+
+		if full {
+			verified.Match = models.MatchFull
+		}
+
+		if err := v.backend.AddVerifiedContract(verified); err != nil {
+			return nil, err
+		}
+
+		v.logger.Info("verified contract", "address", address, "name", verified.ContractName, "match", verified.Match)
+
+		return verified, nil
+	}
+
+	if !found {
+		return nil, errors.New("contract " + req.ContractName + " not found in compiler output")
+	}
+
+	return nil, errNoMatch
+}
+
+// deployedCode returns the runtime code stored when the contract was created, or the current code from the node
+// for contracts the crawler hasn't recorded
+
+func (v *Verifier) deployedCode(address string) ([]byte, error) {
+	creation, err := v.backend.ContractCreation(address)
+
+	if err == nil {
+		return hexutil.Decode(creation.Code)
+	} else if err != storage.ErrNotFound {
+		return nil, err
+	}
+
+	return v.rpc.GetCode(address)
+}